@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -54,71 +52,70 @@ type Activity struct {
 	Duration            *int64              `json:"DURATION"`
 }
 
+var activitySetResource = &Resource[ActivitySet]{
+	Endpoint: "ActivitySets",
+	ID:       func(a *ActivitySet) int64 { return a.ActivitySetID },
+}
+
 type GetActivitySetsConfig struct {
 	Skip       *uint64
 	Top        *uint64
+	Cursor     *Cursor
+	NextCursor *Cursor
 	Brief      *bool
 	CountTotal *bool
 }
 
-// GetActivitySets returns all activitySets
-//
-
-func (service *Service) GetActivitySets(config *GetActivitySetsConfig) (*[]ActivitySet, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "ActivitySets"
-	activitySets := []ActivitySet{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
+func (config *GetActivitySetsConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return newPageConfig(endpoint, nil, nil, nil)
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.setBrief(config.Brief)
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+	return p
+}
 
-		activitySetsBatch := []ActivitySet{}
+// GetActivitySets returns all activitySets
+//
+
+func (service *Service) GetActivitySets(config *GetActivitySetsConfig) (*[]ActivitySet, *errortools.Error) {
+	return service.GetActivitySetsWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &activitySetsBatch,
-		}
+// GetActivitySetsWithContext is the context-aware variant of GetActivitySets
+//
+func (service *Service) GetActivitySetsWithContext(ctx context.Context, config *GetActivitySetsConfig) (*[]ActivitySet, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetActivitySets")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return activitySetResource.List(ctx, service, config)
+}
 
-		activitySets = append(activitySets, activitySetsBatch...)
+// GetActivitySetsPage returns a single page of activitySets and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetActivitySetsPage(config *GetActivitySetsConfig) (*[]ActivitySet, *Cursor, *errortools.Error) {
+	return service.GetActivitySetsPageWithContext(context.Background(), config)
+}
 
-		if len(activitySetsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetActivitySetsPageWithContext is the context-aware variant of GetActivitySetsPage
+//
+func (service *Service) GetActivitySetsPageWithContext(ctx context.Context, config *GetActivitySetsConfig) (*[]ActivitySet, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetActivitySetsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return activitySetResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &activitySets, nil
-		}
-	}
+// ActivitySetsSeq returns an iterator over all activitySets, fetching pages on demand
+//
+func (service *Service) ActivitySetsSeq(config *GetActivitySetsConfig) iter.Seq2[ActivitySet, *errortools.Error] {
+	return service.ActivitySetsSeqWithContext(context.Background(), config)
+}
 
-	return &activitySets, nil
+// ActivitySetsSeqWithContext is the context-aware variant of ActivitySetsSeq
+//
+func (service *Service) ActivitySetsSeqWithContext(ctx context.Context, config *GetActivitySetsConfig) iter.Seq2[ActivitySet, *errortools.Error] {
+	return seq(ctx, service, "ActivitySetsSeq", activitySetResource, config)
 }
@@ -0,0 +1,118 @@
+package insightly_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+// TestConcurrentUse shares one Service between goroutines, run it with -race
+//
+func TestConcurrentUse(t *testing.T) {
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.RateLimitStore = insightly.NewMemoryRateLimitStore()
+	})
+	server.SetRateLimit(100000, 100000, time.Minute)
+
+	seeded := []any{}
+	for i := 0; i < 20; i++ {
+		seeded = append(seeded, &insightly.Contact{})
+	}
+	contactIDs := server.Seed("Contacts", seeded...)
+	server.Seed("Leads", &insightly.Lead{}, &insightly.Lead{}, &insightly.Lead{})
+
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 100*workers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 5; i++ {
+				contactID := contactIDs[(w*5+i)%len(contactIDs)]
+
+				contact, e := service.GetContact(contactID)
+				if e != nil {
+					errs <- e.Message()
+					continue
+				}
+
+				firstName := fmt.Sprintf("worker %v", w)
+				contact.FirstName = &firstName
+				_, e = service.UpdateContact(contact)
+				if e != nil {
+					errs <- e.Message()
+				}
+
+				created, e := service.CreateContact(&insightly.Contact{FirstName: &firstName})
+				if e != nil {
+					errs <- e.Message()
+					continue
+				}
+				e = service.DeleteContact(created.ContactID)
+				if e != nil {
+					errs <- e.Message()
+				}
+
+				_ = service.RateLimit()
+				_ = service.ApiCallCount()
+			}
+		}(w)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			top := uint64(7)
+			count := 0
+			for _, e := range service.ContactsSeq(&insightly.GetContactsConfig{Top: &top}) {
+				if e != nil {
+					errs <- e.Message()
+					return
+				}
+				count++
+			}
+			if count < len(contactIDs) {
+				errs <- fmt.Sprintf("got %v contacts, want at least %v", count, len(contactIDs))
+			}
+
+			for _, e := range service.LeadsSeq(nil) {
+				if e != nil {
+					errs <- e.Message()
+					return
+				}
+			}
+
+			_ = service.RateLimit()
+			_ = service.ApiCallCount()
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for message := range errs {
+		t.Error(message)
+	}
+
+	if server.Count("Contacts") != len(contactIDs) {
+		t.Errorf("got %v contacts, want %v", server.Count("Contacts"), len(contactIDs))
+	}
+
+	requests := int64(len(server.Requests()))
+	if service.ApiCallCount() != requests {
+		t.Errorf("got api call count %v, want %v", service.ApiCallCount(), requests)
+	}
+
+	// responses may be saved out of order, the rate limit is that of one of them
+	rateLimit := service.RateLimit()
+	if rateLimit.Remaining == nil || *rateLimit.Remaining < 100000-requests || *rateLimit.Remaining >= 100000 {
+		t.Errorf("got remaining %v, want between %v and %v", rateLimit.Remaining, 100000-requests, 100000-1)
+	}
+}
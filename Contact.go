@@ -1,11 +1,11 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"net/http"
-	"net/url"
 	"strings"
-	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -56,160 +56,136 @@ type Contact struct {
 	Links                *[]Link                 `json:"LINKS,omitempty"`
 }
 
+var contactResource = &Resource[Contact]{
+	Endpoint: "Contacts",
+	ID:       func(c *Contact) int64 { return c.ContactID },
+}
+
 // GetContact returns a specific contact
 func (service *Service) GetContact(contactID int64) (*Contact, *errortools.Error) {
-	contact := Contact{}
+	return service.GetContactWithContext(context.Background(), contactID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Contacts/%v", contactID)),
-		ResponseModel: &contact,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetContactWithContext is the context-aware variant of GetContact
+func (service *Service) GetContactWithContext(ctx context.Context, contactID int64) (*Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetContact")
+	defer span.End()
 
-	return &contact, nil
+	return contactResource.Get(ctx, service, contactID)
 }
 
-type GetContactsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetContactsConfig = ListConfig
 
 // GetContacts returns all contacts
 func (service *Service) GetContacts(config *GetContactsConfig) (*[]Contact, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Contacts"
-	contacts := []Contact{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetContactsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetContactsWithContext is the context-aware variant of GetContacts
+func (service *Service) GetContactsWithContext(ctx context.Context, config *GetContactsConfig) (*[]Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetContacts")
+	defer span.End()
+
+	return contactResource.List(ctx, service, config)
+}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+// GetContactsPage returns a single page of contacts and the Cursor of the next page, which is nil after the last page
+func (service *Service) GetContactsPage(config *GetContactsConfig) (*[]Contact, *Cursor, *errortools.Error) {
+	return service.GetContactsPageWithContext(context.Background(), config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		contactsBatch := []Contact{}
+// GetContactsPageWithContext is the context-aware variant of GetContactsPage
+func (service *Service) GetContactsPageWithContext(ctx context.Context, config *GetContactsConfig) (*[]Contact, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetContactsPage")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &contactsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return contactResource.Page(ctx, service, config)
+}
 
-		contacts = append(contacts, contactsBatch...)
+// ContactsSeq returns an iterator over all contacts, fetching pages on demand
+func (service *Service) ContactsSeq(config *GetContactsConfig) iter.Seq2[Contact, *errortools.Error] {
+	return service.ContactsSeqWithContext(context.Background(), config)
+}
 
-		if len(contactsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// ContactsSeqWithContext is the context-aware variant of ContactsSeq
+func (service *Service) ContactsSeqWithContext(ctx context.Context, config *GetContactsConfig) iter.Seq2[Contact, *errortools.Error] {
+	return seq(ctx, service, "ContactsSeq", contactResource, config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// SearchContactsByTag returns the contacts that have a specific tag
+func (service *Service) SearchContactsByTag(tagName string, config *SearchByTagConfig) (*[]Contact, *errortools.Error) {
+	return service.SearchContactsByTagWithContext(context.Background(), tagName, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &contacts, nil
-		}
-	}
+// SearchContactsByTagWithContext is the context-aware variant of SearchContactsByTag
+func (service *Service) SearchContactsByTagWithContext(ctx context.Context, tagName string, config *SearchByTagConfig) (*[]Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SearchContactsByTag")
+	defer span.End()
+
+	return contactResource.ListByTag(ctx, service, tagName, config)
+}
+
+// QueryContacts returns the contacts that match a Query
+func (service *Service) QueryContacts(query *Query) (*[]Contact, *errortools.Error) {
+	return service.QueryContactsWithContext(context.Background(), query)
+}
+
+// QueryContactsWithContext is the context-aware variant of QueryContacts
+func (service *Service) QueryContactsWithContext(ctx context.Context, query *Query) (*[]Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "QueryContacts")
+	defer span.End()
+
+	return contactResource.Query(ctx, service, query)
+}
 
-	return &contacts, nil
+// QueryContactsSeq returns an iterator over the contacts that match a Query, fetching pages on demand
+func (service *Service) QueryContactsSeq(query *Query) iter.Seq2[Contact, *errortools.Error] {
+	return service.QueryContactsSeqWithContext(context.Background(), query)
+}
+
+// QueryContactsSeqWithContext is the context-aware variant of QueryContactsSeq
+func (service *Service) QueryContactsSeqWithContext(ctx context.Context, query *Query) iter.Seq2[Contact, *errortools.Error] {
+	return querySeq[Contact](ctx, service, "QueryContactsSeq", contactResource.Endpoint, query)
 }
 
 // CreateContact creates a new contract
 func (service *Service) CreateContact(contact *Contact) (*Contact, *errortools.Error) {
-	if contact == nil {
-		return nil, nil
-	}
-
-	contactNew := Contact{}
+	return service.CreateContactWithContext(context.Background(), contact)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Contacts"),
-		BodyModel:     contact,
-		ResponseModel: &contactNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateContactWithContext is the context-aware variant of CreateContact
+func (service *Service) CreateContactWithContext(ctx context.Context, contact *Contact) (*Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateContact")
+	defer span.End()
 
-	return &contactNew, nil
+	return contactResource.Create(ctx, service, contact)
 }
 
-// UpdateContact updates an existing contract
+// UpdateContact updates an existing contract, a contact without ContactID is rejected
+// without sending a request
 func (service *Service) UpdateContact(contact *Contact) (*Contact, *errortools.Error) {
-	if contact == nil {
-		return nil, nil
-	}
+	return service.UpdateContactWithContext(context.Background(), contact)
+}
 
-	contactUpdated := Contact{}
+// UpdateContactWithContext is the context-aware variant of UpdateContact
+func (service *Service) UpdateContactWithContext(ctx context.Context, contact *Contact) (*Contact, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateContact")
+	defer span.End()
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Contacts"),
-		BodyModel:     contact,
-		ResponseModel: &contactUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
-
-	return &contactUpdated, nil
+	return contactResource.Update(ctx, service, contact)
 }
 
 // DeleteContact deletes a specific contact
 func (service *Service) DeleteContact(contactID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Contacts/%v", contactID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteContactWithContext(context.Background(), contactID)
+}
+
+// DeleteContactWithContext is the context-aware variant of DeleteContact
+func (service *Service) DeleteContactWithContext(ctx context.Context, contactID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteContact")
+	defer span.End()
 
-	return nil
+	return contactResource.Delete(ctx, service, contactID)
 }
 
 func (c *Contact) FullName() string {
@@ -230,6 +206,14 @@ func (c *Contact) FullName() string {
 
 // GetContactFileAttachments returns the file attachments of a specific email
 func (service *Service) GetContactFileAttachments(id int64) (*[]FileAttachment, *errortools.Error) {
+	return service.GetContactFileAttachmentsWithContext(context.Background(), id)
+}
+
+// GetContactFileAttachmentsWithContext is the context-aware variant of GetContactFileAttachments
+func (service *Service) GetContactFileAttachmentsWithContext(ctx context.Context, id int64) (*[]FileAttachment, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetContactFileAttachments")
+	defer span.End()
+
 	var fileAttachments []FileAttachment
 
 	requestConfig := go_http.RequestConfig{
@@ -238,7 +222,7 @@ func (service *Service) GetContactFileAttachments(id int64) (*[]FileAttachment,
 		ResponseModel: &fileAttachments,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
@@ -0,0 +1,116 @@
+package insightly_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+// promptly bounds the time a cancelled call may take, far below the waits the calls
+// would make if they ignored ctx
+const promptly = 5 * time.Second
+
+// cancelTransport cancels a context after passing on a number of responses
+type cancelTransport struct {
+	cancel context.CancelFunc
+	after  int
+	sent   int
+}
+
+func (t *cancelTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := http.DefaultTransport.RoundTrip(request)
+
+	t.sent++
+	if t.sent == t.after {
+		t.cancel()
+	}
+
+	return response, err
+}
+
+// assertCanceled checks that a call cancelled after start returned context.Canceled promptly
+func assertCanceled(t *testing.T, ctx context.Context, e *errortools.Error, start time.Time) {
+	t.Helper()
+
+	if e == nil {
+		t.Fatal("expected an error after cancelling the context")
+	}
+	if err := insightly.AsErrorWithContext(ctx, e); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > promptly {
+		t.Errorf("got the error after %v, want it within %v", elapsed, promptly)
+	}
+}
+
+func TestCancelDuringPagination(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Transport = &cancelTransport{cancel: cancel, after: 1}
+	})
+	server.Seed("Contacts", &insightly.Contact{}, &insightly.Contact{}, &insightly.Contact{})
+
+	start := time.Now()
+	top := uint64(1)
+	_, e := service.GetContactsWithContext(ctx, &insightly.GetContactsConfig{Top: &top})
+
+	assertCanceled(t, ctx, e, start)
+	if requests := len(server.Requests()); requests != 1 {
+		t.Errorf("got %v requests, want only the page before cancelling", requests)
+	}
+}
+
+func TestCancelDuringRateLimitWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service, server := newTestService(t, nil)
+	server.SetRateLimit(10, 1, time.Hour)
+
+	_, e := service.GetContactsWithContext(ctx, nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, e = service.GetContactsWithContext(ctx, nil)
+
+	assertCanceled(t, ctx, e, start)
+	if requests := len(server.Requests()); requests != 1 {
+		t.Errorf("got %v requests, want none sent while waiting for the rate limit", requests)
+	}
+}
+
+func TestCancelDuringRetryBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:      3,
+			InitialInterval: time.Hour,
+			Multiplier:      1,
+			StatusCodes:     []int{http.StatusServiceUnavailable},
+		}
+	})
+	server.FailNext(1, http.StatusServiceUnavailable)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, e := service.GetContactsWithContext(ctx, nil)
+
+	assertCanceled(t, ctx, e, start)
+	if requests := len(server.Requests()); requests != 1 {
+		t.Errorf("got %v requests, want no retry after cancelling", requests)
+	}
+}
@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"net/http"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
@@ -17,6 +18,15 @@ type Country struct {
 //
 
 func (service *Service) GetCountries() (*[]Country, *errortools.Error) {
+	return service.GetCountriesWithContext(context.Background())
+}
+
+// GetCountriesWithContext is the context-aware variant of GetCountries
+//
+func (service *Service) GetCountriesWithContext(ctx context.Context) (*[]Country, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCountries")
+	defer span.End()
+
 	countries := []Country{}
 	requestConfig := go_http.RequestConfig{
 		Method:        http.MethodGet,
@@ -24,7 +34,7 @@ func (service *Service) GetCountries() (*[]Country, *errortools.Error) {
 		ResponseModel: &countries,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
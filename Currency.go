@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"net/http"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
@@ -18,6 +19,15 @@ type Currency struct {
 //
 
 func (service *Service) GetCurrencies() (*[]Currency, *errortools.Error) {
+	return service.GetCurrenciesWithContext(context.Background())
+}
+
+// GetCurrenciesWithContext is the context-aware variant of GetCurrencies
+//
+func (service *Service) GetCurrenciesWithContext(ctx context.Context) (*[]Currency, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCurrencies")
+	defer span.End()
+
 	currencies := []Currency{}
 	requestConfig := go_http.RequestConfig{
 		Method:        http.MethodGet,
@@ -25,7 +35,7 @@ func (service *Service) GetCurrencies() (*[]Currency, *errortools.Error) {
 		ResponseModel: &currencies,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
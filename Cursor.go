@@ -0,0 +1,82 @@
+package insightly
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// Cursor marks the position of the next page of a list request.
+// A Cursor is bound to the endpoint and filter it was returned for and can be
+// serialized (String, MarshalText, JSON) to resume a crawl later, also in another process.
+// Cursors are returned by the Page methods, and by the list methods and iterators in the
+// NextCursor of their config when ServiceConfig.MaxRowCount truncates them.
+//
+type Cursor struct {
+	Endpoint string
+	Query    string
+	Skip     uint64
+}
+
+// cursorToken is the serialized form of Cursor
+//
+type cursorToken struct {
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query,omitempty"`
+	Skip     uint64 `json:"skip"`
+}
+
+// ParseCursor parses a token returned by Cursor.String
+//
+func ParseCursor(token string) (*Cursor, *errortools.Error) {
+	cursor := Cursor{}
+
+	err := cursor.UnmarshalText([]byte(token))
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	return &cursor, nil
+}
+
+// String returns the cursor as an opaque token
+//
+func (c *Cursor) String() string {
+	if c == nil {
+		return ""
+	}
+
+	b, _ := c.MarshalText()
+	return string(b)
+}
+
+func (c Cursor) MarshalText() ([]byte, error) {
+	b, err := json.Marshal(cursorToken(c))
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, base64.RawURLEncoding.EncodedLen(len(b)))
+	base64.RawURLEncoding.Encode(token, b)
+
+	return token, nil
+}
+
+func (c *Cursor) UnmarshalText(text []byte) error {
+	b, err := base64.RawURLEncoding.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid cursor token: %v", err)
+	}
+
+	cursor := cursorToken{}
+
+	err = json.Unmarshal(b, &cursor)
+	if err != nil {
+		return fmt.Errorf("invalid cursor token: %v", err)
+	}
+
+	*c = Cursor(cursor)
+	return nil
+}
@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -50,6 +51,15 @@ type GetCustomFieldsConfig struct {
 // GetCustomFields returns all customobjects
 //
 func (service *Service) GetCustomFields(config *GetCustomFieldsConfig) (*[]CustomField, *errortools.Error) {
+	return service.GetCustomFieldsWithContext(context.Background(), config)
+}
+
+// GetCustomFieldsWithContext is the context-aware variant of GetCustomFields
+//
+func (service *Service) GetCustomFieldsWithContext(ctx context.Context, config *GetCustomFieldsConfig) (*[]CustomField, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCustomFields")
+	defer span.End()
+
 	if config == nil {
 		return nil, nil
 	}
@@ -74,7 +84,7 @@ func (service *Service) GetCustomFields(config *GetCustomFieldsConfig) (*[]Custo
 		Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
 		ResponseModel: &customFields,
 	}
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
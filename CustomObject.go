@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"net/http"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
@@ -28,6 +29,15 @@ type CustomObject struct {
 // GetCustomObjects returns all customobjects
 //
 func (service *Service) GetCustomObjects() (*[]CustomObject, *errortools.Error) {
+	return service.GetCustomObjectsWithContext(context.Background())
+}
+
+// GetCustomObjectsWithContext is the context-aware variant of GetCustomObjects
+//
+func (service *Service) GetCustomObjectsWithContext(ctx context.Context) (*[]CustomObject, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCustomObjects")
+	defer span.End()
+
 	customObjects := []CustomObject{}
 
 	requestConfig := go_http.RequestConfig{
@@ -35,7 +45,7 @@ func (service *Service) GetCustomObjects() (*[]CustomObject, *errortools.Error)
 		Url:           service.url("CustomObjects"),
 		ResponseModel: &customObjects,
 	}
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
@@ -1,13 +1,11 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -47,27 +45,36 @@ func (c *CustomObjectRecord) prepareMarshal() interface{} {
 	}
 }
 
+// customObjectRecordResource returns the Resource of the records of a custom object
+//
+func customObjectRecordResource(customObjectName string) *Resource[CustomObjectRecord] {
+	return &Resource[CustomObjectRecord]{
+		Endpoint: customObjectName,
+		ID:       func(c *CustomObjectRecord) int64 { return c.RecordID },
+		Marshal:  func(c *CustomObjectRecord) interface{} { return c.prepareMarshal() },
+	}
+}
+
 // GetCustomObjectRecord returns a specific customObjectRecord
 //
 func (service *Service) GetCustomObjectRecord(customObjectName string, customObjectRecordID int64) (*CustomObjectRecord, *errortools.Error) {
-	customObjectRecord := CustomObjectRecord{}
+	return service.GetCustomObjectRecordWithContext(context.Background(), customObjectName, customObjectRecordID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("%s/%v", customObjectName, customObjectRecordID)),
-		ResponseModel: &customObjectRecord,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetCustomObjectRecordWithContext is the context-aware variant of GetCustomObjectRecord
+//
+func (service *Service) GetCustomObjectRecordWithContext(ctx context.Context, customObjectName string, customObjectRecordID int64) (*CustomObjectRecord, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCustomObjectRecord")
+	defer span.End()
 
-	return &customObjectRecord, nil
+	return customObjectRecordResource(customObjectName).Get(ctx, service, customObjectRecordID)
 }
 
 type GetCustomObjectRecordsConfig struct {
 	Skip             *uint64
 	Top              *uint64
+	Cursor           *Cursor
+	NextCursor       *Cursor
 	Brief            *bool
 	CountTotal       *bool
 	CustomObjectName string
@@ -75,141 +82,113 @@ type GetCustomObjectRecordsConfig struct {
 	FieldFilter      *FieldFilter
 }
 
-// GetCustomObjectRecords returns all customObjectRecords
-//
-func (service *Service) GetCustomObjectRecords(config *GetCustomObjectRecordsConfig) (*[]CustomObjectRecord, *errortools.Error) {
+func (config *GetCustomObjectRecordsConfig) pageConfig(endpoint string) *pageConfig {
 	if config == nil {
-		return nil, nil
+		return nil
 	}
 
-	params := url.Values{}
-
-	customObjectRecords := []CustomObjectRecord{}
-
-	endpoint := config.CustomObjectName
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.setBrief(config.Brief)
+	p.setSearch(config.UpdatedAfter, config.FieldFilter)
 
-	if isSearch {
-		endpoint += "/Search"
+	return p
+}
+
+// resource returns the Resource of the custom object selected by config, nothing is
+// listed for a nil config
+//
+func (config *GetCustomObjectRecordsConfig) resource() *Resource[CustomObjectRecord] {
+	if config == nil {
+		return customObjectRecordResource("")
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return customObjectRecordResource(config.CustomObjectName)
+}
+
+// GetCustomObjectRecords returns all customObjectRecords
+//
+func (service *Service) GetCustomObjectRecords(config *GetCustomObjectRecordsConfig) (*[]CustomObjectRecord, *errortools.Error) {
+	return service.GetCustomObjectRecordsWithContext(context.Background(), config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		customObjectRecordsBatch := []CustomObjectRecord{}
+// GetCustomObjectRecordsWithContext is the context-aware variant of GetCustomObjectRecords
+//
+func (service *Service) GetCustomObjectRecordsWithContext(ctx context.Context, config *GetCustomObjectRecordsConfig) (*[]CustomObjectRecord, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCustomObjectRecords")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &customObjectRecordsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return config.resource().List(ctx, service, config)
+}
 
-		customObjectRecords = append(customObjectRecords, customObjectRecordsBatch...)
+// GetCustomObjectRecordsPage returns a single page of customObjectRecords and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetCustomObjectRecordsPage(config *GetCustomObjectRecordsConfig) (*[]CustomObjectRecord, *Cursor, *errortools.Error) {
+	return service.GetCustomObjectRecordsPageWithContext(context.Background(), config)
+}
 
-		if len(customObjectRecordsBatch) < int(top) {
-			service.nextSkips[endpoint] = 0
-			break
-		}
+// GetCustomObjectRecordsPageWithContext is the context-aware variant of GetCustomObjectRecordsPage
+//
+func (service *Service) GetCustomObjectRecordsPageWithContext(ctx context.Context, config *GetCustomObjectRecordsConfig) (*[]CustomObjectRecord, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetCustomObjectRecordsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return config.resource().Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &customObjectRecords, nil
-		}
-	}
+// CustomObjectRecordsSeq returns an iterator over all customObjectRecords, fetching pages on demand
+//
+func (service *Service) CustomObjectRecordsSeq(config *GetCustomObjectRecordsConfig) iter.Seq2[CustomObjectRecord, *errortools.Error] {
+	return service.CustomObjectRecordsSeqWithContext(context.Background(), config)
+}
 
-	return &customObjectRecords, nil
+// CustomObjectRecordsSeqWithContext is the context-aware variant of CustomObjectRecordsSeq
+//
+func (service *Service) CustomObjectRecordsSeqWithContext(ctx context.Context, config *GetCustomObjectRecordsConfig) iter.Seq2[CustomObjectRecord, *errortools.Error] {
+	return seq(ctx, service, "CustomObjectRecordsSeq", config.resource(), config)
 }
 
 // CreateCustomObjectRecord creates a new contract
 //
 func (service *Service) CreateCustomObjectRecord(customObjectName string, customObjectRecord *CustomObjectRecord) (*CustomObjectRecord, *errortools.Error) {
-	if customObjectRecord == nil {
-		return nil, nil
-	}
-
-	customObjectRecordNew := CustomObjectRecord{}
+	return service.CreateCustomObjectRecordWithContext(context.Background(), customObjectName, customObjectRecord)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url(customObjectName),
-		BodyModel:     customObjectRecord.prepareMarshal(),
-		ResponseModel: &customObjectRecordNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateCustomObjectRecordWithContext is the context-aware variant of CreateCustomObjectRecord
+//
+func (service *Service) CreateCustomObjectRecordWithContext(ctx context.Context, customObjectName string, customObjectRecord *CustomObjectRecord) (*CustomObjectRecord, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateCustomObjectRecord")
+	defer span.End()
 
-	return &customObjectRecordNew, nil
+	return customObjectRecordResource(customObjectName).Create(ctx, service, customObjectRecord)
 }
 
-// UpdateCustomObjectRecord updates an existing contract
+// UpdateCustomObjectRecord updates an existing contract, a record without RecordID is
+// rejected without sending a request
 //
 func (service *Service) UpdateCustomObjectRecord(customObjectName string, customObjectRecord *CustomObjectRecord) (*CustomObjectRecord, *errortools.Error) {
-	if customObjectRecord == nil {
-		return nil, nil
-	}
-
-	customObjectRecordUpdated := CustomObjectRecord{}
+	return service.UpdateCustomObjectRecordWithContext(context.Background(), customObjectName, customObjectRecord)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url(customObjectName),
-		BodyModel:     customObjectRecord.prepareMarshal(),
-		ResponseModel: &customObjectRecordUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateCustomObjectRecordWithContext is the context-aware variant of UpdateCustomObjectRecord
+//
+func (service *Service) UpdateCustomObjectRecordWithContext(ctx context.Context, customObjectName string, customObjectRecord *CustomObjectRecord) (*CustomObjectRecord, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateCustomObjectRecord")
+	defer span.End()
 
-	return &customObjectRecordUpdated, nil
+	return customObjectRecordResource(customObjectName).Update(ctx, service, customObjectRecord)
 }
 
 // DeleteCustomObjectRecord deletes a specific customObjectRecord
 //
 func (service *Service) DeleteCustomObjectRecord(customObjectName string, customObjectRecordID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("%s/%v", customObjectName, customObjectRecordID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteCustomObjectRecordWithContext(context.Background(), customObjectName, customObjectRecordID)
+}
+
+// DeleteCustomObjectRecordWithContext is the context-aware variant of DeleteCustomObjectRecord
+//
+func (service *Service) DeleteCustomObjectRecordWithContext(ctx context.Context, customObjectName string, customObjectRecordID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteCustomObjectRecord")
+	defer span.End()
 
-	return nil
+	return customObjectRecordResource(customObjectName).Delete(ctx, service, customObjectRecordID)
 }
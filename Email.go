@@ -1,10 +1,10 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"net/http"
-	"net/url"
-	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -31,109 +31,72 @@ type Email struct {
 	Links             *[]Link                 `json:"LINKS"`
 }
 
-type GetEmailsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetEmailsConfig = ListConfig
 
 // GetEmails returns all emails
 func (service *Service) GetEmails(config *GetEmailsConfig) (*[]Email, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Emails"
-	emails := []Email{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
-
-	if isSearch {
-		endpoint += "/Search"
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+	return service.GetEmailsWithContext(context.Background(), config)
+}
 
-		emailsBatch := []Email{}
+// GetEmailsWithContext is the context-aware variant of GetEmails
+func (service *Service) GetEmailsWithContext(ctx context.Context, config *GetEmailsConfig) (*[]Email, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEmails")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &emailsBatch,
-		}
+	return emailResource.List(ctx, service, config)
+}
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// GetEmailsPage returns a single page of emails and the Cursor of the next page, which is nil after the last page
+func (service *Service) GetEmailsPage(config *GetEmailsConfig) (*[]Email, *Cursor, *errortools.Error) {
+	return service.GetEmailsPageWithContext(context.Background(), config)
+}
 
-		emails = append(emails, emailsBatch...)
+// GetEmailsPageWithContext is the context-aware variant of GetEmailsPage
+func (service *Service) GetEmailsPageWithContext(ctx context.Context, config *GetEmailsConfig) (*[]Email, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEmailsPage")
+	defer span.End()
 
-		if len(emailsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+	return emailResource.Page(ctx, service, config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// EmailsSeq returns an iterator over all emails, fetching pages on demand
+func (service *Service) EmailsSeq(config *GetEmailsConfig) iter.Seq2[Email, *errortools.Error] {
+	return service.EmailsSeqWithContext(context.Background(), config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &emails, nil
-		}
-	}
+// EmailsSeqWithContext is the context-aware variant of EmailsSeq
+func (service *Service) EmailsSeqWithContext(ctx context.Context, config *GetEmailsConfig) iter.Seq2[Email, *errortools.Error] {
+	return seq(ctx, service, "EmailsSeq", emailResource, config)
+}
 
-	return &emails, nil
+var emailResource = &Resource[Email]{
+	Endpoint: "Emails",
+	ID:       func(e *Email) int64 { return e.EmailID },
 }
 
 // GetEmail returns a specific email
 func (service *Service) GetEmail(id int64) (*Email, *errortools.Error) {
-	var email Email
-
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("emails/%v", id)),
-		ResponseModel: &email,
-	}
+	return service.GetEmailWithContext(context.Background(), id)
+}
 
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetEmailWithContext is the context-aware variant of GetEmail
+func (service *Service) GetEmailWithContext(ctx context.Context, id int64) (*Email, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEmail")
+	defer span.End()
 
-	return &email, nil
+	return emailResource.Get(ctx, service, id)
 }
 
 // GetEmailFileAttachments returns the file attachments of a specific email
 func (service *Service) GetEmailFileAttachments(id int64) (*[]FileAttachment, *errortools.Error) {
+	return service.GetEmailFileAttachmentsWithContext(context.Background(), id)
+}
+
+// GetEmailFileAttachmentsWithContext is the context-aware variant of GetEmailFileAttachments
+func (service *Service) GetEmailFileAttachmentsWithContext(ctx context.Context, id int64) (*[]FileAttachment, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEmailFileAttachments")
+	defer span.End()
+
 	var fileAttachments []FileAttachment
 
 	requestConfig := go_http.RequestConfig{
@@ -142,7 +105,7 @@ func (service *Service) GetEmailFileAttachments(id int64) (*[]FileAttachment, *e
 		ResponseModel: &fileAttachments,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
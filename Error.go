@@ -0,0 +1,178 @@
+package insightly
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// APIError holds the status code and message of a failed Insightly API request,
+// it is returned by AsError for status codes without a more specific error type
+//
+type APIError struct {
+	StatusCode int
+	Message    string
+	Err        *errortools.Error
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// As lets errors.As find the APIError embedded in the more specific error types
+//
+func (e *APIError) As(target any) bool {
+	if apiError, ok := target.(**APIError); ok {
+		*apiError = e
+		return true
+	}
+
+	return false
+}
+
+// NotFoundError is returned for 404 responses, e.g. when requesting a deleted record
+//
+type NotFoundError struct {
+	APIError
+}
+
+// UnauthorizedError is returned for 401 responses, the api key is invalid
+//
+type UnauthorizedError struct {
+	APIError
+}
+
+// PermissionDeniedError is returned for 402 and 403 responses, the user or plan
+// has no access to the requested record or feature
+//
+type PermissionDeniedError struct {
+	APIError
+}
+
+// ValidationError is returned for 400, 417 and 422 responses, FieldName holds
+// the name of the offending field if Insightly reports it
+//
+type ValidationError struct {
+	APIError
+	FieldName *string
+}
+
+// RateLimitedError is returned for 429 responses, RetryAt holds the time after
+// which the request can be retried if Insightly reports it
+//
+type RateLimitedError struct {
+	APIError
+	RetryAt *time.Time
+}
+
+// ServerError is returned for 5xx responses
+//
+type ServerError struct {
+	APIError
+}
+
+var validationFieldRegex = regexp.MustCompile(`\b([A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+|[A-Za-z0-9]+__c)\b`)
+
+// AsError converts an error returned by the Service into a typed error that can be
+// inspected with errors.As, for example:
+//
+//	_, e := service.GetContact(contactID)
+//	var notFound *insightly.NotFoundError
+//	if errors.As(insightly.AsError(e), &notFound) {
+//		...
+//	}
+//
+// All typed errors can also be inspected as *APIError. Errors without a response are
+// returned as plain errors, use AsErrorWithContext for errors of calls made with a context.
+//
+func AsError(e *errortools.Error) error {
+	return AsErrorWithContext(context.Background(), e)
+}
+
+// AsErrorWithContext is AsError for an error returned by a call made with ctx, errors
+// without a response are returned as ctx.Err(), e.g. context.Canceled, if ctx is done
+//
+func AsErrorWithContext(ctx context.Context, e *errortools.Error) error {
+	if e == nil {
+		return nil
+	}
+
+	response := e.Response()
+	if response == nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return errors.New(e.Message())
+	}
+
+	apiError := APIError{
+		StatusCode: response.StatusCode,
+		Message:    e.Message(),
+		Err:        e,
+	}
+
+	switch response.StatusCode {
+	case http.StatusNotFound:
+		return &NotFoundError{apiError}
+	case http.StatusUnauthorized:
+		return &UnauthorizedError{apiError}
+	case http.StatusPaymentRequired, http.StatusForbidden:
+		return &PermissionDeniedError{apiError}
+	case http.StatusBadRequest, http.StatusExpectationFailed, http.StatusUnprocessableEntity:
+		validationError := ValidationError{APIError: apiError}
+		fieldName := validationFieldRegex.FindString(apiError.Message)
+		if fieldName != "" {
+			validationError.FieldName = &fieldName
+		}
+		return &validationError
+	case http.StatusTooManyRequests:
+		rateLimitedError := RateLimitedError{APIError: apiError}
+		rateLimitedError.RetryAt = retryAt(response)
+		return &rateLimitedError
+	}
+
+	if response.StatusCode >= 500 {
+		return &ServerError{apiError}
+	}
+
+	return &apiError
+}
+
+// retryAt returns the time from the Retry-After header of a response, a number of
+// seconds counts from the time the response was sent according to its Date header
+//
+func retryAt(response *http.Response) *time.Time {
+	sent, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		sent = time.Now()
+	}
+
+	return parseRetryAfter(response.Header.Get("Retry-After"), sent)
+}
+
+// parseRetryAfter parses a Retry-After header, which is either an http date or a
+// number of seconds after sent, and returns nil if it is empty or invalid
+//
+func parseRetryAfter(retryAfter string, sent time.Time) *time.Time {
+	if retryAfter == "" {
+		return nil
+	}
+
+	t, err := http.ParseTime(retryAfter)
+	if err == nil {
+		return &t
+	}
+
+	seconds, err := strconv.ParseInt(retryAfter, 10, 64)
+	if err != nil {
+		return nil
+	}
+	t = sent.Add(time.Duration(seconds) * time.Second)
+
+	return &t
+}
@@ -0,0 +1,137 @@
+package insightly_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestAsErrorNotFound(t *testing.T) {
+	service, _ := newTestService(t, nil)
+
+	_, e := service.GetContact(1)
+	if e == nil {
+		t.Fatal("expected an error for a missing contact")
+	}
+
+	err := insightly.AsError(e)
+
+	var notFound *insightly.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("got %T, want *NotFoundError", err)
+	}
+
+	var apiError *insightly.APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("errors.As does not find the APIError of %T", err)
+	}
+	if apiError.StatusCode != http.StatusNotFound {
+		t.Errorf("got status code %v, want %v", apiError.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestAsErrorServerErrorIsAPIError(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	server.FailNext(1, http.StatusServiceUnavailable)
+
+	_, e := service.GetContacts(nil)
+	err := insightly.AsError(e)
+
+	var serverError *insightly.ServerError
+	var apiError *insightly.APIError
+	if !errors.As(err, &serverError) || !errors.As(err, &apiError) {
+		t.Fatalf("got %T, want *ServerError that is also an *APIError", err)
+	}
+	if apiError != &serverError.APIError {
+		t.Error("errors.As does not return the embedded APIError")
+	}
+}
+
+func TestAsErrorRetryAtIsRelativeToResponse(t *testing.T) {
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	e := errortools.ErrorMessage("Too Many Requests")
+	e.SetResponse(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Date":        []string{sent.Format(http.TimeFormat)},
+			"Retry-After": []string{"30"},
+		},
+	})
+
+	var rateLimited *insightly.RateLimitedError
+	if !errors.As(insightly.AsError(e), &rateLimited) {
+		t.Fatal("expected a RateLimitedError")
+	}
+	if rateLimited.RetryAt == nil || !rateLimited.RetryAt.Equal(sent.Add(30*time.Second)) {
+		t.Errorf("got RetryAt %v, want %v", rateLimited.RetryAt, sent.Add(30*time.Second))
+	}
+}
+
+func TestAsErrorWithContextCanceled(t *testing.T) {
+	service, _ := newTestService(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, e := service.GetContactsWithContext(ctx, nil)
+	if e == nil {
+		t.Fatal("expected an error for a canceled context")
+	}
+
+	if err := insightly.AsErrorWithContext(ctx, e); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+// httpDateTransport rewrites the Retry-After header of responses to an http date
+type httpDateTransport struct {
+	retryAt time.Time
+}
+
+func (t *httpDateTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := http.DefaultTransport.RoundTrip(request)
+	if err == nil && response.Header.Get("Retry-After") != "" {
+		response.Header.Set("Retry-After", t.retryAt.UTC().Format(http.TimeFormat))
+	}
+
+	return response, err
+}
+
+func TestRetryAfterHttpDate(t *testing.T) {
+	retryAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Transport = &httpDateTransport{retryAt: retryAt}
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:      1,
+			InitialInterval: time.Millisecond,
+			MaxElapsedTime:  time.Minute,
+			StatusCodes:     []int{http.StatusTooManyRequests},
+		}
+	})
+	server.Throttle(1, time.Second)
+
+	_, e := service.GetContacts(nil)
+
+	// waiting until retryAt exceeds MaxElapsedTime, a Retry-After that is not
+	// understood would retry after InitialInterval instead
+	var rateLimited *insightly.RateLimitedError
+	if !errors.As(insightly.AsError(e), &rateLimited) {
+		t.Fatalf("got %v, want a RateLimitedError without retrying", e)
+	}
+	if requests := len(server.Requests()); requests != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+
+	rateLimit := service.RateLimit()
+	if rateLimit.RetryAt == nil || !rateLimit.RetryAt.Equal(retryAt) {
+		t.Errorf("got RetryAt %v, want %v", rateLimit.RetryAt, retryAt)
+	}
+}
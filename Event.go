@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -31,102 +28,142 @@ type Event struct {
 	Links           *[]Link                 `json:"LINKS"`
 }
 
+func (e *Event) prepareMarshal() interface{} {
+	if e == nil {
+		return nil
+	}
+
+	return &struct {
+		EventID         *int64                  `json:"EVENT_ID,omitempty"`
+		Title           *string                 `json:"TITLE,omitempty"`
+		Location        *string                 `json:"LOCATION,omitempty"`
+		StartDateUTC    *i_types.DateTimeString `json:"START_DATE_UTC,omitempty"`
+		EndDateUTC      *i_types.DateTimeString `json:"END_DATE_UTC,omitempty"`
+		AllDay          *bool                   `json:"ALL_DAY,omitempty"`
+		Details         *string                 `json:"DETAILS,omitempty"`
+		ReminderDateUTC *i_types.DateTimeString `json:"REMINDER_DATE_UTC,omitempty"`
+		OwnerUserID     *int64                  `json:"OWNER_USER_ID,omitempty"`
+		CustomFields    *CustomFields           `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(e.EventID),
+		&e.Title,
+		&e.Location,
+		dateTimeStringPtr(e.StartDateUTC),
+		dateTimeStringPtr(e.EndDateUTC),
+		&e.AllDay,
+		&e.Details,
+		e.ReminderDateUTC,
+		nonZero(e.OwnerUserID),
+		e.CustomFields,
+	}
+}
+
+var eventResource = &Resource[Event]{
+	Endpoint: "Events",
+	ID:       func(e *Event) int64 { return e.EventID },
+	Marshal:  func(e *Event) interface{} { return e.prepareMarshal() },
+}
+
 // GetEvent returns a specific event
 //
 func (service *Service) GetEvent(eventID int64) (*Event, *errortools.Error) {
-	event := Event{}
+	return service.GetEventWithContext(context.Background(), eventID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Events/%v", eventID)),
-		ResponseModel: &event,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetEventWithContext is the context-aware variant of GetEvent
+//
+func (service *Service) GetEventWithContext(ctx context.Context, eventID int64) (*Event, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEvent")
+	defer span.End()
 
-	return &event, nil
+	return eventResource.Get(ctx, service, eventID)
 }
 
-type GetEventsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetEventsConfig = ListConfig
 
 // GetEvents returns all events
 //
 func (service *Service) GetEvents(config *GetEventsConfig) (*[]Event, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Events"
-	events := []Event{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetEventsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetEventsWithContext is the context-aware variant of GetEvents
+//
+func (service *Service) GetEventsWithContext(ctx context.Context, config *GetEventsConfig) (*[]Event, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEvents")
+	defer span.End()
+
+	return eventResource.List(ctx, service, config)
+}
+
+// GetEventsPage returns a single page of events and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetEventsPage(config *GetEventsConfig) (*[]Event, *Cursor, *errortools.Error) {
+	return service.GetEventsPageWithContext(context.Background(), config)
+}
+
+// GetEventsPageWithContext is the context-aware variant of GetEventsPage
+//
+func (service *Service) GetEventsPageWithContext(ctx context.Context, config *GetEventsConfig) (*[]Event, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetEventsPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return eventResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		eventsBatch := []Event{}
+// EventsSeq returns an iterator over all events, fetching pages on demand
+//
+func (service *Service) EventsSeq(config *GetEventsConfig) iter.Seq2[Event, *errortools.Error] {
+	return service.EventsSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &eventsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// EventsSeqWithContext is the context-aware variant of EventsSeq
+//
+func (service *Service) EventsSeqWithContext(ctx context.Context, config *GetEventsConfig) iter.Seq2[Event, *errortools.Error] {
+	return seq(ctx, service, "EventsSeq", eventResource, config)
+}
 
-		events = append(events, eventsBatch...)
+// CreateEvent creates a new event
+//
+func (service *Service) CreateEvent(event *Event) (*Event, *errortools.Error) {
+	return service.CreateEventWithContext(context.Background(), event)
+}
 
-		if len(eventsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreateEventWithContext is the context-aware variant of CreateEvent
+//
+func (service *Service) CreateEventWithContext(ctx context.Context, event *Event) (*Event, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateEvent")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return eventResource.Create(ctx, service, event)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &events, nil
-		}
-	}
+// UpdateEvent updates an existing event
+//
+func (service *Service) UpdateEvent(event *Event) (*Event, *errortools.Error) {
+	return service.UpdateEventWithContext(context.Background(), event)
+}
+
+// UpdateEventWithContext is the context-aware variant of UpdateEvent
+//
+func (service *Service) UpdateEventWithContext(ctx context.Context, event *Event) (*Event, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateEvent")
+	defer span.End()
+
+	return eventResource.Update(ctx, service, event)
+}
+
+// DeleteEvent deletes a specific event
+//
+func (service *Service) DeleteEvent(eventID int64) *errortools.Error {
+	return service.DeleteEventWithContext(context.Background(), eventID)
+}
+
+// DeleteEventWithContext is the context-aware variant of DeleteEvent
+//
+func (service *Service) DeleteEventWithContext(ctx context.Context, eventID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteEvent")
+	defer span.End()
 
-	return &events, nil
+	return eventResource.Delete(ctx, service, eventID)
 }
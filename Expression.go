@@ -0,0 +1,868 @@
+package insightly
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+const customFieldsPrefix string = "CUSTOMFIELDS."
+
+// Expression is a filter on records of any type of this package, parsed by ParseExpression
+// from e.g.
+//
+//	OPPORTUNITY_STATE = OPEN AND BID_AMOUNT > 10000 AND CUSTOMFIELDS.Region__c IN (EMEA, APAC)
+//
+// Fields are addressed by their JSON name or, ignoring case, their Go name, custom fields
+// by CUSTOMFIELDS.FieldName or just their FieldName if it ends with "__c". Comparisons are
+// =, != (or <>), <, <=, >, >=, IN (...), NOT IN (...), CONTAINS, NOT CONTAINS, IS NULL and
+// IS NOT NULL, combined with AND, OR, NOT and parentheses. Values are numbers, words or
+// quoted strings, 'single' or "double" with a doubled quote as escape. Values are compared
+// as numbers if both are numbers, otherwise as text ignoring case, so dates in the format
+// of the api compare chronologically. TAGS compares the names of the tags of a record and
+// matches if any of them does. Comparisons other than !=, NOT IN, NOT CONTAINS and IS NULL
+// do not match fields without a value.
+//
+type Expression struct {
+	source string
+	root   expressionNode
+}
+
+// ParseExpression parses a filter expression, see Expression
+//
+func ParseExpression(expression string) (*Expression, *errortools.Error) {
+	tokens, e := lexExpression(expression)
+	if e != nil {
+		return nil, e
+	}
+
+	p := expressionParser{source: expression, tokens: tokens}
+
+	root, e := p.parseOr()
+	if e != nil {
+		return nil, e
+	}
+
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, p.errorf(t, "expected AND or OR, found %s", t)
+	}
+
+	return &Expression{source: expression, root: root}, nil
+}
+
+func (x *Expression) String() string {
+	return x.source
+}
+
+// Matches returns whether a record, e.g. an Opportunity or a *Opportunity, matches the expression
+//
+func (x *Expression) Matches(record any) (bool, *errortools.Error) {
+	v := reflect.ValueOf(record)
+	if !v.IsValid() {
+		return false, errortools.ErrorMessage("Cannot match nil record")
+	}
+	if v.Kind() != reflect.Pointer {
+		// marshal a pointer so that fields with pointer receiver marshalers are formatted
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+
+	fields, e := recordFieldIndex(v.Type())
+	if e != nil {
+		return false, e
+	}
+
+	e = x.root.check(x, fields)
+	if e != nil {
+		return false, e
+	}
+
+	r, e := recordFields(v.Interface())
+	if e != nil {
+		return false, e
+	}
+
+	return x.root.evaluate(r, fields), nil
+}
+
+// FilterRecords returns the records that match a filter expression, see Expression
+//
+func FilterRecords[T any](records []T, expression string) ([]T, *errortools.Error) {
+	x, e := ParseExpression(expression)
+	if e != nil {
+		return nil, e
+	}
+
+	fields, e := recordFieldIndex(reflect.TypeFor[*T]())
+	if e != nil {
+		return nil, e
+	}
+
+	e = x.root.check(x, fields)
+	if e != nil {
+		return nil, e
+	}
+
+	filtered := []T{}
+	for i := range records {
+		r, e := recordFields(&records[i])
+		if e != nil {
+			return nil, e
+		}
+		if x.root.evaluate(r, fields) {
+			filtered = append(filtered, records[i])
+		}
+	}
+
+	return filtered, nil
+}
+
+// FilterSeq returns an iterator over the records of seq that match an expression, e.g.
+// to filter the records of ContactsSeq while they are read
+//
+func FilterSeq[T any](seq iter.Seq2[T, *errortools.Error], expression *Expression) iter.Seq2[T, *errortools.Error] {
+	return func(yield func(T, *errortools.Error) bool) {
+		var zero T
+
+		fields, e := recordFieldIndex(reflect.TypeFor[*T]())
+		if e == nil {
+			e = expression.root.check(expression, fields)
+		}
+		if e != nil {
+			yield(zero, e)
+			return
+		}
+
+		for record, e := range seq {
+			if e != nil {
+				yield(zero, e)
+				return
+			}
+
+			r, e := recordFields(&record)
+			if e != nil {
+				yield(zero, e)
+				return
+			}
+			if !expression.root.evaluate(r, fields) {
+				continue
+			}
+
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SortRecords sorts records in place by a comma separated list of fields, each
+// optionally followed by ASC or DESC, e.g. "BID_AMOUNT DESC, OPPORTUNITY_NAME". Fields
+// are addressed like in an Expression, records without a value come last.
+//
+func SortRecords[T any](records []T, orderBy string) *errortools.Error {
+	fields, e := recordFieldIndex(reflect.TypeFor[*T]())
+	if e != nil {
+		return e
+	}
+
+	type sortField struct {
+		field      fieldRef
+		descending bool
+	}
+
+	sortFields := []sortField{}
+	for _, part := range strings.Split(orderBy, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return errortools.ErrorMessagef("Invalid order by %q: expected a field name optionally followed by ASC or DESC, found %q", orderBy, strings.TrimSpace(part))
+		}
+
+		field, ok := fields.resolve(words[0])
+		if !ok {
+			return errortools.ErrorMessagef("Invalid order by %q: unknown field %s of %s", orderBy, words[0], fields.typeName)
+		}
+
+		s := sortField{field: field}
+		if len(words) == 2 {
+			switch strings.ToUpper(words[1]) {
+			case "ASC":
+			case "DESC":
+				s.descending = true
+			default:
+				return errortools.ErrorMessagef("Invalid order by %q: expected ASC or DESC after %s, found %q", orderBy, words[0], words[1])
+			}
+		}
+		sortFields = append(sortFields, s)
+	}
+
+	type sortRow struct {
+		record T
+		keys   []*string
+	}
+
+	rows := make([]sortRow, len(records))
+	for i := range records {
+		r, e := recordFields(&records[i])
+		if e != nil {
+			return e
+		}
+
+		rows[i].record = records[i]
+		for _, s := range sortFields {
+			var key *string
+			if values, ok := s.field.values(r); ok {
+				key = &values[0]
+			}
+			rows[i].keys = append(rows[i].keys, key)
+		}
+	}
+
+	slices.SortStableFunc(rows, func(a, b sortRow) int {
+		for i, s := range sortFields {
+			ka, kb := a.keys[i], b.keys[i]
+			switch {
+			case ka == nil && kb == nil:
+				continue
+			case ka == nil:
+				return 1
+			case kb == nil:
+				return -1
+			}
+
+			c := compareValues(*ka, *kb)
+			if s.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	for i := range rows {
+		records[i] = rows[i].record
+	}
+
+	return nil
+}
+
+// ProjectRecords returns the values of specific fields of records, by JSON name, or
+// FieldName for custom fields, fields without a value are nil. Numbers are returned as
+// json.Number.
+//
+func ProjectRecords[T any](records []T, fieldNames ...string) ([]map[string]interface{}, *errortools.Error) {
+	fields, e := recordFieldIndex(reflect.TypeFor[*T]())
+	if e != nil {
+		return nil, e
+	}
+
+	refs := []fieldRef{}
+	for _, fieldName := range fieldNames {
+		field, ok := fields.resolve(fieldName)
+		if !ok {
+			return nil, errortools.ErrorMessagef("Unknown field %s of %s", fieldName, fields.typeName)
+		}
+		refs = append(refs, field)
+	}
+
+	projected := []map[string]interface{}{}
+	for i := range records {
+		r, e := recordFields(&records[i])
+		if e != nil {
+			return nil, e
+		}
+
+		values := make(map[string]interface{})
+		for _, field := range refs {
+			raw := field.raw(r)
+
+			var value interface{}
+			if raw != nil {
+				decoder := json.NewDecoder(bytes.NewReader(raw))
+				decoder.UseNumber()
+				err := decoder.Decode(&value)
+				if err != nil {
+					return nil, errortools.ErrorMessage(err)
+				}
+			}
+			values[field.name] = value
+		}
+		projected = append(projected, values)
+	}
+
+	return projected, nil
+}
+
+// recordFields returns the fields of a record by JSON name
+//
+func recordFields(record any) (queryRecord, *errortools.Error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	r := queryRecord{}
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, errortools.ErrorMessagef("Cannot read fields of %T: %s", record, err.Error())
+	}
+
+	return r, nil
+}
+
+// fieldIndex maps the lower case JSON and Go names of the fields of a type to their JSON name
+//
+type fieldIndex struct {
+	typeName string
+	names    map[string]string
+}
+
+var fieldIndexes sync.Map
+
+func recordFieldIndex(t reflect.Type) (*fieldIndex, *errortools.Error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errortools.ErrorMessagef("Cannot filter records of type %s, records must be structs", t)
+	}
+
+	if index, ok := fieldIndexes.Load(t); ok {
+		return index.(*fieldIndex), nil
+	}
+
+	index := fieldIndex{typeName: t.Name(), names: make(map[string]string)}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		index.names[strings.ToLower(name)] = name
+		if _, ok := index.names[strings.ToLower(field.Name)]; !ok {
+			index.names[strings.ToLower(field.Name)] = name
+		}
+	}
+
+	i, _ := fieldIndexes.LoadOrStore(t, &index)
+
+	return i.(*fieldIndex), nil
+}
+
+// fieldRef is a field or, if custom is set, custom field of a record
+//
+type fieldRef struct {
+	name   string
+	custom bool
+}
+
+func (index *fieldIndex) resolve(fieldName string) (fieldRef, bool) {
+	if len(fieldName) > len(customFieldsPrefix) && strings.EqualFold(fieldName[:len(customFieldsPrefix)], customFieldsPrefix) {
+		return fieldRef{name: fieldName[len(customFieldsPrefix):], custom: true}, true
+	}
+
+	if name, ok := index.names[strings.ToLower(fieldName)]; ok {
+		return fieldRef{name: name}, true
+	}
+
+	if strings.HasSuffix(strings.ToLower(fieldName), customObjectSuffix) {
+		return fieldRef{name: fieldName, custom: true}, true
+	}
+
+	return fieldRef{}, false
+}
+
+// raw returns the JSON value of the field, nil if it has none
+//
+func (field fieldRef) raw(record queryRecord) json.RawMessage {
+	value := record[field.name]
+
+	if field.custom {
+		customFields := CustomFields{}
+		_ = json.Unmarshal(record["CUSTOMFIELDS"], &customFields)
+
+		customFieldRecord := customFields.get(field.name)
+		if customFieldRecord == nil {
+			return nil
+		}
+		value = customFieldRecord.FieldValue
+	}
+
+	if len(value) == 0 || string(value) == "null" {
+		return nil
+	}
+
+	return value
+}
+
+// values returns the value of the field as text, or the names of the tags for TAGS,
+// false if it has none
+//
+func (field fieldRef) values(record queryRecord) ([]string, bool) {
+	raw := field.raw(record)
+	if raw == nil {
+		return nil, false
+	}
+
+	if !field.custom && field.name == "TAGS" {
+		tags := []Tag{}
+		_ = json.Unmarshal(raw, &tags)
+
+		names := []string{}
+		for _, tag := range tags {
+			names = append(names, tag.TagName)
+		}
+		return names, len(names) > 0
+	}
+
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []string{text}, true
+	}
+
+	return []string{string(raw)}, true
+}
+
+// compareValues compares values as numbers if both are, otherwise as text ignoring case
+//
+func compareValues(a string, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(fa, fb)
+	}
+
+	return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+type expressionNode interface {
+	evaluate(record queryRecord, fields *fieldIndex) bool
+	check(x *Expression, fields *fieldIndex) *errortools.Error
+}
+
+type logicalNode struct {
+	and      bool
+	operands []expressionNode
+}
+
+func (n *logicalNode) evaluate(record queryRecord, fields *fieldIndex) bool {
+	for _, operand := range n.operands {
+		if operand.evaluate(record, fields) != n.and {
+			return !n.and
+		}
+	}
+
+	return n.and
+}
+
+func (n *logicalNode) check(x *Expression, fields *fieldIndex) *errortools.Error {
+	for _, operand := range n.operands {
+		if e := operand.check(x, fields); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+type notNode struct {
+	operand expressionNode
+}
+
+func (n *notNode) evaluate(record queryRecord, fields *fieldIndex) bool {
+	return !n.operand.evaluate(record, fields)
+}
+
+func (n *notNode) check(x *Expression, fields *fieldIndex) *errortools.Error {
+	return n.operand.check(x, fields)
+}
+
+type comparisonOperator string
+
+const (
+	operatorEqual        comparisonOperator = "="
+	operatorLess         comparisonOperator = "<"
+	operatorLessEqual    comparisonOperator = "<="
+	operatorGreater      comparisonOperator = ">"
+	operatorGreaterEqual comparisonOperator = ">="
+	operatorIn           comparisonOperator = "IN"
+	operatorContains     comparisonOperator = "CONTAINS"
+	operatorIsNull       comparisonOperator = "IS NULL"
+)
+
+// comparisonNode compares a field, negate turns = into !=, IN into NOT IN etc.
+//
+type comparisonNode struct {
+	fieldName string
+	position  int
+	operator  comparisonOperator
+	negate    bool
+	values    []string
+}
+
+func (n *comparisonNode) evaluate(record queryRecord, fields *fieldIndex) bool {
+	field, _ := fields.resolve(n.fieldName)
+
+	values, ok := field.values(record)
+	if n.operator == operatorIsNull {
+		return !ok != n.negate
+	}
+	if !ok {
+		return n.negate
+	}
+
+	for _, value := range values {
+		if n.compare(value) {
+			return !n.negate
+		}
+	}
+
+	return n.negate
+}
+
+func (n *comparisonNode) compare(value string) bool {
+	switch n.operator {
+	case operatorEqual:
+		return compareValues(value, n.values[0]) == 0
+	case operatorLess:
+		return compareValues(value, n.values[0]) < 0
+	case operatorLessEqual:
+		return compareValues(value, n.values[0]) <= 0
+	case operatorGreater:
+		return compareValues(value, n.values[0]) > 0
+	case operatorGreaterEqual:
+		return compareValues(value, n.values[0]) >= 0
+	case operatorIn:
+		return slices.ContainsFunc(n.values, func(v string) bool { return compareValues(value, v) == 0 })
+	case operatorContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(n.values[0]))
+	}
+
+	return false
+}
+
+func (n *comparisonNode) check(x *Expression, fields *fieldIndex) *errortools.Error {
+	if _, ok := fields.resolve(n.fieldName); !ok {
+		return errortools.ErrorMessagef("Invalid expression %q: unknown field %s of %s at position %v", x.source, n.fieldName, fields.typeName, n.position+1)
+	}
+
+	return nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEnd:
+		return "end of expression"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// keywords cannot be used as field names or unquoted values
+//
+var keywords = []string{"AND", "OR", "NOT", "IN", "IS", "NULL", "CONTAINS"}
+
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) isAnyKeyword() bool {
+	return slices.ContainsFunc(keywords, t.isKeyword)
+}
+
+func lexExpression(source string) ([]token, *errortools.Error) {
+	tokens := []token{}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", position: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", position: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", position: i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{kind: tokenOperator, text: "=", position: i})
+			i++
+		case c == '!' || c == '<' || c == '>':
+			text := string(c)
+			if i+1 < len(source) && (source[i+1] == '=' || (c == '<' && source[i+1] == '>')) {
+				text += string(source[i+1])
+			}
+			if text == "!" {
+				return nil, errortools.ErrorMessagef("Invalid expression %q: expected '!=' at position %v", source, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: text, position: i})
+			i += len(text)
+		case c == '\'' || c == '"':
+			var text strings.Builder
+			start := i
+			closed := false
+			for i++; i < len(source); i++ {
+				if source[i] != c {
+					text.WriteByte(source[i])
+					continue
+				}
+				if i+1 < len(source) && source[i+1] == c {
+					text.WriteByte(c)
+					i++
+					continue
+				}
+				closed = true
+				i++
+				break
+			}
+			if !closed {
+				return nil, errortools.ErrorMessagef("Invalid expression %q: unterminated string starting at position %v", source, start+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), position: start})
+		default:
+			start := i
+			for i < len(source) && !strings.ContainsRune(" \t\n\r(),=!<>'\"", rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: source[start:i], position: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, position: len(source)}), nil
+}
+
+type expressionParser struct {
+	source   string
+	tokens   []token
+	position int
+}
+
+func (p *expressionParser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *expressionParser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEnd {
+		p.position++
+	}
+
+	return t
+}
+
+func (p *expressionParser) errorf(t token, format string, a ...any) *errortools.Error {
+	return errortools.ErrorMessagef("Invalid expression %q: %s at position %v", p.source, fmt.Sprintf(format, a...), t.position+1)
+}
+
+func (p *expressionParser) parseOr() (expressionNode, *errortools.Error) {
+	return p.parseLogical(false)
+}
+
+// parseLogical parses operands separated by OR, or by AND if and is set
+//
+func (p *expressionParser) parseLogical(and bool) (expressionNode, *errortools.Error) {
+	keyword, parseOperand := "OR", func() (expressionNode, *errortools.Error) { return p.parseLogical(true) }
+	if and {
+		keyword, parseOperand = "AND", p.parseNot
+	}
+
+	operand, e := parseOperand()
+	if e != nil {
+		return nil, e
+	}
+
+	operands := []expressionNode{operand}
+	for p.peek().isKeyword(keyword) {
+		p.next()
+
+		operand, e := parseOperand()
+		if e != nil {
+			return nil, e
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operand, nil
+	}
+
+	return &logicalNode{and: and, operands: operands}, nil
+}
+
+func (p *expressionParser) parseNot() (expressionNode, *errortools.Error) {
+	if p.peek().isKeyword("NOT") {
+		p.next()
+
+		operand, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		return &notNode{operand: operand}, nil
+	}
+
+	if p.peek().kind == tokenLeftParen {
+		open := p.next()
+
+		n, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+
+		if t := p.next(); t.kind != tokenRightParen {
+			return nil, p.errorf(t, "expected ')' to close '(' at position %v, found %s", open.position+1, t)
+		}
+		return n, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (expressionNode, *errortools.Error) {
+	field := p.next()
+	if field.kind != tokenWord || field.isAnyKeyword() || !isFieldName(field.text) {
+		return nil, p.errorf(field, "expected a field name, found %s", field)
+	}
+
+	n := comparisonNode{fieldName: field.text, position: field.position}
+
+	t := p.next()
+	switch {
+	case t.isKeyword("IS"):
+		n.operator = operatorIsNull
+		if p.peek().isKeyword("NOT") {
+			p.next()
+			n.negate = true
+		}
+		if t := p.next(); !t.isKeyword("NULL") {
+			return nil, p.errorf(t, "expected NULL, found %s", t)
+		}
+		return &n, nil
+	case t.isKeyword("NOT"):
+		n.negate = true
+		t = p.next()
+		if !t.isKeyword("IN") && !t.isKeyword("CONTAINS") {
+			return nil, p.errorf(t, "expected IN or CONTAINS after NOT, found %s", t)
+		}
+	case t.kind == tokenOperator:
+		switch t.text {
+		case "!=", "<>":
+			n.operator, n.negate = operatorEqual, true
+		default:
+			n.operator = comparisonOperator(t.text)
+		}
+	case !t.isKeyword("IN") && !t.isKeyword("CONTAINS"):
+		return nil, p.errorf(t, "expected an operator after %s, found %s", field.text, t)
+	}
+
+	if t.isKeyword("CONTAINS") {
+		n.operator = operatorContains
+	}
+
+	if !t.isKeyword("IN") {
+		value, e := p.parseValue()
+		if e != nil {
+			return nil, e
+		}
+		n.values = []string{value}
+		return &n, nil
+	}
+
+	n.operator = operatorIn
+
+	if t := p.next(); t.kind != tokenLeftParen {
+		return nil, p.errorf(t, "expected '(' after IN, found %s", t)
+	}
+	for {
+		value, e := p.parseValue()
+		if e != nil {
+			return nil, e
+		}
+		n.values = append(n.values, value)
+
+		t := p.next()
+		if t.kind == tokenRightParen {
+			return &n, nil
+		}
+		if t.kind != tokenComma {
+			return nil, p.errorf(t, "expected ',' or ')' in list of values, found %s", t)
+		}
+	}
+}
+
+// isFieldName reports whether name is a name of letters, digits and underscores not
+// starting with a digit, optionally prefixed by CUSTOMFIELDS.
+//
+func isFieldName(name string) bool {
+	if len(name) > len(customFieldsPrefix) && strings.EqualFold(name[:len(customFieldsPrefix)], customFieldsPrefix) {
+		name = name[len(customFieldsPrefix):]
+	}
+
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9') {
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+func (p *expressionParser) parseValue() (string, *errortools.Error) {
+	t := p.next()
+
+	if t.isKeyword("NULL") {
+		return "", p.errorf(t, "use IS NULL or IS NOT NULL to compare with NULL, found %s", t)
+	}
+	if t.kind == tokenString || (t.kind == tokenWord && !t.isAnyKeyword()) {
+		return t.text, nil
+	}
+
+	return "", p.errorf(t, "expected a value, found %s", t)
+}
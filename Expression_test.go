@@ -0,0 +1,215 @@
+package insightly_test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		message    string
+	}{
+		{`OPPORTUNITY_NAME = 'Alpha`, "unterminated string starting at position 20"},
+		{`A NOT = 1`, "expected IN or CONTAINS after NOT, found '=' at position 7"},
+		{`A IN (1 2)`, "expected ',' or ')' in list of values, found '2' at position 9"},
+		{`A = 1)`, "expected AND or OR, found ')' at position 6"},
+		{`(A = 1`, "expected ')' to close '(' at position 1, found end of expression at position 7"},
+		{`A ! 1`, "expected '!=' at position 3"},
+		{`A = NULL`, "use IS NULL or IS NOT NULL to compare with NULL, found 'NULL' at position 5"},
+		{`A IS 1`, "expected NULL, found '1' at position 6"},
+		{`A = 1 AND`, "expected a field name, found end of expression at position 10"},
+		{`CUSTOMFIELDS. = 1`, "expected a field name, found 'CUSTOMFIELDS.' at position 1"},
+		{`A = 1 OR a.b.c = 1`, "expected a field name, found 'a.b.c' at position 10"},
+		{`1A = 1`, "expected a field name, found '1A' at position 1"},
+	}
+
+	for _, test := range tests {
+		_, e := insightly.ParseExpression(test.expression)
+		if e == nil {
+			t.Errorf("expected an error for %s", test.expression)
+			continue
+		}
+		if !strings.HasSuffix(e.Message(), test.message) {
+			t.Errorf("got %q for %s, want it to end in %q", e.Message(), test.expression, test.message)
+		}
+	}
+}
+
+// expressionRecords returns opportunities with a bid amount, state, region and tags, Gamma
+// has neither bid amount, region nor tags
+//
+func expressionRecords() []insightly.Opportunity {
+	opportunity := func(name string, state string, bidAmount *float64, region string, tagNames ...string) insightly.Opportunity {
+		o := insightly.Opportunity{OpportunityName: &name, OpportunityState: &state, BidAmount: bidAmount}
+		if region != "" {
+			o.CustomFields = &insightly.CustomFields{{FieldName: "Region__c", FieldValue: json.RawMessage(`"` + region + `"`)}}
+		}
+		if len(tagNames) > 0 {
+			tags := []insightly.Tag{}
+			for _, tagName := range tagNames {
+				tags = append(tags, insightly.Tag{TagName: tagName})
+			}
+			o.Tags = &tags
+		}
+		return o
+	}
+	nine, tenThousand := 9.0, 10000.0
+
+	return []insightly.Opportunity{
+		opportunity("Alpha", "OPEN", &nine, "EMEA", "hot", "q1"),
+		opportunity("beta", "WON", &tenThousand, "APAC", "cold"),
+		opportunity("Gamma", "OPEN", nil, ""),
+	}
+}
+
+func opportunityNames(opportunities []insightly.Opportunity) []string {
+	names := []string{}
+	for _, opportunity := range opportunities {
+		names = append(names, *opportunity.OpportunityName)
+	}
+
+	return names
+}
+
+func TestFilterRecords(t *testing.T) {
+	tests := []struct {
+		expression string
+		names      []string
+	}{
+		// numbers compare as numbers, text as text ignoring case
+		{`BID_AMOUNT > 10`, []string{"beta"}},
+		{`BID_AMOUNT <= 9`, []string{"Alpha"}},
+		{`OPPORTUNITY_NAME > 'alpha'`, []string{"beta", "Gamma"}},
+		{`OPPORTUNITY_NAME = ALPHA`, []string{"Alpha"}},
+		{`OpportunityName CONTAINS ET`, []string{"beta"}},
+		{`BID_AMOUNT != 9`, []string{"beta", "Gamma"}},
+		{`BID_AMOUNT <> 9 AND BID_AMOUNT IS NOT NULL`, []string{"beta"}},
+		{`OPPORTUNITY_STATE IN (OPEN, LOST)`, []string{"Alpha", "Gamma"}},
+		{`OPPORTUNITY_STATE NOT IN ("open")`, []string{"beta"}},
+		{`BID_AMOUNT IS NULL`, []string{"Gamma"}},
+		{`TAGS = hot`, []string{"Alpha"}},
+		{`TAGS IN (cold, q1)`, []string{"Alpha", "beta"}},
+		{`TAGS IS NULL`, []string{"Gamma"}},
+		{`CUSTOMFIELDS.Region__c = emea`, []string{"Alpha"}},
+		{`Region__c NOT CONTAINS pac`, []string{"Alpha", "Gamma"}},
+		{`Region__c IS NULL OR Region__c = APAC`, []string{"beta", "Gamma"}},
+		{`OPPORTUNITY_STATE = OPEN AND NOT (TAGS = hot)`, []string{"Gamma"}},
+	}
+
+	for _, test := range tests {
+		filtered, e := insightly.FilterRecords(expressionRecords(), test.expression)
+		if e != nil {
+			t.Errorf("got %s for %s", e.Message(), test.expression)
+			continue
+		}
+		if names := opportunityNames(filtered); !slices.Equal(names, test.names) {
+			t.Errorf("got %v for %s, want %v", names, test.expression, test.names)
+		}
+	}
+}
+
+func TestFilterRecordsUnknownField(t *testing.T) {
+	_, e := insightly.FilterRecords(expressionRecords(), `BID_AMOUNT > 1 AND BID_AMOUT < 10`)
+	if e == nil || !strings.HasSuffix(e.Message(), "unknown field BID_AMOUT of Opportunity at position 20") {
+		t.Errorf("got %v, want an unknown field error", e)
+	}
+
+	x, e := insightly.ParseExpression(`NAME = 1`)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if _, e := x.Matches(expressionRecords()[0]); e == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, e := x.Matches(nil); e == nil {
+		t.Error("expected an error for a nil record")
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	records := expressionRecords()
+
+	e := insightly.SortRecords(records, "BID_AMOUNT DESC")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if names := opportunityNames(records); !slices.Equal(names, []string{"beta", "Alpha", "Gamma"}) {
+		t.Errorf("got %v, want records without a value last", names)
+	}
+
+	e = insightly.SortRecords(records, "OPPORTUNITY_STATE, OPPORTUNITY_NAME desc")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if names := opportunityNames(records); !slices.Equal(names, []string{"Gamma", "Alpha", "beta"}) {
+		t.Errorf("got %v, want by state then name descending", names)
+	}
+}
+
+func TestSortRecordsErrors(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		message string
+	}{
+		{"", `expected a field name optionally followed by ASC or DESC, found ""`},
+		{"BID_AMOUNT,", `expected a field name optionally followed by ASC or DESC, found ""`},
+		{"BID_AMOUNT DESC FIRST", `expected a field name optionally followed by ASC or DESC, found "BID_AMOUNT DESC FIRST"`},
+		{"BID_AMOUNT UP", `expected ASC or DESC after BID_AMOUNT, found "UP"`},
+		{"AMOUNT", "unknown field AMOUNT of Opportunity"},
+	}
+
+	for _, test := range tests {
+		records := expressionRecords()
+
+		e := insightly.SortRecords(records, test.orderBy)
+		if e == nil {
+			t.Errorf("expected an error for %q", test.orderBy)
+			continue
+		}
+		if !strings.HasSuffix(e.Message(), test.message) {
+			t.Errorf("got %q for %q, want it to end in %q", e.Message(), test.orderBy, test.message)
+		}
+		if names := opportunityNames(records); !slices.Equal(names, []string{"Alpha", "beta", "Gamma"}) {
+			t.Errorf("got %v, want the records unsorted after an error", names)
+		}
+	}
+
+	e := insightly.SortRecords([]int{2, 1}, "A")
+	if e == nil {
+		t.Error("expected an error for records that are not structs")
+	}
+}
+
+func TestProjectRecords(t *testing.T) {
+	projected, e := insightly.ProjectRecords(expressionRecords(), "OPPORTUNITY_NAME", "BidAmount", "Region__c")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(projected) != 3 {
+		t.Fatalf("got %v records, want 3", len(projected))
+	}
+	if alpha := projected[0]; alpha["OPPORTUNITY_NAME"] != "Alpha" || alpha["BID_AMOUNT"] != json.Number("9") || alpha["Region__c"] != "EMEA" {
+		t.Errorf("got %v, want the fields of Alpha", alpha)
+	}
+	if gamma := projected[2]; gamma["BID_AMOUNT"] != nil || gamma["Region__c"] != nil {
+		t.Errorf("got %v, want nil for fields without a value", gamma)
+	}
+}
+
+func TestProjectRecordsErrors(t *testing.T) {
+	_, e := insightly.ProjectRecords(expressionRecords(), "OPPORTUNITY_NAME", "AMOUNT")
+	if e == nil || e.Message() != "Unknown field AMOUNT of Opportunity" {
+		t.Errorf("got %v, want an unknown field error", e)
+	}
+
+	_, e = insightly.ProjectRecords([]string{"a"}, "A")
+	if e == nil {
+		t.Error("expected an error for records that are not structs")
+	}
+}
@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -24,12 +25,20 @@ type FileAttachment struct {
 
 // GetFileAttachment returns a specific file attachments as a slice of bytes
 func (service *Service) GetFileAttachment(fileId int64) ([]byte, *errortools.Error) {
+	return service.GetFileAttachmentWithContext(context.Background(), fileId)
+}
+
+// GetFileAttachmentWithContext is the context-aware variant of GetFileAttachment
+func (service *Service) GetFileAttachmentWithContext(ctx context.Context, fileId int64) ([]byte, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetFileAttachment")
+	defer span.End()
+
 	requestConfig := go_http.RequestConfig{
 		Method: http.MethodGet,
 		Url:    service.url(fmt.Sprintf("fileattachments/%v", fileId)),
 	}
 
-	_, response, e := service.httpRequest(&requestConfig)
+	_, response, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
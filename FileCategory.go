@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // FileCategory stores FileCategory from Service
@@ -18,66 +16,51 @@ type FileCategory struct {
 	BackgroundColor string `json:"BACKGROUND_COLOR"`
 }
 
-type GetFileCategoriesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var fileCategoryResource = &Resource[FileCategory]{
+	Endpoint: "FileCategories",
+	ID:       func(f *FileCategory) int64 { return f.CategoryID },
 }
 
+type GetFileCategoriesConfig = PagingConfig
+
 // GetFileCategories returns all fileCategories
 //
 func (service *Service) GetFileCategories(config *GetFileCategoriesConfig) (*[]FileCategory, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "FileCategories"
-	fileCategories := []FileCategory{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		fileCategoriesBatch := []FileCategory{}
+	return service.GetFileCategoriesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &fileCategoriesBatch,
-		}
+// GetFileCategoriesWithContext is the context-aware variant of GetFileCategories
+//
+func (service *Service) GetFileCategoriesWithContext(ctx context.Context, config *GetFileCategoriesConfig) (*[]FileCategory, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetFileCategories")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return fileCategoryResource.List(ctx, service, config)
+}
 
-		fileCategories = append(fileCategories, fileCategoriesBatch...)
+// GetFileCategoriesPage returns a single page of fileCategories and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetFileCategoriesPage(config *GetFileCategoriesConfig) (*[]FileCategory, *Cursor, *errortools.Error) {
+	return service.GetFileCategoriesPageWithContext(context.Background(), config)
+}
 
-		if len(fileCategoriesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetFileCategoriesPageWithContext is the context-aware variant of GetFileCategoriesPage
+//
+func (service *Service) GetFileCategoriesPageWithContext(ctx context.Context, config *GetFileCategoriesConfig) (*[]FileCategory, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetFileCategoriesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return fileCategoryResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &fileCategories, nil
-		}
-	}
+// FileCategoriesSeq returns an iterator over all fileCategories, fetching pages on demand
+//
+func (service *Service) FileCategoriesSeq(config *GetFileCategoriesConfig) iter.Seq2[FileCategory, *errortools.Error] {
+	return service.FileCategoriesSeqWithContext(context.Background(), config)
+}
 
-	return &fileCategories, nil
+// FileCategoriesSeqWithContext is the context-aware variant of FileCategoriesSeq
+//
+func (service *Service) FileCategoriesSeqWithContext(ctx context.Context, config *GetFileCategoriesConfig) iter.Seq2[FileCategory, *errortools.Error] {
+	return seq(ctx, service, "FileCategoriesSeq", fileCategoryResource, config)
 }
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package insightly
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+var errFileLockNotSupported = errors.New("file locking is not supported on this platform")
+
+func lockFile(ctx context.Context, file *os.File, exclusive bool) error {
+	return errFileLockNotSupported
+}
+
+func unlockFile(file *os.File) error {
+	return errFileLockNotSupported
+}
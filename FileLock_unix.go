@@ -0,0 +1,43 @@
+//go:build unix
+
+package insightly
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileLockPollInterval is the wait between attempts to take a lock held by another process
+const fileLockPollInterval = 10 * time.Millisecond
+
+// lockFile locks file, polling while the lock is held elsewhere until ctx is done
+func lockFile(ctx context.Context, file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case !errors.Is(err, syscall.EWOULDBLOCK):
+			return err
+		}
+
+		err = sleepWithContext(ctx, fileLockPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,102 @@
+//go:build unix
+
+package insightly_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestFileRateLimitStoresShareFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	// every goroutine uses a store of its own, as separate processes would, so only
+	// the file lock keeps a Load from reading a Save half way
+	wg := sync.WaitGroup{}
+	errs := make(chan string, 8)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			store := insightly.NewFileRateLimitStore(path)
+			limit := int64(1000)
+			for j := range 50 {
+				remaining := int64(i*1000 + j)
+				e := store.Save(context.Background(), insightly.RateLimit{Limit: &limit, Remaining: &remaining})
+				if e != nil {
+					errs <- e.Message()
+					return
+				}
+
+				rateLimit, e := store.Load(context.Background())
+				if e != nil {
+					errs <- e.Message()
+					return
+				}
+				if rateLimit.Limit == nil || *rateLimit.Limit != limit || rateLimit.Remaining == nil {
+					errs <- "loaded an incomplete rate limit"
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestFileRateLimitStoreLockHonoursContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	store := insightly.NewFileRateLimitStore(path)
+
+	e := store.Save(context.Background(), insightly.RateLimit{})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	// hold the lock as another process would
+	file, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fd := int(file.Fd())
+	err = syscall.Flock(fd, syscall.LOCK_EX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, e = store.Load(ctx)
+	if e == nil {
+		t.Fatal("expected an error while the file is locked")
+	}
+	if elapsed := time.Since(start); elapsed > promptly {
+		t.Errorf("got the error after %v, want it once ctx is done", elapsed)
+	}
+
+	// the store takes the lock once it is released
+	time.AfterFunc(50*time.Millisecond, func() { _ = syscall.Flock(fd, syscall.LOCK_UN) })
+
+	ctx, cancel = context.WithTimeout(context.Background(), promptly)
+	defer cancel()
+
+	e = store.Save(ctx, insightly.RateLimit{})
+	if e != nil {
+		t.Errorf("expected the lock after it was released, got %s", e.Message())
+	}
+}
@@ -0,0 +1,403 @@
+package insightly
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+const (
+	defaultGraphMaxDepth    int = 2
+	defaultGraphConcurrency int = 4
+)
+
+// GraphNodeID identifies a record in a Graph
+//
+type GraphNodeID struct {
+	ObjectName ObjectName
+	ObjectID   int64
+}
+
+func (id GraphNodeID) String() string {
+	return fmt.Sprintf("%s/%v", id.ObjectName, id.ObjectID)
+}
+
+// GraphNode is a record in a Graph, Depth is the number of links between it and the root
+//
+type GraphNode struct {
+	GraphNodeID
+	Depth int
+}
+
+// GraphEdge is a link between two records in a Graph, Label is the title of its
+// relationship in the direction From to To, or its role if it has no relationship
+//
+type GraphEdge struct {
+	From           GraphNodeID
+	To             GraphNodeID
+	LinkID         int64
+	Label          string
+	Role           *string
+	Details        *string
+	RelationshipID *int64
+}
+
+// Graph holds the records connected to a root record through links, as built by BuildGraph
+//
+type Graph struct {
+	Root      GraphNodeID
+	nodes     map[GraphNodeID]*GraphNode
+	edges     []GraphEdge
+	edgeKeys  map[string]bool
+	adjacency map[GraphNodeID][]int
+}
+
+type BuildGraphConfig struct {
+	RootObjectName ObjectName
+	RootObjectID   int64
+	// MaxDepth is the maximum number of links between the root and a record in the
+	// graph, it defaults to 2
+	MaxDepth int
+	// Concurrency is the maximum number of concurrent requests, it defaults to 4
+	Concurrency int
+	// ObjectNames restricts the graph to records of these types, besides the root
+	ObjectNames []ObjectName
+}
+
+// BuildGraph walks the links of a record breadth-first up to MaxDepth and returns the
+// graph of the records it finds. Edges are labeled with the ForwardTitle or ReverseTitle
+// of their Relationship. Records of types that have no links endpoint, such as products,
+// are included but not walked. BuildGraph fails if ServiceConfig.MaxRowCount truncates
+// the read of the relationships.
+//
+func (service *Service) BuildGraph(config *BuildGraphConfig) (*Graph, *errortools.Error) {
+	return service.BuildGraphWithContext(context.Background(), config)
+}
+
+// BuildGraphWithContext is the context-aware variant of BuildGraph
+//
+func (service *Service) BuildGraphWithContext(ctx context.Context, config *BuildGraphConfig) (*Graph, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "BuildGraph")
+	defer span.End()
+
+	if config == nil {
+		return nil, errortools.ErrorMessage("BuildGraphConfig must not be nil")
+	}
+
+	maxDepth := config.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultGraphMaxDepth
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultGraphConcurrency
+	}
+
+	relationships, e := relationshipResource.listAll(ctx, service, nil)
+	if e != nil {
+		return nil, e
+	}
+
+	relationshipsByID := make(map[int64]*Relationship)
+	for i := range *relationships {
+		relationshipsByID[(*relationships)[i].RelationshipID] = &(*relationships)[i]
+	}
+
+	root := GraphNodeID{ObjectName: config.RootObjectName, ObjectID: config.RootObjectID}
+	graph := newGraph(root)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frontier := []GraphNodeID{root}
+
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		links := make([]*[]Link, len(frontier))
+
+		var wg sync.WaitGroup
+		var once sync.Once
+		var firstError *errortools.Error
+		semaphore := make(chan struct{}, concurrency)
+
+		for i, id := range frontier {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				l, e := getLinks(ctx, service, id.ObjectName, id.ObjectID)
+				if e != nil {
+					once.Do(func() {
+						firstError = e
+						cancel()
+					})
+					return
+				}
+				links[i] = l
+			}()
+		}
+		wg.Wait()
+
+		if firstError != nil {
+			return nil, firstError
+		}
+		// ctx was done before the links of every record were read
+		if err := ctx.Err(); err != nil {
+			return nil, errortools.ErrorMessage(err)
+		}
+
+		next := []GraphNodeID{}
+
+		for i, id := range frontier {
+			for _, link := range *links[i] {
+				to, ok := linkedNode(id, link)
+				if !ok || !config.includes(to.ObjectName) {
+					continue
+				}
+
+				if _, ok := graph.nodes[to]; !ok {
+					graph.nodes[to] = &GraphNode{GraphNodeID: to, Depth: depth}
+					if _, e := to.ObjectName.linksEndpoint(); e == nil {
+						next = append(next, to)
+					}
+				}
+
+				graph.addEdge(id, to, link, relationshipsByID)
+			}
+		}
+
+		frontier = next
+	}
+
+	return graph, nil
+}
+
+func (config *BuildGraphConfig) includes(objectName ObjectName) bool {
+	return len(config.ObjectNames) == 0 || slices.Contains(config.ObjectNames, objectName)
+}
+
+// linkedNode returns the record at the other end of a link of from
+//
+func linkedNode(from GraphNodeID, link Link) (GraphNodeID, bool) {
+	if link.LinkObjectName == nil || link.LinkObjectID == nil {
+		return GraphNodeID{}, false
+	}
+
+	to := GraphNodeID{ObjectName: ObjectName(*link.LinkObjectName), ObjectID: *link.LinkObjectID}
+	if to == from {
+		return GraphNodeID{}, false
+	}
+
+	return to, true
+}
+
+func newGraph(root GraphNodeID) *Graph {
+	return &Graph{
+		Root:      root,
+		nodes:     map[GraphNodeID]*GraphNode{root: {GraphNodeID: root}},
+		edgeKeys:  make(map[string]bool),
+		adjacency: make(map[GraphNodeID][]int),
+	}
+}
+
+// addEdge adds the edge of a link once, links are returned by the records at both ends
+//
+func (graph *Graph) addEdge(from GraphNodeID, to GraphNodeID, link Link, relationships map[int64]*Relationship) {
+	key := fmt.Sprintf("%s-%s", min(from.String(), to.String()), max(from.String(), to.String()))
+	if link.LinkID != nil {
+		key = fmt.Sprintf("%v", *link.LinkID)
+	}
+	if graph.edgeKeys[key] {
+		return
+	}
+	graph.edgeKeys[key] = true
+
+	edge := GraphEdge{
+		From:           from,
+		To:             to,
+		Role:           link.Role,
+		Details:        link.Details,
+		RelationshipID: link.RelationshipID,
+	}
+	if link.LinkID != nil {
+		edge.LinkID = *link.LinkID
+	}
+
+	if link.RelationshipID != nil {
+		if relationship, ok := relationships[*link.RelationshipID]; ok {
+			if link.IsForward == nil || *link.IsForward {
+				edge.Label = relationship.ForwardTitle
+			} else {
+				edge.Label = relationship.ReverseTitle
+			}
+		}
+	}
+	if edge.Label == "" && link.Role != nil {
+		edge.Label = *link.Role
+	}
+
+	graph.edges = append(graph.edges, edge)
+	graph.adjacency[from] = append(graph.adjacency[from], len(graph.edges)-1)
+	graph.adjacency[to] = append(graph.adjacency[to], len(graph.edges)-1)
+}
+
+// Node returns the node of a record, false if the record is not in the graph
+//
+func (graph *Graph) Node(id GraphNodeID) (GraphNode, bool) {
+	node, ok := graph.nodes[id]
+	if !ok {
+		return GraphNode{}, false
+	}
+
+	return *node, true
+}
+
+// Nodes returns all nodes ordered by depth, object name and id
+//
+func (graph *Graph) Nodes() []GraphNode {
+	nodes := []GraphNode{}
+	for _, node := range graph.nodes {
+		nodes = append(nodes, *node)
+	}
+
+	slices.SortFunc(nodes, func(a, b GraphNode) int {
+		return cmp.Or(
+			cmp.Compare(a.Depth, b.Depth),
+			cmp.Compare(a.ObjectName, b.ObjectName),
+			cmp.Compare(a.ObjectID, b.ObjectID),
+		)
+	})
+
+	return nodes
+}
+
+// NodesOf returns the nodes of records of a specific type, e.g. all contacts
+//
+func (graph *Graph) NodesOf(objectName ObjectName) []GraphNode {
+	nodes := []GraphNode{}
+	for _, node := range graph.Nodes() {
+		if node.ObjectName == objectName {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
+// Edges returns all edges in the order they were found
+//
+func (graph *Graph) Edges() []GraphEdge {
+	return slices.Clone(graph.edges)
+}
+
+// EdgesOf returns the edges from or to a record
+//
+func (graph *Graph) EdgesOf(id GraphNodeID) []GraphEdge {
+	edges := []GraphEdge{}
+	for _, i := range graph.adjacency[id] {
+		edges = append(edges, graph.edges[i])
+	}
+
+	return edges
+}
+
+// Neighbours returns the records linked to a record
+//
+func (graph *Graph) Neighbours(id GraphNodeID) []GraphNodeID {
+	neighbours := []GraphNodeID{}
+	for _, edge := range graph.EdgesOf(id) {
+		if edge.From == id {
+			neighbours = append(neighbours, edge.To)
+		} else {
+			neighbours = append(neighbours, edge.From)
+		}
+	}
+
+	return neighbours
+}
+
+// DOT returns the graph in the Graphviz DOT language
+//
+func (graph *Graph) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph insightly {\n")
+	for _, node := range graph.Nodes() {
+		fmt.Fprintf(&b, "\t%q [label=%q];\n", node.String(), fmt.Sprintf("%s %v", node.ObjectName, node.ObjectID))
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&b, "\t%q -> %q", edge.From.String(), edge.To.String())
+		if edge.Label != "" {
+			fmt.Fprintf(&b, " [label=%q]", edge.Label)
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+type graphNodeJSON struct {
+	ObjectName ObjectName `json:"object_name"`
+	ObjectID   int64      `json:"object_id"`
+	Depth      int        `json:"depth"`
+}
+
+type graphEdgeJSON struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	LinkID         int64   `json:"link_id,omitempty"`
+	Label          string  `json:"label,omitempty"`
+	Role           *string `json:"role,omitempty"`
+	Details        *string `json:"details,omitempty"`
+	RelationshipID *int64  `json:"relationship_id,omitempty"`
+}
+
+// MarshalJSON exports the graph as an object with root, nodes and edges, nodes are
+// referred to as "ObjectName/ObjectID"
+//
+func (graph *Graph) MarshalJSON() ([]byte, error) {
+	nodes := []graphNodeJSON{}
+	for _, node := range graph.Nodes() {
+		nodes = append(nodes, graphNodeJSON{
+			ObjectName: node.ObjectName,
+			ObjectID:   node.ObjectID,
+			Depth:      node.Depth,
+		})
+	}
+
+	edges := []graphEdgeJSON{}
+	for _, edge := range graph.edges {
+		edges = append(edges, graphEdgeJSON{
+			From:           edge.From.String(),
+			To:             edge.To.String(),
+			LinkID:         edge.LinkID,
+			Label:          edge.Label,
+			Role:           edge.Role,
+			Details:        edge.Details,
+			RelationshipID: edge.RelationshipID,
+		})
+	}
+
+	return json.Marshal(struct {
+		Root  string          `json:"root"`
+		Nodes []graphNodeJSON `json:"nodes"`
+		Edges []graphEdgeJSON `json:"edges"`
+	}{
+		Root:  graph.Root.String(),
+		Nodes: nodes,
+		Edges: edges,
+	})
+}
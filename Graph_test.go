@@ -0,0 +1,258 @@
+package insightly_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// seedGraph seeds contact 1 as an employee of organisation 1 and owner of project 1,
+// contact 2 of organisation 1 and opportunity 1 of contact 2
+//
+func seedGraph(server *insightlytest.Server) {
+	server.Seed("Contacts", map[string]any{"FIRST_NAME": "Jane"}, map[string]any{"FIRST_NAME": "John"})
+	server.Seed("Organisations", map[string]any{"ORGANISATION_NAME": "Acme"})
+	server.Seed("Projects", map[string]any{"PROJECT_NAME": "Launch"})
+	server.Seed("Opportunities", map[string]any{"OPPORTUNITY_NAME": "Website"})
+	relationshipID := server.Seed("Relationships", &insightly.Relationship{ForwardTitle: "Employer of", ReverseTitle: "Employee of"})[0]
+
+	link := func(objectName insightly.ObjectName, objectID int64, linkObjectName insightly.ObjectName, linkObjectID int64) insightly.Link {
+		l := *insightly.NewLink(linkObjectName, linkObjectID)
+		name := string(objectName)
+		l.ObjectName = &name
+		l.ObjectID = &objectID
+		return l
+	}
+
+	isForward := true
+	employee := link(insightly.ObjectNameOrganisation, 1, insightly.ObjectNameContact, 1)
+	employee.RelationshipID = &relationshipID
+	employee.IsForward = &isForward
+	server.SeedLink(employee)
+
+	role := "Owner"
+	owner := link(insightly.ObjectNameContact, 1, insightly.ObjectNameProject, 1)
+	owner.Role = &role
+	server.SeedLink(owner)
+
+	server.SeedLink(link(insightly.ObjectNameOrganisation, 1, insightly.ObjectNameContact, 2))
+	server.SeedLink(link(insightly.ObjectNameContact, 2, insightly.ObjectNameOpportunity, 1))
+}
+
+func graphNode(objectName insightly.ObjectName, objectID int64) insightly.GraphNodeID {
+	return insightly.GraphNodeID{ObjectName: objectName, ObjectID: objectID}
+}
+
+func TestBuildGraphDepth(t *testing.T) {
+	service, server := newTestService(t, nil)
+	seedGraph(server)
+
+	graph, e := service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	want := []insightly.GraphNode{
+		{GraphNodeID: graphNode(insightly.ObjectNameContact, 1), Depth: 0},
+		{GraphNodeID: graphNode(insightly.ObjectNameOrganisation, 1), Depth: 1},
+		{GraphNodeID: graphNode(insightly.ObjectNameProject, 1), Depth: 1},
+		{GraphNodeID: graphNode(insightly.ObjectNameContact, 2), Depth: 2},
+	}
+	nodes := graph.Nodes()
+	if len(nodes) != len(want) {
+		t.Fatalf("got nodes %v, want %v", nodes, want)
+	}
+	for i, node := range nodes {
+		if node != want[i] {
+			t.Errorf("got node %v at depth %v, want %v at depth %v", node.GraphNodeID, node.Depth, want[i].GraphNodeID, want[i].Depth)
+		}
+	}
+	if edges := graph.Edges(); len(edges) != 3 {
+		t.Errorf("got %v edges, want 3", len(edges))
+	}
+
+	graph, e = service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1, MaxDepth: 3})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if node, ok := graph.Node(graphNode(insightly.ObjectNameOpportunity, 1)); !ok || node.Depth != 3 {
+		t.Errorf("got opportunity at depth %v, want it at depth 3", node.Depth)
+	}
+	if edges := graph.Edges(); len(edges) != 4 {
+		t.Errorf("got %v edges, want every link once", len(edges))
+	}
+	if neighbours := graph.Neighbours(graphNode(insightly.ObjectNameOrganisation, 1)); len(neighbours) != 2 {
+		t.Errorf("got neighbours %v of the organisation, want both contacts", neighbours)
+	}
+}
+
+func TestBuildGraphObjectNames(t *testing.T) {
+	service, server := newTestService(t, nil)
+	seedGraph(server)
+
+	graph, e := service.BuildGraph(&insightly.BuildGraphConfig{
+		RootObjectName: insightly.ObjectNameContact,
+		RootObjectID:   1,
+		MaxDepth:       3,
+		ObjectNames:    []insightly.ObjectName{insightly.ObjectNameContact, insightly.ObjectNameOrganisation},
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if nodes := graph.Nodes(); len(nodes) != 3 {
+		t.Errorf("got nodes %v, want the contacts and the organisation", nodes)
+	}
+	if nodes := graph.NodesOf(insightly.ObjectNameProject); len(nodes) != 0 {
+		t.Errorf("got projects %v, want none", nodes)
+	}
+	if nodes := graph.NodesOf(insightly.ObjectNameOpportunity); len(nodes) != 0 {
+		t.Errorf("got opportunities %v, want none", nodes)
+	}
+}
+
+func TestBuildGraphEdgeLabels(t *testing.T) {
+	service, server := newTestService(t, nil)
+	seedGraph(server)
+
+	graph, e := service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1, MaxDepth: 1})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	labels := map[insightly.GraphNodeID]string{}
+	for _, edge := range graph.EdgesOf(graph.Root) {
+		labels[edge.To] = edge.Label
+	}
+	if label := labels[graphNode(insightly.ObjectNameOrganisation, 1)]; label != "Employee of" {
+		t.Errorf("got label %q, want the reverse title of the relationship", label)
+	}
+	if label := labels[graphNode(insightly.ObjectNameProject, 1)]; label != "Owner" {
+		t.Errorf("got label %q, want the role", label)
+	}
+
+	// seen from the organisation the relationship is forward
+	graph, e = service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameOrganisation, RootObjectID: 1, MaxDepth: 1})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	for _, edge := range graph.EdgesOf(graph.Root) {
+		if edge.To == graphNode(insightly.ObjectNameContact, 1) && edge.Label != "Employer of" {
+			t.Errorf("got label %q, want the forward title of the relationship", edge.Label)
+		}
+	}
+}
+
+func TestGraphDOT(t *testing.T) {
+	service, server := newTestService(t, nil)
+	seedGraph(server)
+
+	graph, e := service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1, MaxDepth: 1})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	want := strings.Join([]string{
+		"digraph insightly {",
+		"\t\"Contact/1\" [label=\"Contact 1\"];",
+		"\t\"Organisation/1\" [label=\"Organisation 1\"];",
+		"\t\"Project/1\" [label=\"Project 1\"];",
+		"\t\"Contact/1\" -> \"Organisation/1\" [label=\"Employee of\"];",
+		"\t\"Contact/1\" -> \"Project/1\" [label=\"Owner\"];",
+		"}",
+		"",
+	}, "\n")
+	if dot := graph.DOT(); dot != want {
+		t.Errorf("got DOT\n%s\nwant\n%s", dot, want)
+	}
+}
+
+func TestGraphMarshalJSON(t *testing.T) {
+	service, server := newTestService(t, nil)
+	seedGraph(server)
+
+	graph, e := service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1, MaxDepth: 1})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	b, err := json.Marshal(graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported := struct {
+		Root  string `json:"root"`
+		Nodes []struct {
+			ObjectName string `json:"object_name"`
+			ObjectID   int64  `json:"object_id"`
+			Depth      int    `json:"depth"`
+		} `json:"nodes"`
+		Edges []struct {
+			From           string `json:"from"`
+			To             string `json:"to"`
+			LinkID         int64  `json:"link_id"`
+			Label          string `json:"label"`
+			Role           string `json:"role"`
+			RelationshipID int64  `json:"relationship_id"`
+		} `json:"edges"`
+	}{}
+	err = json.Unmarshal(b, &exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported.Root != "Contact/1" || len(exported.Nodes) != 3 || len(exported.Edges) != 2 {
+		t.Fatalf("got %s", b)
+	}
+	if node := exported.Nodes[1]; node.ObjectName != "Organisation" || node.ObjectID != 1 || node.Depth != 1 {
+		t.Errorf("got node %+v, want organisation 1 at depth 1", node)
+	}
+	employee, owner := exported.Edges[0], exported.Edges[1]
+	if employee.From != "Contact/1" || employee.To != "Organisation/1" || employee.LinkID == 0 || employee.Label != "Employee of" || employee.RelationshipID != 1 {
+		t.Errorf("got edge %+v, want the employee link", employee)
+	}
+	if owner.To != "Project/1" || owner.Role != "Owner" {
+		t.Errorf("got edge %+v, want the owner link", owner)
+	}
+}
+
+func TestBuildGraphCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel once the links of the root are read, before the next depth is walked
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.AfterResponse = func(_ context.Context, info insightly.ResponseInfo) {
+			if strings.HasSuffix(info.Endpoint, "/Links") {
+				cancel()
+			}
+		}
+	})
+	seedGraph(server)
+
+	start := time.Now()
+	_, e := service.BuildGraphWithContext(ctx, &insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1})
+	assertCanceled(t, ctx, e, start)
+}
+
+func TestBuildGraphTruncatedRelationships(t *testing.T) {
+	service, server := truncatingTestService(t)
+	seedGraph(server)
+
+	relationships := []any{}
+	for range pageSize {
+		relationships = append(relationships, &insightly.Relationship{ForwardTitle: "Partner of", ReverseTitle: "Partner of"})
+	}
+	server.Seed("Relationships", relationships...)
+
+	_, e := service.BuildGraph(&insightly.BuildGraphConfig{RootObjectName: insightly.ObjectNameContact, RootObjectID: 1})
+	if e == nil {
+		t.Error("expected an error for truncated relationships")
+	}
+}
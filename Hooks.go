@@ -0,0 +1,43 @@
+package insightly
+
+import (
+	"context"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// RequestInfo is passed to ServiceConfig.BeforeRequest before every attempt of an api request
+//
+type RequestInfo struct {
+	Method string
+	// Endpoint is the path of the request relative to the api url, e.g. "Contacts/Search"
+	Endpoint string
+	// Retry is zero for the first attempt and counts the retries after that
+	Retry int
+	// RateLimit is the snapshot the request was throttled on
+	RateLimit RateLimit
+}
+
+// ResponseInfo is passed to ServiceConfig.AfterResponse after every attempt of an api request
+//
+type ResponseInfo struct {
+	Method   string
+	Endpoint string
+	// StatusCode is zero if no response was received
+	StatusCode int
+	// Duration is the time the attempt took, excluding waits for rate limits and retries
+	Duration time.Duration
+	Retry    int
+	// RateLimit is the snapshot as updated from the response headers
+	RateLimit RateLimit
+	Error     *errortools.Error
+}
+
+// BeforeRequestHook is called before every attempt of an api request
+//
+type BeforeRequestHook func(ctx context.Context, info RequestInfo)
+
+// AfterResponseHook is called after every attempt of an api request, including failed ones
+//
+type AfterResponseHook func(ctx context.Context, info ResponseInfo)
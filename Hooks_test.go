@@ -0,0 +1,194 @@
+package insightly_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+// recordHandler is a slog.Handler keeping the records it handles
+type recordHandler struct {
+	mutex   sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.records = append(h.records, record)
+
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// find returns the attributes of the records with message
+func (h *recordHandler) find(message string) []map[string]slog.Value {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	found := []map[string]slog.Value{}
+	for _, record := range h.records {
+		if record.Message != message {
+			continue
+		}
+		attrs := map[string]slog.Value{}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value
+			return true
+		})
+		found = append(found, attrs)
+	}
+
+	return found
+}
+
+type ctxKey struct{}
+
+func TestHooks(t *testing.T) {
+	events := []string{}
+	requests := []insightly.RequestInfo{}
+	responses := []insightly.ResponseInfo{}
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:      1,
+			InitialInterval: time.Millisecond,
+			StatusCodes:     []int{http.StatusServiceUnavailable},
+		}
+		config.Logger = slog.New(&recordHandler{})
+		config.BeforeRequest = func(ctx context.Context, info insightly.RequestInfo) {
+			if ctx.Value(ctxKey{}) != "call" {
+				t.Error("expected BeforeRequest to get the context of the call")
+			}
+			events = append(events, "before")
+			requests = append(requests, info)
+		}
+		config.AfterResponse = func(ctx context.Context, info insightly.ResponseInfo) {
+			if ctx.Value(ctxKey{}) != "call" {
+				t.Error("expected AfterResponse to get the context of the call")
+			}
+			events = append(events, "after")
+			responses = append(responses, info)
+		}
+	})
+	server.SetRateLimit(10, 10, time.Minute)
+	ids := server.Seed("Contacts", &insightly.Contact{})
+	server.FailNext(1, http.StatusServiceUnavailable)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "call")
+	_, e := service.GetContactWithContext(ctx, ids[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(events) != 4 || events[0] != "before" || events[1] != "after" || events[2] != "before" || events[3] != "after" {
+		t.Fatalf("got hooks %v, want before and after for both attempts", events)
+	}
+
+	for retry, request := range requests {
+		if request.Method != http.MethodGet || request.Endpoint != "Contacts/1" || request.Retry != retry {
+			t.Errorf("got request %s %s retry %v, want GET Contacts/1 retry %v", request.Method, request.Endpoint, request.Retry, retry)
+		}
+	}
+	if requests[0].RateLimit.Remaining != nil {
+		t.Error("expected no rate limit before the first response")
+	}
+
+	failed, succeeded := responses[0], responses[1]
+	if failed.StatusCode != http.StatusServiceUnavailable || failed.Error == nil || failed.Retry != 0 {
+		t.Errorf("got first response %v retry %v, want a failed 503 without retries", failed.StatusCode, failed.Retry)
+	}
+	if succeeded.StatusCode != http.StatusOK || succeeded.Error != nil || succeeded.Retry != 1 {
+		t.Errorf("got second response %v retry %v, want 200 on the first retry", succeeded.StatusCode, succeeded.Retry)
+	}
+	if succeeded.Duration <= 0 {
+		t.Error("expected the duration of the attempt")
+	}
+	if succeeded.RateLimit.Remaining == nil || *succeeded.RateLimit.Remaining != 9 {
+		t.Error("expected the rate limit as updated from the response")
+	}
+}
+
+func TestLogging(t *testing.T) {
+	handler := &recordHandler{}
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Logger = slog.New(handler)
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:      1,
+			InitialInterval: time.Millisecond,
+			StatusCodes:     []int{http.StatusServiceUnavailable},
+		}
+	})
+	server.FailNext(1, http.StatusServiceUnavailable)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	requests := handler.find("insightly: request")
+	if len(requests) != 2 {
+		t.Fatalf("got %v request records, want 2", len(requests))
+	}
+	for retry, attrs := range requests {
+		if attrs["method"].String() != http.MethodGet || attrs["endpoint"].String() != "Contacts" || attrs["retry"].Int64() != int64(retry) {
+			t.Errorf("got request record %v, want GET Contacts retry %v", attrs, retry)
+		}
+		if attrs["duration"].Kind() != slog.KindDuration {
+			t.Errorf("got duration %v, want a duration", attrs["duration"])
+		}
+	}
+	if requests[0]["status"].Int64() != http.StatusServiceUnavailable || requests[1]["status"].Int64() != http.StatusOK {
+		t.Errorf("got statuses %v and %v, want 503 and 200", requests[0]["status"], requests[1]["status"])
+	}
+
+	retries := handler.find("insightly: request failed, retrying")
+	if len(retries) != 1 {
+		t.Fatalf("got %v retry records, want 1", len(retries))
+	}
+	if attrs := retries[0]; attrs["status"].Int64() != http.StatusServiceUnavailable || attrs["retry"].Int64() != 1 || attrs["error"].String() == "" || attrs["wait"].Kind() != slog.KindDuration {
+		t.Errorf("got retry record %v, want status 503, retry 1, the error and the wait", attrs)
+	}
+}
+
+func TestLoggingRateLimitWait(t *testing.T) {
+	handler := &recordHandler{}
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Logger = slog.New(handler)
+	})
+	server.SetRateLimit(10, 1, time.Second)
+
+	for range 2 {
+		_, e := service.GetContacts(nil)
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+	}
+
+	waits := handler.find("insightly: rate limit exceeded, waiting")
+	if len(waits) != 1 {
+		t.Fatalf("got %v rate limit records, want 1", len(waits))
+	}
+	if attrs := waits[0]; attrs["endpoint"].String() != "Contacts" || attrs["wait"].Duration() <= 0 {
+		t.Errorf("got rate limit record %v, want endpoint Contacts and the wait", attrs)
+	}
+}
@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"net/http"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
@@ -20,6 +21,15 @@ type Instance struct {
 //
 
 func (service *Service) GetInstance() (*Instance, *http.Response, *errortools.Error) {
+	return service.GetInstanceWithContext(context.Background())
+}
+
+// GetInstanceWithContext is the context-aware variant of GetInstance
+//
+func (service *Service) GetInstanceWithContext(ctx context.Context) (*Instance, *http.Response, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetInstance")
+	defer span.End()
+
 	instance := Instance{}
 	requestConfig := go_http.RequestConfig{
 		Method:        http.MethodGet,
@@ -27,7 +37,7 @@ func (service *Service) GetInstance() (*Instance, *http.Response, *errortools.Er
 		ResponseModel: &instance,
 	}
 
-	_, response, e := service.httpRequest(&requestConfig)
+	_, response, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, response, e
 	}
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -127,163 +124,149 @@ func (l *Lead) prepareMarshal() interface{} {
 	}
 }
 
+var leadResource = &Resource[Lead]{
+	Endpoint: "Leads",
+	ID:       func(l *Lead) int64 { return l.LeadID },
+	Marshal:  func(l *Lead) interface{} { return l.prepareMarshal() },
+}
+
 // GetLead returns a specific lead
 //
 func (service *Service) GetLead(leadID int64) (*Lead, *errortools.Error) {
-	lead := Lead{}
+	return service.GetLeadWithContext(context.Background(), leadID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Leads/%v", leadID)),
-		ResponseModel: &lead,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetLeadWithContext is the context-aware variant of GetLead
+//
+func (service *Service) GetLeadWithContext(ctx context.Context, leadID int64) (*Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLead")
+	defer span.End()
 
-	return &lead, nil
+	return leadResource.Get(ctx, service, leadID)
 }
 
-type GetLeadsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetLeadsConfig = ListConfig
 
 // GetLeads returns all leads
 //
 func (service *Service) GetLeads(config *GetLeadsConfig) (*[]Lead, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Leads"
-	leads := []Lead{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetLeadsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetLeadsWithContext is the context-aware variant of GetLeads
+//
+func (service *Service) GetLeadsWithContext(ctx context.Context, config *GetLeadsConfig) (*[]Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeads")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return leadResource.List(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		leadsBatch := []Lead{}
+// GetLeadsPage returns a single page of leads and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetLeadsPage(config *GetLeadsConfig) (*[]Lead, *Cursor, *errortools.Error) {
+	return service.GetLeadsPageWithContext(context.Background(), config)
+}
+
+// GetLeadsPageWithContext is the context-aware variant of GetLeadsPage
+//
+func (service *Service) GetLeadsPageWithContext(ctx context.Context, config *GetLeadsConfig) (*[]Lead, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeadsPage")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &leadsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return leadResource.Page(ctx, service, config)
+}
+
+// LeadsSeq returns an iterator over all leads, fetching pages on demand
+//
+func (service *Service) LeadsSeq(config *GetLeadsConfig) iter.Seq2[Lead, *errortools.Error] {
+	return service.LeadsSeqWithContext(context.Background(), config)
+}
 
-		leads = append(leads, leadsBatch...)
+// LeadsSeqWithContext is the context-aware variant of LeadsSeq
+//
+func (service *Service) LeadsSeqWithContext(ctx context.Context, config *GetLeadsConfig) iter.Seq2[Lead, *errortools.Error] {
+	return seq(ctx, service, "LeadsSeq", leadResource, config)
+}
 
-		if len(leadsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// SearchLeadsByTag returns the leads that have a specific tag
+func (service *Service) SearchLeadsByTag(tagName string, config *SearchByTagConfig) (*[]Lead, *errortools.Error) {
+	return service.SearchLeadsByTagWithContext(context.Background(), tagName, config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// SearchLeadsByTagWithContext is the context-aware variant of SearchLeadsByTag
+func (service *Service) SearchLeadsByTagWithContext(ctx context.Context, tagName string, config *SearchByTagConfig) (*[]Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SearchLeadsByTag")
+	defer span.End()
 
-		if rowCount >= service.maxRowCount {
-			return &leads, nil
-		}
-	}
+	return leadResource.ListByTag(ctx, service, tagName, config)
+}
 
-	return &leads, nil
+// QueryLeads returns the leads that match a Query
+func (service *Service) QueryLeads(query *Query) (*[]Lead, *errortools.Error) {
+	return service.QueryLeadsWithContext(context.Background(), query)
+}
+
+// QueryLeadsWithContext is the context-aware variant of QueryLeads
+func (service *Service) QueryLeadsWithContext(ctx context.Context, query *Query) (*[]Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "QueryLeads")
+	defer span.End()
+
+	return leadResource.Query(ctx, service, query)
+}
+
+// QueryLeadsSeq returns an iterator over the leads that match a Query, fetching pages on demand
+func (service *Service) QueryLeadsSeq(query *Query) iter.Seq2[Lead, *errortools.Error] {
+	return service.QueryLeadsSeqWithContext(context.Background(), query)
+}
+
+// QueryLeadsSeqWithContext is the context-aware variant of QueryLeadsSeq
+func (service *Service) QueryLeadsSeqWithContext(ctx context.Context, query *Query) iter.Seq2[Lead, *errortools.Error] {
+	return querySeq[Lead](ctx, service, "QueryLeadsSeq", leadResource.Endpoint, query)
 }
 
 // CreateLead creates a new contract
 //
 func (service *Service) CreateLead(lead *Lead) (*Lead, *errortools.Error) {
-	if lead == nil {
-		return nil, nil
-	}
-
-	leadNew := Lead{}
+	return service.CreateLeadWithContext(context.Background(), lead)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Leads"),
-		BodyModel:     lead.prepareMarshal(),
-		ResponseModel: &leadNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateLeadWithContext is the context-aware variant of CreateLead
+//
+func (service *Service) CreateLeadWithContext(ctx context.Context, lead *Lead) (*Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateLead")
+	defer span.End()
 
-	return &leadNew, nil
+	return leadResource.Create(ctx, service, lead)
 }
 
-// UpdateLead updates an existing contract
+// UpdateLead updates an existing contract, a lead without LeadID is rejected without
+// sending a request
 //
 func (service *Service) UpdateLead(lead *Lead) (*Lead, *errortools.Error) {
-	if lead == nil {
-		return nil, nil
-	}
-
-	leadUpdated := Lead{}
+	return service.UpdateLeadWithContext(context.Background(), lead)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Leads"),
-		BodyModel:     lead.prepareMarshal(),
-		ResponseModel: &leadUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateLeadWithContext is the context-aware variant of UpdateLead
+//
+func (service *Service) UpdateLeadWithContext(ctx context.Context, lead *Lead) (*Lead, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateLead")
+	defer span.End()
 
-	return &leadUpdated, nil
+	return leadResource.Update(ctx, service, lead)
 }
 
 // DeleteLead deletes a specific lead
 //
 func (service *Service) DeleteLead(leadID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Leads/%v", leadID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteLeadWithContext(context.Background(), leadID)
+}
+
+// DeleteLeadWithContext is the context-aware variant of DeleteLead
+//
+func (service *Service) DeleteLeadWithContext(ctx context.Context, leadID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteLead")
+	defer span.End()
 
-	return nil
+	return leadResource.Delete(ctx, service, leadID)
 }
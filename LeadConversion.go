@@ -0,0 +1,364 @@
+package insightly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	i_types "github.com/leapforce-libraries/go_insightly/types"
+)
+
+type ConvertLeadConfig struct {
+	// OrganisationID converts the lead into an existing organisation instead of matching
+	// or creating one
+	OrganisationID *int64
+	// MatchOrganisationByWebsite matches an organisation by the domain of the lead's
+	// website if none matches its OrganisationName
+	MatchOrganisationByWebsite bool
+	// MatchContactByEmail converts the lead into an existing contact with the lead's email
+	// address instead of creating one
+	MatchContactByEmail bool
+	// Opportunity is created for the converted lead if set, OpportunityName defaults to
+	// the name of the organisation, or the lead if there is none
+	Opportunity *Opportunity
+	// OrganisationCustomFields, ContactCustomFields and OpportunityCustomFields map custom
+	// field names of the lead to those of the created records
+	OrganisationCustomFields map[string]string
+	ContactCustomFields      map[string]string
+	OpportunityCustomFields  map[string]string
+	// CopyTags copies the tags of the lead to the created records
+	CopyTags bool
+	// DryRun reports the conversion without making it
+	DryRun bool
+}
+
+// LeadConversion reports the records of a lead conversion, records that were not
+// created, e.g. because an earlier step failed, are nil. In a dry run the records are
+// not saved, records that would be created and the links have no ids.
+//
+type LeadConversion struct {
+	DryRun bool
+	Lead   *Lead
+	// Organisation is the matched or created organisation, OrganisationCreated tells which
+	Organisation        *Organisation
+	OrganisationCreated bool
+	// Contact is the matched or created contact, ContactCreated tells which
+	Contact        *Contact
+	ContactCreated bool
+	Opportunity    *Opportunity
+	Links          []Link
+}
+
+// ConvertLead converts a lead into a contact, with its organisation and optionally an
+// opportunity. The organisation is matched by OrganisationName, and if configured by
+// website domain, and created if there is no match. Matching by website domain searches
+// for the domain with and without "www." and "http://" or "https://", which costs up to
+// six requests, a website stored in another form, e.g. with a path, does not match. The
+// contact is matched by email address if configured, or created from the name, email and
+// phone numbers of the lead, an opportunity is linked to the contact. Finally the lead
+// is marked converted. ConvertLead stops at the first failure and returns a
+// LeadConversion with the records created so far along with the error.
+//
+func (service *Service) ConvertLead(leadID int64, config *ConvertLeadConfig) (*LeadConversion, *errortools.Error) {
+	return service.ConvertLeadWithContext(context.Background(), leadID, config)
+}
+
+// ConvertLeadWithContext is the context-aware variant of ConvertLead
+//
+func (service *Service) ConvertLeadWithContext(ctx context.Context, leadID int64, config *ConvertLeadConfig) (*LeadConversion, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "ConvertLead")
+	defer span.End()
+
+	if config == nil {
+		config = &ConvertLeadConfig{}
+	}
+
+	lead, e := leadResource.Get(ctx, service, leadID)
+	if e != nil {
+		return nil, e
+	}
+
+	if lead.Converted {
+		return nil, errortools.ErrorMessagef("Lead %v is already converted", leadID)
+	}
+
+	conversion := LeadConversion{DryRun: config.DryRun}
+
+	// organisation
+	conversion.Organisation, e = matchOrganisation(ctx, service, lead, config)
+	if e != nil {
+		return &conversion, e
+	}
+
+	if conversion.Organisation == nil && lead.OrganisationName != nil && *lead.OrganisationName != "" {
+		conversion.Organisation, e = createUnlessDryRun(ctx, service, organisationResource, config.DryRun, &Organisation{
+			OrganisationName:       lead.OrganisationName,
+			OwnerUserID:            nonZero(lead.OwnerUserID),
+			Phone:                  lead.Phone,
+			PhoneFax:               lead.Fax,
+			Website:                lead.Website,
+			AddressBillingStreet:   lead.AddressStreet,
+			AddressBillingCity:     lead.AddressCity,
+			AddressBillingState:    lead.AddressState,
+			AddressBillingCountry:  lead.AddressCountry,
+			AddressBillingPostcode: lead.AddressPostcode,
+			CustomFields:           mapCustomFields(lead.CustomFields, config.OrganisationCustomFields),
+			Tags:                   config.tags(lead),
+		})
+		if e != nil {
+			return &conversion, e
+		}
+		conversion.OrganisationCreated = true
+	}
+
+	var organisationID *int64
+	if conversion.Organisation != nil {
+		organisationID = &conversion.Organisation.OrganisationID
+	}
+
+	// contact
+	conversion.Contact, e = matchContact(ctx, service, lead, config)
+	if e != nil {
+		return &conversion, e
+	}
+
+	if conversion.Contact == nil {
+		conversion.Contact, e = createUnlessDryRun(ctx, service, contactResource, config.DryRun, &Contact{
+			Salutation:          lead.Salutation,
+			FirstName:           lead.FirstName,
+			LastName:            lead.LastName,
+			Title:               lead.Title,
+			OwnerUserID:         nonZero(lead.OwnerUserID),
+			EmailAddress:        lead.Email,
+			Phone:               lead.Phone,
+			PhoneMobile:         lead.Mobile,
+			PhoneFax:            lead.Fax,
+			AddressMailStreet:   lead.AddressStreet,
+			AddressMailCity:     lead.AddressCity,
+			AddressMailState:    lead.AddressState,
+			AddressMailPostcode: lead.AddressPostcode,
+			AddressMailCountry:  lead.AddressCountry,
+			OrganisationID:      organisationID,
+			EmailOptedOut:       &lead.EmailOptedOut,
+			CustomFields:        mapCustomFields(lead.CustomFields, config.ContactCustomFields),
+			Tags:                config.tags(lead),
+		})
+		if e != nil {
+			return &conversion, e
+		}
+		conversion.ContactCreated = true
+	}
+
+	// opportunity
+	if config.Opportunity != nil {
+		opportunity := *config.Opportunity
+		opportunity.OpportunityID = 0
+		if opportunity.OpportunityName == nil {
+			if conversion.Organisation != nil {
+				opportunity.OpportunityName = conversion.Organisation.OrganisationName
+			} else {
+				name := leadName(lead)
+				opportunity.OpportunityName = &name
+			}
+		}
+		if opportunity.OrganisationID == nil {
+			opportunity.OrganisationID = organisationID
+		}
+		if opportunity.CustomFields == nil {
+			opportunity.CustomFields = mapCustomFields(lead.CustomFields, config.OpportunityCustomFields)
+		}
+		if opportunity.Tags == nil {
+			opportunity.Tags = config.tags(lead)
+		}
+
+		conversion.Opportunity, e = createUnlessDryRun(ctx, service, opportunityResource, config.DryRun, &opportunity)
+		if e != nil {
+			return &conversion, e
+		}
+
+		link := NewLink(ObjectNameContact, conversion.Contact.ContactID)
+		if !config.DryRun {
+			link, e = writeLink(ctx, service, http.MethodPost, ObjectNameOpportunity, conversion.Opportunity.OpportunityID, link)
+			if e != nil {
+				return &conversion, e
+			}
+		}
+		conversion.Links = append(conversion.Links, *link)
+	}
+
+	// lead
+	convertedDate := i_types.DateTimeString(time.Now().UTC())
+
+	lead.Converted = true
+	lead.ConvertedDateUTC = &convertedDate
+	lead.ConvertedContactID = &conversion.Contact.ContactID
+	lead.ConvertedOrganisationID = organisationID
+	if conversion.Opportunity != nil {
+		lead.ConvertedOpportunityID = &conversion.Opportunity.OpportunityID
+	}
+
+	if config.DryRun {
+		conversion.Lead = lead
+		return &conversion, nil
+	}
+
+	conversion.Lead, e = leadResource.Update(ctx, service, lead)
+	if e != nil {
+		return &conversion, e
+	}
+
+	return &conversion, nil
+}
+
+// createUnlessDryRun creates record, or returns it unsaved in a dry run
+//
+func createUnlessDryRun[T any](ctx context.Context, service *Service, r *Resource[T], dryRun bool, record *T) (*T, *errortools.Error) {
+	if dryRun {
+		return record, nil
+	}
+
+	return r.Create(ctx, service, record)
+}
+
+// matchOrganisation returns the existing organisation the lead is converted into, nil if
+// there is none
+//
+func matchOrganisation(ctx context.Context, service *Service, lead *Lead, config *ConvertLeadConfig) (*Organisation, *errortools.Error) {
+	if config.OrganisationID != nil {
+		return organisationResource.Get(ctx, service, *config.OrganisationID)
+	}
+
+	if lead.OrganisationName != nil && *lead.OrganisationName != "" {
+		organisation, e := findFirst(ctx, service, organisationResource, "ORGANISATION_NAME", *lead.OrganisationName)
+		if e != nil || organisation != nil {
+			return organisation, e
+		}
+	}
+
+	if !config.MatchOrganisationByWebsite || lead.Website == nil {
+		return nil, nil
+	}
+
+	domain := websiteDomain(*lead.Website)
+	if domain == "" {
+		return nil, nil
+	}
+
+	for _, host := range []string{domain, "www." + domain} {
+		for _, scheme := range []string{"", "http://", "https://"} {
+			organisation, e := findFirst(ctx, service, organisationResource, "WEBSITE", scheme+host)
+			if e != nil || organisation != nil {
+				return organisation, e
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// matchContact returns the existing contact the lead is converted into, nil if there is none
+//
+func matchContact(ctx context.Context, service *Service, lead *Lead, config *ConvertLeadConfig) (*Contact, *errortools.Error) {
+	if !config.MatchContactByEmail || lead.Email == nil || *lead.Email == "" {
+		return nil, nil
+	}
+
+	return findFirst(ctx, service, contactResource, "EMAIL_ADDRESS", *lead.Email)
+}
+
+// findFirst returns the first record of r with fieldValue in fieldName, nil if there is none
+//
+func findFirst[T any](ctx context.Context, service *Service, r *Resource[T], fieldName string, fieldValue string) (*T, *errortools.Error) {
+	top := uint64(1)
+	records, _, e := r.Page(ctx, service, &ListConfig{
+		Top: &top,
+		FieldFilter: &FieldFilter{
+			FieldName:  fieldName,
+			FieldValue: fieldValue,
+		},
+	})
+	if e != nil {
+		return nil, e
+	}
+	if len(*records) == 0 {
+		return nil, nil
+	}
+
+	return &(*records)[0], nil
+}
+
+// websiteDomain returns the lower case host of a website without "www.", e.g.
+// "example.com" for "https://www.Example.com/contact"
+//
+func websiteDomain(website string) string {
+	website = strings.TrimSpace(strings.ToLower(website))
+	if website == "" {
+		return ""
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "http://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// mapCustomFields copies the custom fields of a lead that are in mapping, under their mapped name
+//
+func mapCustomFields(customFields *CustomFields, mapping map[string]string) *CustomFields {
+	if customFields == nil || len(mapping) == 0 {
+		return nil
+	}
+
+	mapped := CustomFields{}
+	for leadFieldName, fieldName := range mapping {
+		customFieldRecord := customFields.get(leadFieldName)
+		if customFieldRecord == nil {
+			continue
+		}
+		mapped = append(mapped, CustomFieldRecord{
+			FieldName:  fieldName,
+			FieldValue: customFieldRecord.FieldValue,
+		})
+	}
+
+	if len(mapped) == 0 {
+		return nil
+	}
+
+	return &mapped
+}
+
+func (config *ConvertLeadConfig) tags(lead *Lead) *[]Tag {
+	if !config.CopyTags || lead.Tags == nil || len(*lead.Tags) == 0 {
+		return nil
+	}
+
+	tags := append([]Tag{}, *lead.Tags...)
+
+	return &tags
+}
+
+func leadName(lead *Lead) string {
+	names := []string{}
+	for _, name := range []*string{lead.FirstName, lead.LastName} {
+		if name != nil && *name != "" {
+			names = append(names, *name)
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("Lead %v", lead.LeadID)
+	}
+
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,194 @@
+package insightly_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// seedLead seeds a lead of Jane Doe of Acme
+//
+func seedLead(server *insightlytest.Server) int64 {
+	return server.Seed("Leads", map[string]any{
+		"FIRST_NAME":        "Jane",
+		"LAST_NAME":         "Doe",
+		"ORGANISATION_NAME": "Acme",
+		"EMAIL":             "jane@acme.example",
+		"PHONE":             "555-0100",
+		"WEBSITE":           "acme.example/contact",
+		"CUSTOMFIELDS":      []any{map[string]any{"FIELD_NAME": "Source__c", "FIELD_VALUE": "Fair"}},
+		"TAGS":              []any{map[string]any{"TAG_NAME": "hot"}},
+	})[0]
+}
+
+func TestConvertLead(t *testing.T) {
+	service, server := newTestService(t, nil)
+	leadID := seedLead(server)
+
+	conversion, e := service.ConvertLead(leadID, &insightly.ConvertLeadConfig{
+		Opportunity:         &insightly.Opportunity{},
+		ContactCustomFields: map[string]string{"Source__c": "Origin__c"},
+		CopyTags:            true,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	organisation := conversion.Organisation
+	if !conversion.OrganisationCreated || organisation.OrganisationID == 0 || *organisation.OrganisationName != "Acme" {
+		t.Errorf("got organisation %v created %v, want Acme created", organisation.OrganisationName, conversion.OrganisationCreated)
+	}
+
+	contact := conversion.Contact
+	if !conversion.ContactCreated || contact.ContactID == 0 || *contact.FirstName != "Jane" || *contact.EmailAddress != "jane@acme.example" {
+		t.Errorf("got contact %v created %v, want Jane created", contact.FirstName, conversion.ContactCreated)
+	}
+	if contact.OrganisationID == nil || *contact.OrganisationID != organisation.OrganisationID {
+		t.Error("expected the contact in the organisation")
+	}
+	if contact.CustomFields == nil || len(*contact.CustomFields) != 1 || (*contact.CustomFields)[0].FieldName != "Origin__c" {
+		t.Errorf("got custom fields %v, want Origin__c", contact.CustomFields)
+	}
+	if contact.Tags == nil || len(*contact.Tags) != 1 || (*contact.Tags)[0].TagName != "hot" {
+		t.Errorf("got tags %v, want the tags of the lead", contact.Tags)
+	}
+
+	opportunity := conversion.Opportunity
+	if opportunity.OpportunityID == 0 || *opportunity.OpportunityName != "Acme" || *opportunity.OrganisationID != organisation.OrganisationID {
+		t.Errorf("got opportunity %v, want Acme of the organisation", opportunity.OpportunityName)
+	}
+	links, e := service.GetLinks(insightly.ObjectNameOpportunity, opportunity.OpportunityID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*links) != 1 || *(*links)[0].LinkObjectID != contact.ContactID || len(conversion.Links) != 1 {
+		t.Errorf("got links %v, want a link of the opportunity to the contact", *links)
+	}
+
+	lead, e := service.GetLead(leadID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if !lead.Converted || *lead.ConvertedContactID != contact.ContactID || *lead.ConvertedOrganisationID != organisation.OrganisationID || *lead.ConvertedOpportunityID != opportunity.OpportunityID {
+		t.Error("expected the lead converted into the created records")
+	}
+
+	_, e = service.ConvertLead(leadID, nil)
+	if e == nil {
+		t.Error("expected an error for a converted lead")
+	}
+}
+
+func TestConvertLeadMatches(t *testing.T) {
+	service, server := newTestService(t, nil)
+	leadID := seedLead(server)
+	organisationIDs := server.Seed("Organisations",
+		map[string]any{"ORGANISATION_NAME": "Other", "WEBSITE": "https://other.example"},
+		map[string]any{"ORGANISATION_NAME": "Acme Inc.", "WEBSITE": "https://www.acme.example"},
+	)
+	contactID := server.Seed("Contacts", map[string]any{"FIRST_NAME": "Jane", "EMAIL_ADDRESS": "Jane@Acme.example"})[0]
+
+	conversion, e := service.ConvertLead(leadID, &insightly.ConvertLeadConfig{
+		MatchOrganisationByWebsite: true,
+		MatchContactByEmail:        true,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if conversion.OrganisationCreated || conversion.Organisation.OrganisationID != organisationIDs[1] {
+		t.Errorf("got organisation %v, want %v matched by website", conversion.Organisation.OrganisationID, organisationIDs[1])
+	}
+	if conversion.ContactCreated || conversion.Contact.ContactID != contactID {
+		t.Errorf("got contact %v, want %v matched by email", conversion.Contact.ContactID, contactID)
+	}
+	if count := requestCount(server, http.MethodPost, ""); count != 0 {
+		t.Errorf("got %v creates, want none", count)
+	}
+
+	// the organisation matched by name takes precedence over the website
+	leadID = seedLead(server)
+	organisationID := server.Seed("Organisations", map[string]any{"ORGANISATION_NAME": "acme"})[0]
+
+	conversion, e = service.ConvertLead(leadID, &insightly.ConvertLeadConfig{MatchOrganisationByWebsite: true})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if conversion.OrganisationCreated || conversion.Organisation.OrganisationID != organisationID {
+		t.Errorf("got organisation %v, want %v matched by name", conversion.Organisation.OrganisationID, organisationID)
+	}
+	if !conversion.ContactCreated {
+		t.Error("expected a contact to be created without MatchContactByEmail")
+	}
+}
+
+func TestConvertLeadDryRun(t *testing.T) {
+	service, server := newTestService(t, nil)
+	leadID := seedLead(server)
+
+	conversion, e := service.ConvertLead(leadID, &insightly.ConvertLeadConfig{
+		Opportunity: &insightly.Opportunity{},
+		DryRun:      true,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if !conversion.DryRun || !conversion.OrganisationCreated || !conversion.ContactCreated {
+		t.Error("expected a dry run reporting the organisation and contact to create")
+	}
+	if conversion.Organisation.OrganisationID != 0 || conversion.Contact.ContactID != 0 || conversion.Opportunity.OpportunityID != 0 {
+		t.Error("expected the records of a dry run to have no ids")
+	}
+	if *conversion.Contact.LastName != "Doe" || *conversion.Opportunity.OpportunityName != "Acme" || len(conversion.Links) != 1 {
+		t.Error("expected the records that would be created")
+	}
+	if !conversion.Lead.Converted {
+		t.Error("expected the lead as it would be converted")
+	}
+
+	for _, request := range server.Requests() {
+		if request.Method != http.MethodGet {
+			t.Errorf("got %s %s in a dry run, want reads only", request.Method, request.Path)
+		}
+	}
+	lead, e := service.GetLead(leadID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if lead.Converted {
+		t.Error("expected the lead not to be converted in a dry run")
+	}
+}
+
+func TestConvertLeadPartialFailure(t *testing.T) {
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Transport = &rejectTransport{reject: func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/Opportunities")
+		}}
+	})
+	leadID := seedLead(server)
+
+	conversion, e := service.ConvertLead(leadID, &insightly.ConvertLeadConfig{Opportunity: &insightly.Opportunity{}})
+	if e == nil {
+		t.Fatal("expected an error for a rejected opportunity")
+	}
+
+	if conversion == nil || conversion.Organisation == nil || conversion.Organisation.OrganisationID == 0 || conversion.Contact == nil || conversion.Contact.ContactID == 0 {
+		t.Fatal("expected the organisation and contact created before the failure")
+	}
+	if conversion.Opportunity != nil || conversion.Lead != nil || len(conversion.Links) != 0 {
+		t.Error("expected nothing after the failure")
+	}
+
+	lead, e := service.GetLead(leadID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if lead.Converted {
+		t.Error("expected the lead not to be converted after a failure")
+	}
+}
@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // LeadSource stores LeadSource from Service
@@ -18,66 +16,51 @@ type LeadSource struct {
 	FieldOrder   int64  `json:"FIELD_ORDER"`
 }
 
-type GetLeadSourcesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var leadSourceResource = &Resource[LeadSource]{
+	Endpoint: "LeadSources",
+	ID:       func(l *LeadSource) int64 { return l.LeadSourceID },
 }
 
+type GetLeadSourcesConfig = PagingConfig
+
 // GetLeadSources returns all leadSources
 //
 func (service *Service) GetLeadSources(config *GetLeadSourcesConfig) (*[]LeadSource, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "LeadSources"
-	leadSources := []LeadSource{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		leadSourcesBatch := []LeadSource{}
+	return service.GetLeadSourcesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &leadSourcesBatch,
-		}
+// GetLeadSourcesWithContext is the context-aware variant of GetLeadSources
+//
+func (service *Service) GetLeadSourcesWithContext(ctx context.Context, config *GetLeadSourcesConfig) (*[]LeadSource, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeadSources")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return leadSourceResource.List(ctx, service, config)
+}
 
-		leadSources = append(leadSources, leadSourcesBatch...)
+// GetLeadSourcesPage returns a single page of leadSources and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetLeadSourcesPage(config *GetLeadSourcesConfig) (*[]LeadSource, *Cursor, *errortools.Error) {
+	return service.GetLeadSourcesPageWithContext(context.Background(), config)
+}
 
-		if len(leadSourcesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetLeadSourcesPageWithContext is the context-aware variant of GetLeadSourcesPage
+//
+func (service *Service) GetLeadSourcesPageWithContext(ctx context.Context, config *GetLeadSourcesConfig) (*[]LeadSource, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeadSourcesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return leadSourceResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &leadSources, nil
-		}
-	}
+// LeadSourcesSeq returns an iterator over all leadSources, fetching pages on demand
+//
+func (service *Service) LeadSourcesSeq(config *GetLeadSourcesConfig) iter.Seq2[LeadSource, *errortools.Error] {
+	return service.LeadSourcesSeqWithContext(context.Background(), config)
+}
 
-	return &leadSources, nil
+// LeadSourcesSeqWithContext is the context-aware variant of LeadSourcesSeq
+//
+func (service *Service) LeadSourcesSeqWithContext(ctx context.Context, config *GetLeadSourcesConfig) iter.Seq2[LeadSource, *errortools.Error] {
+	return seq(ctx, service, "LeadSourcesSeq", leadSourceResource, config)
 }
@@ -1,12 +1,11 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // LeadStatus stores LeadStatus from Service
@@ -19,70 +18,71 @@ type LeadStatus struct {
 	FieldOrder    int64  `json:"FIELD_ORDER"`
 }
 
+var leadStatusResource = &Resource[LeadStatus]{
+	Endpoint: "LeadStatuses",
+	ID:       func(l *LeadStatus) int64 { return l.LeadStatusID },
+}
+
 type GetLeadStatusesConfig struct {
 	Skip             *uint64
 	Top              *uint64
+	Cursor           *Cursor
+	NextCursor       *Cursor
 	CountTotal       *bool
 	IncludeConverted *bool
 }
 
-// GetLeadStatuses returns all leadStatuses
-//
-func (service *Service) GetLeadStatuses(config *GetLeadStatusesConfig) (*[]LeadStatus, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "LeadStatuses"
-	leadStatuses := []LeadStatus{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.IncludeConverted != nil {
-			params.Set("include_converted", fmt.Sprintf("%v", *config.IncludeConverted))
-		}
+func (config *GetLeadStatusesConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return newPageConfig(endpoint, nil, nil, nil)
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	if config.IncludeConverted != nil {
+		p.params.Set("include_converted", fmt.Sprintf("%v", *config.IncludeConverted))
+	}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+	return p
+}
 
-		leadStatusesBatch := []LeadStatus{}
+// GetLeadStatuses returns all leadStatuses
+//
+func (service *Service) GetLeadStatuses(config *GetLeadStatusesConfig) (*[]LeadStatus, *errortools.Error) {
+	return service.GetLeadStatusesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &leadStatusesBatch,
-		}
+// GetLeadStatusesWithContext is the context-aware variant of GetLeadStatuses
+//
+func (service *Service) GetLeadStatusesWithContext(ctx context.Context, config *GetLeadStatusesConfig) (*[]LeadStatus, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeadStatuses")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return leadStatusResource.List(ctx, service, config)
+}
 
-		leadStatuses = append(leadStatuses, leadStatusesBatch...)
+// GetLeadStatusesPage returns a single page of leadStatuses and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetLeadStatusesPage(config *GetLeadStatusesConfig) (*[]LeadStatus, *Cursor, *errortools.Error) {
+	return service.GetLeadStatusesPageWithContext(context.Background(), config)
+}
 
-		if len(leadStatusesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetLeadStatusesPageWithContext is the context-aware variant of GetLeadStatusesPage
+//
+func (service *Service) GetLeadStatusesPageWithContext(ctx context.Context, config *GetLeadStatusesConfig) (*[]LeadStatus, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLeadStatusesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return leadStatusResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &leadStatuses, nil
-		}
-	}
+// LeadStatusesSeq returns an iterator over all leadStatuses, fetching pages on demand
+//
+func (service *Service) LeadStatusesSeq(config *GetLeadStatusesConfig) iter.Seq2[LeadStatus, *errortools.Error] {
+	return service.LeadStatusesSeqWithContext(context.Background(), config)
+}
 
-	return &leadStatuses, nil
+// LeadStatusesSeqWithContext is the context-aware variant of LeadStatusesSeq
+//
+func (service *Service) LeadStatusesSeqWithContext(ctx context.Context, config *GetLeadStatusesConfig) iter.Seq2[LeadStatus, *errortools.Error] {
+	return seq(ctx, service, "LeadStatusesSeq", leadStatusResource, config)
 }
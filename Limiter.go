@@ -0,0 +1,340 @@
+package insightly
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// LimiterConfig configures a Limiter
+//
+type LimiterConfig struct {
+	// RequestsPerSecond is the sustained request rate, zero means no per-second limit
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once, it defaults to
+	// RequestsPerSecond rounded up
+	Burst int
+	// RequestsPerDay is the daily request budget, zero means the daily limit of the
+	// plan as reported by Insightly in the X-RateLimit-Limit header
+	RequestsPerDay int64
+	// Location determines at which midnight the daily budget resets, UTC if nil
+	Location *time.Location
+	// Pace spreads the remaining daily budget evenly over the rest of the day instead
+	// of spending it as fast as RequestsPerSecond allows
+	Pace bool
+}
+
+// Limiter paces requests on the client side so they stay within a per-second rate and
+// a daily request budget, instead of running into 429 responses. Set it as
+// ServiceConfig.Limiter to have the Service wait for it before every request and seed
+// the daily budget from the X-RateLimit-Limit and X-RateLimit-Remaining headers.
+//
+// A Limiter is safe for concurrent use and can be shared by multiple Services using
+// the same api key.
+//
+type Limiter struct {
+	mutex sync.Mutex
+	now   func() time.Time
+
+	// per-second token bucket
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// daily budget, unknown as long as dailyLimit is zero
+	budget          int64
+	dailyLimit      int64
+	dailyRemaining  int64
+	nextDayReserved int64
+	location        *time.Location
+	resetAt         time.Time
+	dayStart        time.Time
+	used            int64
+	pace            bool
+	nextPaced       time.Time
+}
+
+// LimiterStatus is a snapshot of the daily budget of a Limiter
+//
+type LimiterStatus struct {
+	// DailyLimit and DailyRemaining are zero as long as the daily budget is unknown
+	DailyLimit     int64
+	DailyRemaining int64
+	ResetAt        time.Time
+	// ProjectedExhaustion is the time the daily budget runs out at the pace of the
+	// requests made so far today, nil if it lasts until ResetAt
+	ProjectedExhaustion *time.Time
+}
+
+// Reservation holds a request slot reserved by Limiter.Reserve
+//
+type Reservation struct {
+	limiter   *Limiter
+	ok        bool
+	timeToAct time.Time
+	tokens    bool
+	daily     bool
+	nextDay   bool
+	// paced is the interval the reservation moved nextPaced by
+	paced time.Duration
+}
+
+func NewLimiter(config LimiterConfig) *Limiter {
+	return newLimiter(config, time.Now)
+}
+
+// newLimiter returns a Limiter reading the time from now
+//
+func newLimiter(config LimiterConfig, now func() time.Time) *Limiter {
+	l := Limiter{
+		now:      now,
+		rate:     math.Max(config.RequestsPerSecond, 0),
+		budget:   max(config.RequestsPerDay, 0),
+		location: config.Location,
+		pace:     config.Pace,
+	}
+
+	if l.location == nil {
+		l.location = time.UTC
+	}
+
+	l.burst = float64(config.Burst)
+	if l.burst < 1 {
+		l.burst = math.Max(math.Ceil(l.rate), 1)
+	}
+	l.tokens = l.burst
+
+	if l.budget > 0 {
+		l.dailyLimit = l.budget
+		l.dailyRemaining = l.budget
+	}
+
+	start := l.now()
+	l.last = start
+	l.dayStart = start
+	l.resetAt = l.nextMidnight(start)
+
+	return &l
+}
+
+// Reserve reserves a request slot, the request can be sent after Delay
+//
+func (l *Limiter) Reserve() *Reservation {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.advance(now)
+
+	r := Reservation{
+		limiter:   l,
+		ok:        true,
+		timeToAct: now,
+	}
+
+	if l.dailyLimit > 0 {
+		if l.dailyRemaining > 0 {
+			if l.pace {
+				interval := l.resetAt.Sub(now) / time.Duration(l.dailyRemaining)
+				r.timeToAct = latest(r.timeToAct, l.nextPaced)
+				r.paced = interval
+				l.nextPaced = r.timeToAct.Add(interval)
+			}
+			l.dailyRemaining--
+			l.used++
+			r.daily = true
+		} else if l.nextDayReserved < l.dailyLimit {
+			l.nextDayReserved++
+			r.nextDay = true
+			r.timeToAct = latest(r.timeToAct, l.resetAt)
+		} else {
+			r.ok = false
+			return &r
+		}
+	}
+
+	if l.rate > 0 {
+		l.tokens--
+		if l.tokens < 0 {
+			r.timeToAct = latest(r.timeToAct, now.Add(time.Duration(-l.tokens/l.rate*float64(time.Second))))
+		}
+		r.tokens = true
+	}
+
+	if l.dailyLimit == 0 {
+		l.used++
+	}
+
+	return &r
+}
+
+// Wait blocks until a request can be sent or ctx is done, it returns immediately with
+// an error if the wait would exceed the deadline of ctx
+//
+func (l *Limiter) Wait(ctx context.Context) *errortools.Error {
+	r := l.Reserve()
+	if !r.OK() {
+		return errortools.ErrorMessage("Daily request budget exhausted")
+	}
+
+	delay := r.Delay()
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.timeToAct) {
+		r.Cancel()
+		return errortools.ErrorMessagef("Rate limiter wait of %v exceeds context deadline", delay)
+	}
+
+	err := sleepWithContext(ctx, delay)
+	if err != nil {
+		r.Cancel()
+		return errortools.ErrorMessage(err)
+	}
+
+	return nil
+}
+
+// Status returns a snapshot of the daily budget
+//
+func (l *Limiter) Status() LimiterStatus {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.advance(now)
+
+	status := LimiterStatus{
+		DailyLimit:     l.dailyLimit,
+		DailyRemaining: l.dailyRemaining,
+		ResetAt:        l.resetAt,
+	}
+
+	elapsed := now.Sub(l.dayStart)
+	if l.dailyLimit > 0 && l.used > 0 && elapsed > 0 {
+		perSecond := float64(l.used) / elapsed.Seconds()
+		exhaustion := now.Add(time.Duration(float64(l.dailyRemaining) / perSecond * float64(time.Second)))
+		if exhaustion.Before(l.resetAt) {
+			status.ProjectedExhaustion = &exhaustion
+		}
+	}
+
+	return status
+}
+
+// update seeds the daily budget from the X-RateLimit-Limit and X-RateLimit-Remaining
+// headers, a configured RequestsPerDay budget is never raised by them
+//
+func (l *Limiter) update(limit *int64, remaining *int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.advance(l.now())
+
+	if l.budget > 0 {
+		if remaining != nil {
+			l.dailyRemaining = min(l.dailyRemaining, *remaining)
+		}
+		return
+	}
+
+	if limit != nil {
+		l.dailyLimit = *limit
+	}
+	if remaining != nil {
+		l.dailyRemaining = *remaining
+	}
+}
+
+// advance refills the token bucket and resets the daily budget at midnight
+//
+func (l *Limiter) advance(now time.Time) {
+	if l.rate > 0 && now.After(l.last) {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = latest(l.last, now)
+
+	if now.Before(l.resetAt) {
+		return
+	}
+
+	// the requests reserved for the next day are sent at its start
+	l.dailyRemaining = max(l.dailyLimit-l.nextDayReserved, 0)
+	l.used = l.nextDayReserved
+	l.nextDayReserved = 0
+	l.resetAt = l.nextMidnight(now)
+	l.dayStart = l.midnight(now)
+}
+
+func (l *Limiter) midnight(t time.Time) time.Time {
+	t = t.In(l.location)
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, l.location)
+}
+
+func (l *Limiter) nextMidnight(t time.Time) time.Time {
+	t = t.In(l.location)
+
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, l.location)
+}
+
+// OK reports whether a slot was reserved, it is false if the budget of both today and
+// tomorrow is used up
+//
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before sending the request
+//
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+
+	return max(r.timeToAct.Sub(r.limiter.now()), 0)
+}
+
+// Cancel returns the slot of a request that will not be sent
+//
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+
+	l := r.limiter
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.advance(now)
+
+	if r.tokens {
+		l.tokens = math.Min(l.burst, l.tokens+1)
+	}
+	if r.daily && !r.timeToAct.Before(l.dayStart) {
+		l.dailyRemaining++
+		l.used--
+		// give the interval back, so the next reservation is not paced behind a request never sent
+		l.nextPaced = l.nextPaced.Add(-r.paced)
+	}
+	if r.nextDay && r.timeToAct.After(now) {
+		l.nextDayReserved--
+	}
+
+	r.ok = false
+}
+
+func latest(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
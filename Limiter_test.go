@@ -0,0 +1,246 @@
+package insightly
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testClock is a clock for a Limiter that only moves when told to
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestLimiter(config LimiterConfig, start time.Time) (*Limiter, *testClock) {
+	clock := &testClock{t: start}
+
+	return newLimiter(config, clock.now), clock
+}
+
+func delays(reservations ...*Reservation) []time.Duration {
+	d := []time.Duration{}
+	for _, r := range reservations {
+		d = append(d, r.Delay())
+	}
+
+	return d
+}
+
+func TestLimiterRate(t *testing.T) {
+	l, clock := newTestLimiter(LimiterConfig{RequestsPerSecond: 2}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	d := delays(l.Reserve(), l.Reserve(), l.Reserve(), l.Reserve())
+	if d[0] != 0 || d[1] != 0 || d[2] != 500*time.Millisecond || d[3] != time.Second {
+		t.Errorf("got delays %v, want a burst of 2 then one every 500ms", d)
+	}
+
+	clock.advance(3 * time.Second)
+
+	// the bucket refills up to the burst
+	d = delays(l.Reserve(), l.Reserve(), l.Reserve())
+	if d[0] != 0 || d[1] != 0 || d[2] != 500*time.Millisecond {
+		t.Errorf("got delays %v after 3s, want a burst of 2 again", d)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l, _ := newTestLimiter(LimiterConfig{RequestsPerSecond: 1, RequestsPerDay: 10}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	l.Reserve()
+	r := l.Reserve()
+	if r.Delay() != time.Second {
+		t.Fatalf("got delay %v, want 1s", r.Delay())
+	}
+
+	r.Cancel()
+
+	if status := l.Status(); status.DailyRemaining != 9 {
+		t.Errorf("got %v remaining after cancel, want 9", status.DailyRemaining)
+	}
+	if d := l.Reserve().Delay(); d != time.Second {
+		t.Errorf("got delay %v after cancel, want the token returned", d)
+	}
+
+	// cancelling twice returns the slot once
+	r.Cancel()
+	if status := l.Status(); status.DailyRemaining != 8 {
+		t.Errorf("got %v remaining after a second cancel, want 8", status.DailyRemaining)
+	}
+}
+
+func TestLimiterPace(t *testing.T) {
+	// 10 requests over the 10 hours left until midnight
+	l, clock := newTestLimiter(LimiterConfig{RequestsPerDay: 10, Pace: true}, time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC))
+
+	first, second := l.Reserve(), l.Reserve()
+	if first.Delay() != 0 || second.Delay() != time.Hour {
+		t.Fatalf("got delays %v, want 0 and 1h", delays(first, second))
+	}
+
+	second.Cancel()
+
+	if d := l.Reserve().Delay(); d != time.Hour {
+		t.Errorf("got delay %v after cancel, want the cancelled slot of 1h", d)
+	}
+
+	clock.advance(5 * time.Hour)
+
+	if d := l.Reserve().Delay(); d != 0 {
+		t.Errorf("got delay %v after falling behind the pace, want 0", d)
+	}
+}
+
+func TestLimiterNextDay(t *testing.T) {
+	l, clock := newTestLimiter(LimiterConfig{RequestsPerDay: 2}, time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC))
+	midnight := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	today := []*Reservation{l.Reserve(), l.Reserve()}
+	tomorrow := []*Reservation{l.Reserve(), l.Reserve()}
+	exhausted := l.Reserve()
+
+	if d := delays(today...); d[0] != 0 || d[1] != 0 {
+		t.Errorf("got delays %v, want today's budget without delay", d)
+	}
+	for _, r := range tomorrow {
+		if !r.OK() || r.Delay() != time.Hour {
+			t.Errorf("got delay %v, want the reservation pushed to midnight", r.Delay())
+		}
+	}
+	if exhausted.OK() {
+		t.Error("expected no reservation once the budget of tomorrow is used up too")
+	}
+
+	err := l.Wait(context.Background())
+	if err == nil {
+		t.Error("expected Wait to fail once the budget of tomorrow is used up too")
+	}
+
+	tomorrow[1].Cancel()
+
+	clock.advance(90 * time.Minute)
+
+	status := l.Status()
+	if status.DailyRemaining != 1 {
+		t.Errorf("got %v remaining after midnight, want 1 left after the reserved request", status.DailyRemaining)
+	}
+	if !status.ResetAt.Equal(midnight.Add(24 * time.Hour)) {
+		t.Errorf("got reset at %v, want the next midnight", status.ResetAt)
+	}
+
+	// cancelling a reservation of a day that has passed does not return its slot
+	today[1].Cancel()
+	if status := l.Status(); status.DailyRemaining != 1 {
+		t.Errorf("got %v remaining after cancelling yesterday's reservation, want 1", status.DailyRemaining)
+	}
+}
+
+func TestLimiterProjectedExhaustion(t *testing.T) {
+	l, clock := newTestLimiter(LimiterConfig{RequestsPerDay: 100}, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	clock.advance(12 * time.Hour)
+	for range 10 {
+		l.Reserve()
+	}
+
+	// 10 requests in 12 hours leave 90 requests for 108 hours
+	if status := l.Status(); status.ProjectedExhaustion != nil {
+		t.Errorf("got exhaustion at %v, want the budget to last the day", status.ProjectedExhaustion)
+	}
+
+	for range 50 {
+		l.Reserve()
+	}
+
+	// 60 requests in 12 hours leave 40 requests for 8 hours
+	want := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	status := l.Status()
+	if status.ProjectedExhaustion == nil || status.ProjectedExhaustion.Sub(want).Abs() > time.Second {
+		t.Errorf("got exhaustion at %v, want %v", status.ProjectedExhaustion, want)
+	}
+}
+
+func TestLimiterProjectedExhaustionIgnoresNextDay(t *testing.T) {
+	l, clock := newTestLimiter(LimiterConfig{}, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	clock.advance(12 * time.Hour)
+	l.update(ptrTo(int64(10)), ptrTo(int64(2)))
+	l.Reserve()
+	l.Reserve()
+	l.Reserve()
+	l.Reserve()
+
+	// Insightly reports 2 requests left, the 2 requests of today leave 2 requests
+	// for 12 hours, the 2 requests reserved for tomorrow do not count
+	l.update(ptrTo(int64(10)), ptrTo(int64(2)))
+	if status := l.Status(); status.ProjectedExhaustion != nil {
+		t.Errorf("got exhaustion at %v, want the budget to last the day", status.ProjectedExhaustion)
+	}
+}
+
+func TestLimiterUpdate(t *testing.T) {
+	l, _ := newTestLimiter(LimiterConfig{}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	if r := l.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Error("expected no limit while the daily budget is unknown")
+	}
+
+	l.update(ptrTo(int64(1000)), ptrTo(int64(500)))
+	if status := l.Status(); status.DailyLimit != 1000 || status.DailyRemaining != 500 {
+		t.Errorf("got %v of %v, want 500 of 1000 from the headers", status.DailyRemaining, status.DailyLimit)
+	}
+
+	budgeted, _ := newTestLimiter(LimiterConfig{RequestsPerDay: 100}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	budgeted.update(ptrTo(int64(1000)), ptrTo(int64(500)))
+	if status := budgeted.Status(); status.DailyLimit != 100 || status.DailyRemaining != 100 {
+		t.Errorf("got %v of %v, want the configured budget of 100", status.DailyRemaining, status.DailyLimit)
+	}
+	budgeted.update(ptrTo(int64(1000)), ptrTo(int64(40)))
+	if status := budgeted.Status(); status.DailyRemaining != 40 {
+		t.Errorf("got %v remaining, want 40 as lowered by the headers", status.DailyRemaining)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	// Wait sleeps on the real clock, the test clock standing still makes every next
+	// request wait for a second
+	l, _ := newTestLimiter(LimiterConfig{RequestsPerSecond: 1, RequestsPerDay: 10}, time.Now())
+
+	err := l.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err.Message())
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+
+	err = l.Wait(short)
+	if err == nil {
+		t.Error("expected an error for a wait beyond the deadline")
+	}
+	if status := l.Status(); status.DailyRemaining != 9 {
+		t.Errorf("got %v remaining, want the slot of the failed wait returned", status.DailyRemaining)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = l.Wait(cancelled)
+	if err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+	if status := l.Status(); status.DailyRemaining != 9 {
+		t.Errorf("got %v remaining, want the slot of the cancelled wait returned", status.DailyRemaining)
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
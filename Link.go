@@ -1,5 +1,16 @@
 package insightly
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	go_http "github.com/leapforce-libraries/go_http"
+)
+
 type Link struct {
 	LinkID         *int64  `json:"LINK_ID,omitempty"`
 	ObjectName     *string `json:"OBJECT_NAME,omitempty"`
@@ -11,3 +22,199 @@ type Link struct {
 	RelationshipID *int64  `json:"RELATIONSHIP_ID,omitempty"`
 	IsForward      *bool   `json:"IS_FORWARD,omitempty"`
 }
+
+// ObjectName is the name Insightly uses for a type of record in links and tags, custom
+// object records use the name of their custom object, e.g. ObjectName("Invoice__c")
+//
+type ObjectName string
+
+const (
+	ObjectNameContact      ObjectName = "Contact"
+	ObjectNameLead         ObjectName = "Lead"
+	ObjectNameOrganisation ObjectName = "Organisation"
+	ObjectNameOpportunity  ObjectName = "Opportunity"
+	ObjectNameProject      ObjectName = "Project"
+	ObjectNameTask         ObjectName = "Task"
+	ObjectNameEvent        ObjectName = "Event"
+	ObjectNameNote         ObjectName = "Note"
+	ObjectNameEmail        ObjectName = "Email"
+	ObjectNameProspect     ObjectName = "Prospect"
+)
+
+const customObjectSuffix string = "__c"
+
+// objectNameEndpoints maps object names to the endpoint of their records, as defined by
+// their Resource
+//
+var objectNameEndpoints = map[ObjectName]string{
+	ObjectNameContact:      contactResource.Endpoint,
+	ObjectNameLead:         leadResource.Endpoint,
+	ObjectNameOrganisation: organisationResource.Endpoint,
+	ObjectNameOpportunity:  opportunityResource.Endpoint,
+	ObjectNameProject:      projectResource.Endpoint,
+	ObjectNameTask:         taskResource.Endpoint,
+	ObjectNameEvent:        eventResource.Endpoint,
+	ObjectNameNote:         noteResource.Endpoint,
+	ObjectNameEmail:        emailResource.Endpoint,
+	ObjectNameProspect:     prospectResource.Endpoint,
+}
+
+var linkableObjectNames = []ObjectName{
+	ObjectNameContact,
+	ObjectNameLead,
+	ObjectNameOrganisation,
+	ObjectNameOpportunity,
+	ObjectNameProject,
+	ObjectNameTask,
+	ObjectNameEvent,
+	ObjectNameNote,
+	ObjectNameEmail,
+}
+
+// linksEndpoint returns the endpoint of the records of the object type if they can be linked
+//
+func (objectName ObjectName) linksEndpoint() (string, *errortools.Error) {
+	if slices.Contains(linkableObjectNames, objectName) {
+		return objectNameEndpoints[objectName], nil
+	}
+
+	if strings.HasSuffix(string(objectName), customObjectSuffix) {
+		return string(objectName), nil
+	}
+
+	return "", errortools.ErrorMessagef("Object %s cannot be linked", objectName)
+}
+
+// NewLink returns a Link to the record with id linkObjectID of type linkObjectName, to
+// be passed to CreateLink
+//
+func NewLink(linkObjectName ObjectName, linkObjectID int64) *Link {
+	name := string(linkObjectName)
+
+	return &Link{
+		LinkObjectName: &name,
+		LinkObjectID:   &linkObjectID,
+	}
+}
+
+// GetLinks returns the links of a specific record
+//
+func (service *Service) GetLinks(objectName ObjectName, objectID int64) (*[]Link, *errortools.Error) {
+	return service.GetLinksWithContext(context.Background(), objectName, objectID)
+}
+
+// GetLinksWithContext is the context-aware variant of GetLinks
+//
+func (service *Service) GetLinksWithContext(ctx context.Context, objectName ObjectName, objectID int64) (*[]Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetLinks")
+	defer span.End()
+
+	return getLinks(ctx, service, objectName, objectID)
+}
+
+// CreateLink links a specific record to the record in link
+//
+func (service *Service) CreateLink(objectName ObjectName, objectID int64, link *Link) (*Link, *errortools.Error) {
+	return service.CreateLinkWithContext(context.Background(), objectName, objectID, link)
+}
+
+// CreateLinkWithContext is the context-aware variant of CreateLink
+//
+func (service *Service) CreateLinkWithContext(ctx context.Context, objectName ObjectName, objectID int64, link *Link) (*Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateLink")
+	defer span.End()
+
+	return writeLink(ctx, service, http.MethodPost, objectName, objectID, link)
+}
+
+// UpdateLink updates the Role, Details and RelationshipID of an existing link of a specific record
+//
+func (service *Service) UpdateLink(objectName ObjectName, objectID int64, link *Link) (*Link, *errortools.Error) {
+	return service.UpdateLinkWithContext(context.Background(), objectName, objectID, link)
+}
+
+// UpdateLinkWithContext is the context-aware variant of UpdateLink
+//
+func (service *Service) UpdateLinkWithContext(ctx context.Context, objectName ObjectName, objectID int64, link *Link) (*Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateLink")
+	defer span.End()
+
+	if link != nil && link.LinkID == nil {
+		return nil, errortools.ErrorMessage("Cannot update link without id")
+	}
+
+	return writeLink(ctx, service, http.MethodPut, objectName, objectID, link)
+}
+
+// DeleteLink deletes a specific link of a specific record
+//
+func (service *Service) DeleteLink(objectName ObjectName, objectID int64, linkID int64) *errortools.Error {
+	return service.DeleteLinkWithContext(context.Background(), objectName, objectID, linkID)
+}
+
+// DeleteLinkWithContext is the context-aware variant of DeleteLink
+//
+func (service *Service) DeleteLinkWithContext(ctx context.Context, objectName ObjectName, objectID int64, linkID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteLink")
+	defer span.End()
+
+	endpoint, e := objectName.linksEndpoint()
+	if e != nil {
+		return e
+	}
+
+	requestConfig := go_http.RequestConfig{
+		Method: http.MethodDelete,
+		Url:    service.url(fmt.Sprintf("%s/%v/Links/%v", endpoint, objectID, linkID)),
+	}
+	_, _, e = service.httpRequest(ctx, &requestConfig)
+
+	return e
+}
+
+func getLinks(ctx context.Context, service *Service, objectName ObjectName, objectID int64) (*[]Link, *errortools.Error) {
+	endpoint, e := objectName.linksEndpoint()
+	if e != nil {
+		return nil, e
+	}
+
+	links := []Link{}
+
+	requestConfig := go_http.RequestConfig{
+		Method:        http.MethodGet,
+		Url:           service.url(fmt.Sprintf("%s/%v/Links", endpoint, objectID)),
+		ResponseModel: &links,
+	}
+	_, _, e = service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return nil, e
+	}
+
+	return &links, nil
+}
+
+func writeLink(ctx context.Context, service *Service, method string, objectName ObjectName, objectID int64, link *Link) (*Link, *errortools.Error) {
+	if link == nil {
+		return nil, nil
+	}
+
+	endpoint, e := objectName.linksEndpoint()
+	if e != nil {
+		return nil, e
+	}
+
+	linkNew := Link{}
+
+	requestConfig := go_http.RequestConfig{
+		Method:        method,
+		Url:           service.url(fmt.Sprintf("%s/%v/Links", endpoint, objectID)),
+		BodyModel:     link,
+		ResponseModel: &linkNew,
+	}
+	_, _, e = service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return nil, e
+	}
+
+	return &linkNew, nil
+}
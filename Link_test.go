@@ -0,0 +1,131 @@
+package insightly_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestCreateUpdateDeleteLink(t *testing.T) {
+	service, server := newTestService(t, nil)
+	firstName := "Jane"
+	contactID := server.Seed("Contacts", &insightly.Contact{FirstName: &firstName})[0]
+	projectID := server.Seed("Projects", map[string]any{"PROJECT_NAME": "Launch"})[0]
+
+	role := "Lead"
+	link, e := service.CreateLink(insightly.ObjectNameProject, projectID, &insightly.Link{
+		LinkObjectName: insightly.NewLink(insightly.ObjectNameContact, contactID).LinkObjectName,
+		LinkObjectID:   &contactID,
+		Role:           &role,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if link.LinkID == nil || *link.ObjectName != "Project" || *link.ObjectID != projectID || *link.LinkObjectID != contactID || *link.Role != role {
+		t.Fatalf("got link %+v, want the project linked to the contact", link)
+	}
+
+	details := "Runs the launch"
+	link.Details = &details
+	updated, e := service.UpdateLink(insightly.ObjectNameProject, projectID, link)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if updated.Details == nil || *updated.Details != details {
+		t.Errorf("got details %v, want %q", updated.Details, details)
+	}
+
+	// the link is seen from the contact too
+	links, e := service.GetLinks(insightly.ObjectNameContact, contactID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*links) != 1 || *(*links)[0].LinkObjectID != projectID || *(*links)[0].Details != details {
+		t.Errorf("got links %v of the contact, want the updated link to the project", *links)
+	}
+
+	e = service.DeleteLink(insightly.ObjectNameProject, projectID, *link.LinkID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	links, e = service.GetLinks(insightly.ObjectNameProject, projectID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*links) != 0 {
+		t.Errorf("got %v links after delete, want 0", len(*links))
+	}
+
+	want := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, fmt.Sprintf("/Projects/%v/Links", projectID)},
+		{http.MethodPut, fmt.Sprintf("/Projects/%v/Links", projectID)},
+		{http.MethodGet, fmt.Sprintf("/Contacts/%v/Links", contactID)},
+		{http.MethodDelete, fmt.Sprintf("/Projects/%v/Links/%v", projectID, *link.LinkID)},
+		{http.MethodGet, fmt.Sprintf("/Projects/%v/Links", projectID)},
+	}
+	requests := server.Requests()
+	if len(requests) != len(want) {
+		t.Fatalf("got %v requests, want %v", len(requests), len(want))
+	}
+	for i, request := range requests {
+		if request.Method != want[i].method || !strings.HasSuffix(request.Path, want[i].path) {
+			t.Errorf("got %s %s, want %s %s", request.Method, request.Path, want[i].method, want[i].path)
+		}
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	_, e := service.UpdateLink(insightly.ObjectNameContact, 1, &insightly.Link{})
+	if e == nil {
+		t.Error("expected an error for a link without id")
+	}
+
+	_, e = service.CreateLink(insightly.ObjectNameProspect, 1, insightly.NewLink(insightly.ObjectNameContact, 1))
+	if e == nil {
+		t.Error("expected an error for an object that cannot be linked")
+	}
+
+	e = service.DeleteLink(insightly.ObjectName("Pricebook"), 1, 1)
+	if e == nil {
+		t.Error("expected an error for an object that cannot be linked")
+	}
+
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("got %v requests, want none", len(requests))
+	}
+}
+
+func TestGetOpportunityLinks(t *testing.T) {
+	service, server := newTestService(t, nil)
+	name := "Website"
+	opportunityID := server.Seed("Opportunities", &insightly.Opportunity{OpportunityName: &name})[0]
+	organisationID := server.Seed("Organisations", map[string]any{"ORGANISATION_NAME": "Acme"})[0]
+
+	_, e := service.CreateOpportunityLink(opportunityID, insightly.NewLink(insightly.ObjectNameOrganisation, organisationID))
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	server.ResetRequests()
+
+	links, e := service.GetOpportunityLinks(opportunityID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*links) != 1 || *(*links)[0].LinkObjectID != organisationID {
+		t.Errorf("got links %v, want the link to the organisation", *links)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Method != http.MethodGet || !strings.HasSuffix(requests[0].Path, fmt.Sprintf("/Opportunities/%v/Links", opportunityID)) {
+		t.Errorf("got requests %v, want GET Opportunities/%v/Links", requests, opportunityID)
+	}
+}
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -26,102 +23,136 @@ type Milestone struct {
 	ResponsibleUserID int64                   `json:"RESPONSIBLE_USER"`
 }
 
+func (m *Milestone) prepareMarshal() interface{} {
+	if m == nil {
+		return nil
+	}
+
+	return &struct {
+		MilestoneID       *int64                  `json:"MILESTONE_ID,omitempty"`
+		Title             *string                 `json:"TITLE,omitempty"`
+		Completed         *bool                   `json:"COMPLETED,omitempty"`
+		DueDate           *i_types.DateTimeString `json:"DUE_DATE,omitempty"`
+		OwnerUserID       *int64                  `json:"OWNER_USER_ID,omitempty"`
+		ProjectID         *int64                  `json:"PROJECT_ID,omitempty"`
+		ResponsibleUserID *int64                  `json:"RESPONSIBLE_USER,omitempty"`
+	}{
+		nonZero(m.MilestoneID),
+		&m.Title,
+		&m.Completed,
+		dateTimeStringPtr(m.DueDate),
+		nonZero(m.OwnerUserID),
+		nonZero(m.ProjectID),
+		nonZero(m.ResponsibleUserID),
+	}
+}
+
+var milestoneResource = &Resource[Milestone]{
+	Endpoint: "Milestones",
+	ID:       func(m *Milestone) int64 { return m.MilestoneID },
+	Marshal:  func(m *Milestone) interface{} { return m.prepareMarshal() },
+}
+
 // GetMilestone returns a specific milestone
 //
 func (service *Service) GetMilestone(milestoneID int64) (*Milestone, *errortools.Error) {
-	milestone := Milestone{}
+	return service.GetMilestoneWithContext(context.Background(), milestoneID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Milestones/%v", milestoneID)),
-		ResponseModel: &milestone,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetMilestoneWithContext is the context-aware variant of GetMilestone
+//
+func (service *Service) GetMilestoneWithContext(ctx context.Context, milestoneID int64) (*Milestone, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetMilestone")
+	defer span.End()
 
-	return &milestone, nil
+	return milestoneResource.Get(ctx, service, milestoneID)
 }
 
-type GetMilestonesConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetMilestonesConfig = ListConfig
 
 // GetMilestones returns all milestones
 //
 func (service *Service) GetMilestones(config *GetMilestonesConfig) (*[]Milestone, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Milestones"
-	milestones := []Milestone{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetMilestonesWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetMilestonesWithContext is the context-aware variant of GetMilestones
+//
+func (service *Service) GetMilestonesWithContext(ctx context.Context, config *GetMilestonesConfig) (*[]Milestone, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetMilestones")
+	defer span.End()
+
+	return milestoneResource.List(ctx, service, config)
+}
+
+// GetMilestonesPage returns a single page of milestones and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetMilestonesPage(config *GetMilestonesConfig) (*[]Milestone, *Cursor, *errortools.Error) {
+	return service.GetMilestonesPageWithContext(context.Background(), config)
+}
+
+// GetMilestonesPageWithContext is the context-aware variant of GetMilestonesPage
+//
+func (service *Service) GetMilestonesPageWithContext(ctx context.Context, config *GetMilestonesConfig) (*[]Milestone, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetMilestonesPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return milestoneResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		milestonesBatch := []Milestone{}
+// MilestonesSeq returns an iterator over all milestones, fetching pages on demand
+//
+func (service *Service) MilestonesSeq(config *GetMilestonesConfig) iter.Seq2[Milestone, *errortools.Error] {
+	return service.MilestonesSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &milestonesBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// MilestonesSeqWithContext is the context-aware variant of MilestonesSeq
+//
+func (service *Service) MilestonesSeqWithContext(ctx context.Context, config *GetMilestonesConfig) iter.Seq2[Milestone, *errortools.Error] {
+	return seq(ctx, service, "MilestonesSeq", milestoneResource, config)
+}
 
-		milestones = append(milestones, milestonesBatch...)
+// CreateMilestone creates a new milestone
+//
+func (service *Service) CreateMilestone(milestone *Milestone) (*Milestone, *errortools.Error) {
+	return service.CreateMilestoneWithContext(context.Background(), milestone)
+}
 
-		if len(milestonesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreateMilestoneWithContext is the context-aware variant of CreateMilestone
+//
+func (service *Service) CreateMilestoneWithContext(ctx context.Context, milestone *Milestone) (*Milestone, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateMilestone")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return milestoneResource.Create(ctx, service, milestone)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &milestones, nil
-		}
-	}
+// UpdateMilestone updates an existing milestone
+//
+func (service *Service) UpdateMilestone(milestone *Milestone) (*Milestone, *errortools.Error) {
+	return service.UpdateMilestoneWithContext(context.Background(), milestone)
+}
+
+// UpdateMilestoneWithContext is the context-aware variant of UpdateMilestone
+//
+func (service *Service) UpdateMilestoneWithContext(ctx context.Context, milestone *Milestone) (*Milestone, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateMilestone")
+	defer span.End()
+
+	return milestoneResource.Update(ctx, service, milestone)
+}
+
+// DeleteMilestone deletes a specific milestone
+//
+func (service *Service) DeleteMilestone(milestoneID int64) *errortools.Error {
+	return service.DeleteMilestoneWithContext(context.Background(), milestoneID)
+}
+
+// DeleteMilestoneWithContext is the context-aware variant of DeleteMilestone
+//
+func (service *Service) DeleteMilestoneWithContext(ctx context.Context, milestoneID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteMilestone")
+	defer span.End()
 
-	return &milestones, nil
+	return milestoneResource.Delete(ctx, service, milestoneID)
 }
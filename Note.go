@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -23,102 +20,130 @@ type Note struct {
 	Links          *[]Link                `json:"LINKS"`
 }
 
+func (n *Note) prepareMarshal() interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return &struct {
+		NoteID      *int64  `json:"NOTE_ID,omitempty"`
+		Title       *string `json:"TITLE,omitempty"`
+		Body        *string `json:"BODY,omitempty"`
+		OwnerUserID *int64  `json:"OWNER_USER_ID,omitempty"`
+	}{
+		nonZero(n.NoteID),
+		&n.Title,
+		&n.Body,
+		nonZero(n.OwnerUserID),
+	}
+}
+
+var noteResource = &Resource[Note]{
+	Endpoint: "Notes",
+	ID:       func(n *Note) int64 { return n.NoteID },
+	Marshal:  func(n *Note) interface{} { return n.prepareMarshal() },
+}
+
 // GetNote returns a specific note
 //
 func (service *Service) GetNote(noteID int64) (*Note, *errortools.Error) {
-	note := Note{}
+	return service.GetNoteWithContext(context.Background(), noteID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Notes/%v", noteID)),
-		ResponseModel: &note,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetNoteWithContext is the context-aware variant of GetNote
+//
+func (service *Service) GetNoteWithContext(ctx context.Context, noteID int64) (*Note, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetNote")
+	defer span.End()
 
-	return &note, nil
+	return noteResource.Get(ctx, service, noteID)
 }
 
-type GetNotesConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetNotesConfig = ListConfig
 
 // GetNotes returns all notes
 //
 func (service *Service) GetNotes(config *GetNotesConfig) (*[]Note, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Notes"
-	notes := []Note{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetNotesWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetNotesWithContext is the context-aware variant of GetNotes
+//
+func (service *Service) GetNotesWithContext(ctx context.Context, config *GetNotesConfig) (*[]Note, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetNotes")
+	defer span.End()
+
+	return noteResource.List(ctx, service, config)
+}
+
+// GetNotesPage returns a single page of notes and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetNotesPage(config *GetNotesConfig) (*[]Note, *Cursor, *errortools.Error) {
+	return service.GetNotesPageWithContext(context.Background(), config)
+}
+
+// GetNotesPageWithContext is the context-aware variant of GetNotesPage
+//
+func (service *Service) GetNotesPageWithContext(ctx context.Context, config *GetNotesConfig) (*[]Note, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetNotesPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return noteResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		notesBatch := []Note{}
+// NotesSeq returns an iterator over all notes, fetching pages on demand
+//
+func (service *Service) NotesSeq(config *GetNotesConfig) iter.Seq2[Note, *errortools.Error] {
+	return service.NotesSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &notesBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// NotesSeqWithContext is the context-aware variant of NotesSeq
+//
+func (service *Service) NotesSeqWithContext(ctx context.Context, config *GetNotesConfig) iter.Seq2[Note, *errortools.Error] {
+	return seq(ctx, service, "NotesSeq", noteResource, config)
+}
 
-		notes = append(notes, notesBatch...)
+// CreateNote creates a new note
+//
+func (service *Service) CreateNote(note *Note) (*Note, *errortools.Error) {
+	return service.CreateNoteWithContext(context.Background(), note)
+}
 
-		if len(notesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreateNoteWithContext is the context-aware variant of CreateNote
+//
+func (service *Service) CreateNoteWithContext(ctx context.Context, note *Note) (*Note, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateNote")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return noteResource.Create(ctx, service, note)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &notes, nil
-		}
-	}
+// UpdateNote updates an existing note
+//
+func (service *Service) UpdateNote(note *Note) (*Note, *errortools.Error) {
+	return service.UpdateNoteWithContext(context.Background(), note)
+}
+
+// UpdateNoteWithContext is the context-aware variant of UpdateNote
+//
+func (service *Service) UpdateNoteWithContext(ctx context.Context, note *Note) (*Note, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateNote")
+	defer span.End()
+
+	return noteResource.Update(ctx, service, note)
+}
+
+// DeleteNote deletes a specific note
+//
+func (service *Service) DeleteNote(noteID int64) *errortools.Error {
+	return service.DeleteNoteWithContext(context.Background(), noteID)
+}
+
+// DeleteNoteWithContext is the context-aware variant of DeleteNote
+//
+func (service *Service) DeleteNoteWithContext(ctx context.Context, noteID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteNote")
+	defer span.End()
 
-	return &notes, nil
+	return noteResource.Delete(ctx, service, noteID)
 }
@@ -1,10 +1,10 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"net/http"
-	"net/url"
-	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -42,146 +42,123 @@ type Opportunity struct {
 	Links               *[]Link                 `json:"LINKS,omitempty"`
 }
 
+var opportunityResource = &Resource[Opportunity]{
+	Endpoint: "Opportunities",
+	ID:       func(o *Opportunity) int64 { return o.OpportunityID },
+}
+
 // GetOpportunity returns a specific opportunity
 func (service *Service) GetOpportunity(opportunityID int64) (*Opportunity, *errortools.Error) {
-	opportunity := Opportunity{}
+	return service.GetOpportunityWithContext(context.Background(), opportunityID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Opportunities/%v", opportunityID)),
-		ResponseModel: &opportunity,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetOpportunityWithContext is the context-aware variant of GetOpportunity
+func (service *Service) GetOpportunityWithContext(ctx context.Context, opportunityID int64) (*Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunity")
+	defer span.End()
 
-	return &opportunity, nil
+	return opportunityResource.Get(ctx, service, opportunityID)
 }
 
-type GetOpportunitiesConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetOpportunitiesConfig = ListConfig
 
 // GetOpportunities returns all opportunities
 func (service *Service) GetOpportunities(config *GetOpportunitiesConfig) (*[]Opportunity, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Opportunities"
-	opportunities := []Opportunity{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetOpportunitiesWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetOpportunitiesWithContext is the context-aware variant of GetOpportunities
+func (service *Service) GetOpportunitiesWithContext(ctx context.Context, config *GetOpportunitiesConfig) (*[]Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunities")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return opportunityResource.List(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		opportunitiesBatch := []Opportunity{}
+// GetOpportunitiesPage returns a single page of opportunities and the Cursor of the next page, which is nil after the last page
+func (service *Service) GetOpportunitiesPage(config *GetOpportunitiesConfig) (*[]Opportunity, *Cursor, *errortools.Error) {
+	return service.GetOpportunitiesPageWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &opportunitiesBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// GetOpportunitiesPageWithContext is the context-aware variant of GetOpportunitiesPage
+func (service *Service) GetOpportunitiesPageWithContext(ctx context.Context, config *GetOpportunitiesConfig) (*[]Opportunity, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunitiesPage")
+	defer span.End()
 
-		opportunities = append(opportunities, opportunitiesBatch...)
+	return opportunityResource.Page(ctx, service, config)
+}
 
-		if len(opportunitiesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// OpportunitiesSeq returns an iterator over all opportunities, fetching pages on demand
+func (service *Service) OpportunitiesSeq(config *GetOpportunitiesConfig) iter.Seq2[Opportunity, *errortools.Error] {
+	return service.OpportunitiesSeqWithContext(context.Background(), config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// OpportunitiesSeqWithContext is the context-aware variant of OpportunitiesSeq
+func (service *Service) OpportunitiesSeqWithContext(ctx context.Context, config *GetOpportunitiesConfig) iter.Seq2[Opportunity, *errortools.Error] {
+	return seq(ctx, service, "OpportunitiesSeq", opportunityResource, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &opportunities, nil
-		}
-	}
+// SearchOpportunitiesByTag returns the opportunities that have a specific tag
+func (service *Service) SearchOpportunitiesByTag(tagName string, config *SearchByTagConfig) (*[]Opportunity, *errortools.Error) {
+	return service.SearchOpportunitiesByTagWithContext(context.Background(), tagName, config)
+}
 
-	return &opportunities, nil
+// SearchOpportunitiesByTagWithContext is the context-aware variant of SearchOpportunitiesByTag
+func (service *Service) SearchOpportunitiesByTagWithContext(ctx context.Context, tagName string, config *SearchByTagConfig) (*[]Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SearchOpportunitiesByTag")
+	defer span.End()
+
+	return opportunityResource.ListByTag(ctx, service, tagName, config)
+}
+
+// QueryOpportunities returns the opportunities that match a Query
+func (service *Service) QueryOpportunities(query *Query) (*[]Opportunity, *errortools.Error) {
+	return service.QueryOpportunitiesWithContext(context.Background(), query)
+}
+
+// QueryOpportunitiesWithContext is the context-aware variant of QueryOpportunities
+func (service *Service) QueryOpportunitiesWithContext(ctx context.Context, query *Query) (*[]Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "QueryOpportunities")
+	defer span.End()
+
+	return opportunityResource.Query(ctx, service, query)
+}
+
+// QueryOpportunitiesSeq returns an iterator over the opportunities that match a Query, fetching pages on demand
+func (service *Service) QueryOpportunitiesSeq(query *Query) iter.Seq2[Opportunity, *errortools.Error] {
+	return service.QueryOpportunitiesSeqWithContext(context.Background(), query)
+}
+
+// QueryOpportunitiesSeqWithContext is the context-aware variant of QueryOpportunitiesSeq
+func (service *Service) QueryOpportunitiesSeqWithContext(ctx context.Context, query *Query) iter.Seq2[Opportunity, *errortools.Error] {
+	return querySeq[Opportunity](ctx, service, "QueryOpportunitiesSeq", opportunityResource.Endpoint, query)
 }
 
 // CreateOpportunity creates a new contract
 func (service *Service) CreateOpportunity(opportunity *Opportunity) (*Opportunity, *errortools.Error) {
-	if opportunity == nil {
-		return nil, nil
-	}
-
-	opportunityNew := Opportunity{}
+	return service.CreateOpportunityWithContext(context.Background(), opportunity)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Opportunities"),
-		BodyModel:     opportunity,
-		ResponseModel: &opportunityNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateOpportunityWithContext is the context-aware variant of CreateOpportunity
+func (service *Service) CreateOpportunityWithContext(ctx context.Context, opportunity *Opportunity) (*Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateOpportunity")
+	defer span.End()
 
-	return &opportunityNew, nil
+	return opportunityResource.Create(ctx, service, opportunity)
 }
 
-// UpdateOpportunity updates an existing opportunity
+// UpdateOpportunity updates an existing opportunity, an opportunity without OpportunityID
+// is rejected without sending a request
 func (service *Service) UpdateOpportunity(opportunity *Opportunity) (*Opportunity, *errortools.Error) {
-	if opportunity == nil {
-		return nil, nil
-	}
-
-	opportunityUpdated := Opportunity{}
+	return service.UpdateOpportunityWithContext(context.Background(), opportunity)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Opportunities"),
-		BodyModel:     opportunity,
-		ResponseModel: &opportunityUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateOpportunityWithContext is the context-aware variant of UpdateOpportunity
+func (service *Service) UpdateOpportunityWithContext(ctx context.Context, opportunity *Opportunity) (*Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateOpportunity")
+	defer span.End()
 
-	return &opportunityUpdated, nil
+	return opportunityResource.Update(ctx, service, opportunity)
 }
 
 type OpportunityPipeline struct {
@@ -209,6 +186,14 @@ type OpportunityPipelineActivityAssignment struct {
 
 // UpdateOpportunityPipeline updates pipeline of an existing opportunity
 func (service *Service) UpdateOpportunityPipeline(opportunityId int64, opportunityPipeline *OpportunityPipeline) (*Opportunity, *errortools.Error) {
+	return service.UpdateOpportunityPipelineWithContext(context.Background(), opportunityId, opportunityPipeline)
+}
+
+// UpdateOpportunityPipelineWithContext is the context-aware variant of UpdateOpportunityPipeline
+func (service *Service) UpdateOpportunityPipelineWithContext(ctx context.Context, opportunityId int64, opportunityPipeline *OpportunityPipeline) (*Opportunity, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateOpportunityPipeline")
+	defer span.End()
+
 	if opportunityPipeline == nil {
 		return nil, nil
 	}
@@ -221,7 +206,7 @@ func (service *Service) UpdateOpportunityPipeline(opportunityId int64, opportuni
 		BodyModel:     opportunityPipeline,
 		ResponseModel: &opportunityUpdated,
 	}
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
@@ -231,59 +216,53 @@ func (service *Service) UpdateOpportunityPipeline(opportunityId int64, opportuni
 
 // DeleteOpportunity deletes a specific opportunity
 func (service *Service) DeleteOpportunity(opportunityID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Opportunities/%v", opportunityID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteOpportunityWithContext(context.Background(), opportunityID)
+}
 
-	return nil
+// DeleteOpportunityWithContext is the context-aware variant of DeleteOpportunity
+func (service *Service) DeleteOpportunityWithContext(ctx context.Context, opportunityID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteOpportunity")
+	defer span.End()
+
+	return opportunityResource.Delete(ctx, service, opportunityID)
 }
 
 // GetOpportunityLinks returns links for a specific opportunity
 func (service *Service) GetOpportunityLinks(opportunityID int64) (*[]Link, *errortools.Error) {
-	links := []Link{}
+	return service.GetOpportunityLinksWithContext(context.Background(), opportunityID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Opportunity/%v/Links", opportunityID)),
-		ResponseModel: &links,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetOpportunityLinksWithContext is the context-aware variant of GetOpportunityLinks
+func (service *Service) GetOpportunityLinksWithContext(ctx context.Context, opportunityID int64) (*[]Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityLinks")
+	defer span.End()
 
-	return &links, nil
+	return getLinks(ctx, service, ObjectNameOpportunity, opportunityID)
 }
 
 // CreateOpportunityLink creates a new link for an opportunity
-func (service *Service) CreateOpportunityLink(opportunityId int64, link *Link) (*Opportunity, *errortools.Error) {
-	if link == nil {
-		return nil, nil
-	}
-
-	opportunityNew := Opportunity{}
+func (service *Service) CreateOpportunityLink(opportunityId int64, link *Link) (*Link, *errortools.Error) {
+	return service.CreateOpportunityLinkWithContext(context.Background(), opportunityId, link)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url(fmt.Sprintf("Opportunities/%v/Links", opportunityId)),
-		BodyModel:     link,
-		ResponseModel: &opportunityNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateOpportunityLinkWithContext is the context-aware variant of CreateOpportunityLink
+func (service *Service) CreateOpportunityLinkWithContext(ctx context.Context, opportunityId int64, link *Link) (*Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateOpportunityLink")
+	defer span.End()
 
-	return &opportunityNew, nil
+	return writeLink(ctx, service, http.MethodPost, ObjectNameOpportunity, opportunityId, link)
 }
 
 // GetOpportunityFileAttachments returns the file attachments of a specific email
 func (service *Service) GetOpportunityFileAttachments(id int64) (*[]FileAttachment, *errortools.Error) {
+	return service.GetOpportunityFileAttachmentsWithContext(context.Background(), id)
+}
+
+// GetOpportunityFileAttachmentsWithContext is the context-aware variant of GetOpportunityFileAttachments
+func (service *Service) GetOpportunityFileAttachmentsWithContext(ctx context.Context, id int64) (*[]FileAttachment, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityFileAttachments")
+	defer span.End()
+
 	var fileAttachments []FileAttachment
 
 	requestConfig := go_http.RequestConfig{
@@ -292,7 +271,7 @@ func (service *Service) GetOpportunityFileAttachments(id int64) (*[]FileAttachme
 		ResponseModel: &fileAttachments,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // OpportunityCategory stores OpportunityCategory from Service
@@ -18,66 +16,51 @@ type OpportunityCategory struct {
 	BackgroundColor string `json:"BACKGROUND_COLOR"`
 }
 
-type GetOpportunityCategoriesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var opportunityCategoryResource = &Resource[OpportunityCategory]{
+	Endpoint: "OpportunityCategories",
+	ID:       func(o *OpportunityCategory) int64 { return o.CategoryID },
 }
 
+type GetOpportunityCategoriesConfig = PagingConfig
+
 // GetOpportunityCategories returns all opportunityCategories
 //
 func (service *Service) GetOpportunityCategories(config *GetOpportunityCategoriesConfig) (*[]OpportunityCategory, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "OpportunityCategories"
-	opportunityCategories := []OpportunityCategory{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		opportunityCategoriesBatch := []OpportunityCategory{}
+	return service.GetOpportunityCategoriesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &opportunityCategoriesBatch,
-		}
+// GetOpportunityCategoriesWithContext is the context-aware variant of GetOpportunityCategories
+//
+func (service *Service) GetOpportunityCategoriesWithContext(ctx context.Context, config *GetOpportunityCategoriesConfig) (*[]OpportunityCategory, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityCategories")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return opportunityCategoryResource.List(ctx, service, config)
+}
 
-		opportunityCategories = append(opportunityCategories, opportunityCategoriesBatch...)
+// GetOpportunityCategoriesPage returns a single page of opportunityCategories and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetOpportunityCategoriesPage(config *GetOpportunityCategoriesConfig) (*[]OpportunityCategory, *Cursor, *errortools.Error) {
+	return service.GetOpportunityCategoriesPageWithContext(context.Background(), config)
+}
 
-		if len(opportunityCategoriesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetOpportunityCategoriesPageWithContext is the context-aware variant of GetOpportunityCategoriesPage
+//
+func (service *Service) GetOpportunityCategoriesPageWithContext(ctx context.Context, config *GetOpportunityCategoriesConfig) (*[]OpportunityCategory, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityCategoriesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return opportunityCategoryResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &opportunityCategories, nil
-		}
-	}
+// OpportunityCategoriesSeq returns an iterator over all opportunityCategories, fetching pages on demand
+//
+func (service *Service) OpportunityCategoriesSeq(config *GetOpportunityCategoriesConfig) iter.Seq2[OpportunityCategory, *errortools.Error] {
+	return service.OpportunityCategoriesSeqWithContext(context.Background(), config)
+}
 
-	return &opportunityCategories, nil
+// OpportunityCategoriesSeqWithContext is the context-aware variant of OpportunityCategoriesSeq
+//
+func (service *Service) OpportunityCategoriesSeqWithContext(ctx context.Context, config *GetOpportunityCategoriesConfig) iter.Seq2[OpportunityCategory, *errortools.Error] {
+	return seq(ctx, service, "OpportunityCategoriesSeq", opportunityCategoryResource, config)
 }
@@ -1,13 +1,12 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"iter"
+	"math"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -31,86 +30,289 @@ type OpportunityProduct struct {
 	CustomFields      *CustomFields           `json:"CUSTOMFIELDS"`
 }
 
-type GetOpportunityProductsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
+func (o *OpportunityProduct) prepareMarshal() interface{} {
+	if o == nil {
+		return nil
+	}
+
+	return &struct {
+		OpportunityItemID *int64                  `json:"OPPORTUNITY_ITEM_ID,omitempty"`
+		OpportunityID     *int64                  `json:"OPPORTUNITY_ID,omitempty"`
+		PricebookEntryID  *int64                  `json:"PRICEBOOK_ENTRY_ID,omitempty"`
+		CurrencyCode      *string                 `json:"CURRENCY_CODE,omitempty"`
+		UnitPrice         *float64                `json:"UNIT_PRICE,omitempty"`
+		Description       *string                 `json:"DESCRIPTION,omitempty"`
+		Quantity          *int64                  `json:"QUANTITY,omitempty"`
+		ServiceDate       *i_types.DateTimeString `json:"SERVICE_DATE,omitempty"`
+		Discount          *float64                `json:"DISCOUNT,omitempty"`
+		CustomFields      *CustomFields           `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(o.OpportunityItemID),
+		nonZero(o.OpportunityID),
+		nonZero(o.PricebookEntryID),
+		nonZero(o.CurrencyCode),
+		nonZero(o.UnitPrice),
+		nonZero(o.Description),
+		nonZero(o.Quantity),
+		o.ServiceDate,
+		nonZero(o.Discount),
+		o.CustomFields,
+	}
+}
+
+var opportunityProductResource = &Resource[OpportunityProduct]{
+	Endpoint: "OpportunityLineItem",
+	ID:       func(o *OpportunityProduct) int64 { return o.OpportunityItemID },
+	Marshal:  func(o *OpportunityProduct) interface{} { return o.prepareMarshal() },
+}
+
+// CalculateTotals sets Subtotal and TotalPrice from Quantity, UnitPrice and Discount the
+// way Insightly does: Subtotal is UnitPrice times Quantity, TotalPrice is Subtotal less
+// Discount percent, both rounded to cents
+//
+func (o *OpportunityProduct) CalculateTotals() {
+	if o == nil {
+		return
+	}
+
+	subtotal := o.UnitPrice * float64(o.Quantity)
+
+	o.Subtotal = roundToCents(subtotal)
+	o.TotalPrice = roundToCents(subtotal * (1 - o.Discount/100))
 }
 
+func roundToCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+type GetOpportunityProductsConfig = ListConfig
+
 // GetOpportunityProducts returns all opportunityProducts
 //
 func (service *Service) GetOpportunityProducts(config *GetOpportunityProductsConfig) (*[]OpportunityProduct, *errortools.Error) {
-	params := url.Values{}
+	return service.GetOpportunityProductsWithContext(context.Background(), config)
+}
 
-	endpoint := "OpportunityLineItem"
-	opportunityProducts := []OpportunityProduct{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
+// GetOpportunityProductsWithContext is the context-aware variant of GetOpportunityProducts
+//
+func (service *Service) GetOpportunityProductsWithContext(ctx context.Context, config *GetOpportunityProductsConfig) (*[]OpportunityProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityProducts")
+	defer span.End()
 
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
+	return opportunityProductResource.List(ctx, service, config)
+}
+
+// GetOpportunityProductsPage returns a single page of opportunityProducts and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetOpportunityProductsPage(config *GetOpportunityProductsConfig) (*[]OpportunityProduct, *Cursor, *errortools.Error) {
+	return service.GetOpportunityProductsPageWithContext(context.Background(), config)
+}
+
+// GetOpportunityProductsPageWithContext is the context-aware variant of GetOpportunityProductsPage
+//
+func (service *Service) GetOpportunityProductsPageWithContext(ctx context.Context, config *GetOpportunityProductsConfig) (*[]OpportunityProduct, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityProductsPage")
+	defer span.End()
+
+	return opportunityProductResource.Page(ctx, service, config)
+}
+
+// OpportunityProductsSeq returns an iterator over all opportunityProducts, fetching pages on demand
+//
+func (service *Service) OpportunityProductsSeq(config *GetOpportunityProductsConfig) iter.Seq2[OpportunityProduct, *errortools.Error] {
+	return service.OpportunityProductsSeqWithContext(context.Background(), config)
+}
+
+// OpportunityProductsSeqWithContext is the context-aware variant of OpportunityProductsSeq
+//
+func (service *Service) OpportunityProductsSeqWithContext(ctx context.Context, config *GetOpportunityProductsConfig) iter.Seq2[OpportunityProduct, *errortools.Error] {
+	return seq(ctx, service, "OpportunityProductsSeq", opportunityProductResource, config)
+}
+
+// CreateOpportunityProduct adds a product to an opportunity
+//
+func (service *Service) CreateOpportunityProduct(opportunityProduct *OpportunityProduct) (*OpportunityProduct, *errortools.Error) {
+	return service.CreateOpportunityProductWithContext(context.Background(), opportunityProduct)
+}
+
+// CreateOpportunityProductWithContext is the context-aware variant of CreateOpportunityProduct
+//
+func (service *Service) CreateOpportunityProductWithContext(ctx context.Context, opportunityProduct *OpportunityProduct) (*OpportunityProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateOpportunityProduct")
+	defer span.End()
+
+	if opportunityProduct != nil && opportunityProduct.OpportunityID == 0 {
+		return nil, errortools.ErrorMessage("Cannot create opportunity product without opportunity id")
+	}
+
+	return opportunityProductResource.Create(ctx, service, opportunityProduct)
+}
+
+// UpdateOpportunityProduct updates an existing product of an opportunity
+//
+func (service *Service) UpdateOpportunityProduct(opportunityProduct *OpportunityProduct) (*OpportunityProduct, *errortools.Error) {
+	return service.UpdateOpportunityProductWithContext(context.Background(), opportunityProduct)
+}
+
+// UpdateOpportunityProductWithContext is the context-aware variant of UpdateOpportunityProduct
+//
+func (service *Service) UpdateOpportunityProductWithContext(ctx context.Context, opportunityProduct *OpportunityProduct) (*OpportunityProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateOpportunityProduct")
+	defer span.End()
+
+	return opportunityProductResource.Update(ctx, service, opportunityProduct)
+}
+
+// DeleteOpportunityProduct removes a product from an opportunity
+//
+func (service *Service) DeleteOpportunityProduct(opportunityItemID int64) *errortools.Error {
+	return service.DeleteOpportunityProductWithContext(context.Background(), opportunityItemID)
+}
+
+// DeleteOpportunityProductWithContext is the context-aware variant of DeleteOpportunityProduct
+//
+func (service *Service) DeleteOpportunityProductWithContext(ctx context.Context, opportunityItemID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteOpportunityProduct")
+	defer span.End()
+
+	return opportunityProductResource.Delete(ctx, service, opportunityItemID)
+}
+
+type BuildOpportunityProductConfig struct {
+	OpportunityID int64
+	ProductID     int64
+	Quantity      int64
+	// Discount is a percentage of the subtotal
+	Discount float64
+	// UnitPrice is the sales price, it defaults to the list price
+	UnitPrice *float64
+	// PricebookID is the pricebook to take the price from, the standard pricebook if nil
+	PricebookID *int64
+}
+
+// BuildOpportunityProduct returns an OpportunityProduct for a product, priced from the
+// active entry of the pricebook in the opportunity's BidCurrency. If that entry uses the
+// standard price, the list price is taken from the standard pricebook. The result is not
+// saved, pass it to CreateOpportunityProduct to add it to the opportunity. It fails if
+// ServiceConfig.MaxRowCount truncates the read of the pricebooks or of the entries of the product.
+//
+func (service *Service) BuildOpportunityProduct(config *BuildOpportunityProductConfig) (*OpportunityProduct, *errortools.Error) {
+	return service.BuildOpportunityProductWithContext(context.Background(), config)
+}
+
+// BuildOpportunityProductWithContext is the context-aware variant of BuildOpportunityProduct
+//
+func (service *Service) BuildOpportunityProductWithContext(ctx context.Context, config *BuildOpportunityProductConfig) (*OpportunityProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "BuildOpportunityProduct")
+	defer span.End()
+
+	if config == nil {
+		return nil, errortools.ErrorMessage("BuildOpportunityProductConfig must not be nil")
 	}
 
-	if isSearch {
-		endpoint += "/Search"
+	opportunity, e := opportunityResource.Get(ctx, service, config.OpportunityID)
+	if e != nil {
+		return nil, e
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	if opportunity.BidCurrency == nil || *opportunity.BidCurrency == "" {
+		return nil, errortools.ErrorMessagef("Opportunity %v has no bid currency", config.OpportunityID)
+	}
+	currencyCode := *opportunity.BidCurrency
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+	product, e := productResource.Get(ctx, service, config.ProductID)
+	if e != nil {
+		return nil, e
+	}
 
-		opportunityProductsBatch := []OpportunityProduct{}
+	if !product.Active {
+		return nil, errortools.ErrorMessagef("Product %v is not active", config.ProductID)
+	}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &opportunityProductsBatch,
+	pricebooks, e := pricebookResource.listAll(ctx, service, nil)
+	if e != nil {
+		return nil, e
+	}
+
+	var standardPricebook, pricebook *Pricebook
+	for i := range *pricebooks {
+		p := &(*pricebooks)[i]
+		if p.IsStandard {
+			standardPricebook = p
+		}
+		if config.PricebookID != nil && p.PricebookID == *config.PricebookID {
+			pricebook = p
 		}
+	}
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
+	if config.PricebookID == nil {
+		pricebook = standardPricebook
+	}
+	if pricebook == nil {
+		if config.PricebookID == nil {
+			return nil, errortools.ErrorMessage("No standard pricebook found")
 		}
+		return nil, errortools.ErrorMessagef("Pricebook %v not found", *config.PricebookID)
+	}
+	if !pricebook.Active {
+		return nil, errortools.ErrorMessagef("Pricebook %v is not active", pricebook.PricebookID)
+	}
 
-		opportunityProducts = append(opportunityProducts, opportunityProductsBatch...)
+	pricebookEntries, e := pricebookEntryResource.listAll(ctx, service, &ListConfig{
+		FieldFilter: &FieldFilter{
+			FieldName:  "PRODUCT_ID",
+			FieldValue: fmt.Sprintf("%v", config.ProductID),
+		},
+	})
+	if e != nil {
+		return nil, e
+	}
 
-		if len(opportunityProductsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
+	findEntry := func(pricebookID int64) *PricebookEntry {
+		for i := range *pricebookEntries {
+			entry := &(*pricebookEntries)[i]
+			if entry.PricebookID == pricebookID && entry.CurrencyCode == currencyCode {
+				return entry
+			}
 		}
+		return nil
+	}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	entry := findEntry(pricebook.PricebookID)
+	if entry == nil || !entry.Active {
+		return nil, errortools.ErrorMessagef("Product %v has no active entry in pricebook %v for currency %s", config.ProductID, pricebook.PricebookID, currencyCode)
+	}
 
-		if rowCount >= service.maxRowCount {
-			return &opportunityProducts, nil
+	listPrice := entry.Price
+	if entry.UseStandardPrice && !pricebook.IsStandard {
+		if standardPricebook == nil {
+			return nil, errortools.ErrorMessage("No standard pricebook found")
+		}
+		standardEntry := findEntry(standardPricebook.PricebookID)
+		if standardEntry == nil || !standardEntry.Active {
+			return nil, errortools.ErrorMessagef("Product %v has no active standard price for currency %s", config.ProductID, currencyCode)
 		}
+		listPrice = standardEntry.Price
+	}
+
+	unitPrice := listPrice
+	if config.UnitPrice != nil {
+		unitPrice = *config.UnitPrice
+	}
+
+	opportunityProduct := OpportunityProduct{
+		OpportunityID:    config.OpportunityID,
+		PricebookEntryID: entry.PricebookEntryID,
+		CurrencyCode:     currencyCode,
+		Quantity:         config.Quantity,
+		ListPrice:        listPrice,
+		UnitPrice:        unitPrice,
+		Discount:         config.Discount,
+	}
+	if product.Description != nil {
+		opportunityProduct.Description = *product.Description
 	}
+	opportunityProduct.CalculateTotals()
 
-	return &opportunityProducts, nil
+	return &opportunityProduct, nil
 }
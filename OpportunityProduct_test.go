@@ -0,0 +1,123 @@
+package insightly_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// seedPricing seeds an opportunity in EUR and a product priced in a standard pricebook
+// and in a second pricebook that uses the standard price
+//
+func seedPricing(server *insightlytest.Server, standardActive bool) (opportunityID int64, productID int64, pricebookID int64) {
+	currency := "EUR"
+	opportunityID = server.Seed("Opportunities", insightly.Opportunity{BidCurrency: &currency})[0]
+	productID = server.Seed("Products", &insightly.Product{ProductName: "Widget", Active: true})[0]
+	pricebookIDs := server.Seed("Pricebook",
+		&insightly.Pricebook{Name: "Standard", IsStandard: true, Active: true},
+		&insightly.Pricebook{Name: "Partners", Active: true},
+	)
+	server.Seed("PricebookEntry",
+		&insightly.PricebookEntry{PricebookID: pricebookIDs[0], ProductID: productID, CurrencyCode: currency, Price: 100, Active: standardActive},
+		&insightly.PricebookEntry{PricebookID: pricebookIDs[1], ProductID: productID, CurrencyCode: currency, Price: 80, UseStandardPrice: true, Active: true},
+	)
+
+	return opportunityID, productID, pricebookIDs[1]
+}
+
+func TestBuildOpportunityProductUsesStandardPrice(t *testing.T) {
+	service, server := newTestService(t, nil)
+	opportunityID, productID, pricebookID := seedPricing(server, true)
+
+	opportunityProduct, e := service.BuildOpportunityProduct(&insightly.BuildOpportunityProductConfig{
+		OpportunityID: opportunityID,
+		ProductID:     productID,
+		Quantity:      2,
+		PricebookID:   &pricebookID,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if opportunityProduct.ListPrice != 100 || opportunityProduct.UnitPrice != 100 {
+		t.Errorf("got list price %v and unit price %v, want the standard price 100", opportunityProduct.ListPrice, opportunityProduct.UnitPrice)
+	}
+}
+
+func TestBuildOpportunityProductInactiveStandardPrice(t *testing.T) {
+	service, server := newTestService(t, nil)
+	opportunityID, productID, pricebookID := seedPricing(server, false)
+
+	_, e := service.BuildOpportunityProduct(&insightly.BuildOpportunityProductConfig{
+		OpportunityID: opportunityID,
+		ProductID:     productID,
+		Quantity:      2,
+		PricebookID:   &pricebookID,
+	})
+	if e == nil {
+		t.Fatal("expected an error for an inactive standard price")
+	}
+}
+
+func TestBuildOpportunityProductTruncated(t *testing.T) {
+	service, server := truncatingTestService(t)
+	opportunityID, productID, pricebookID := seedPricing(server, true)
+
+	entries := []any{}
+	for range pageSize {
+		entries = append(entries, map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": productID, "CURRENCY_CODE": "USD", "PRICE": 90, "ACTIVE": true})
+	}
+	server.Seed("PricebookEntry", entries...)
+
+	_, e := service.BuildOpportunityProduct(&insightly.BuildOpportunityProductConfig{
+		OpportunityID: opportunityID,
+		ProductID:     productID,
+		Quantity:      2,
+		PricebookID:   &pricebookID,
+	})
+	if e == nil {
+		t.Error("expected an error for truncated pricebook entries")
+	}
+
+	pricebooks := []any{}
+	for range pageSize {
+		pricebooks = append(pricebooks, map[string]any{"NAME": "Archive", "ACTIVE": false})
+	}
+	server.Seed("Pricebook", pricebooks...)
+
+	_, e = service.BuildOpportunityProduct(&insightly.BuildOpportunityProductConfig{
+		OpportunityID: opportunityID,
+		ProductID:     productID,
+		Quantity:      2,
+	})
+	if e == nil {
+		t.Error("expected an error for truncated pricebooks")
+	}
+}
+
+func TestCreateOpportunityProductOmitsZeroAmounts(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	_, e := service.CreateOpportunityProduct(&insightly.OpportunityProduct{OpportunityID: 1, PricebookEntryID: 2, Quantity: 3})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	requests := server.Requests()
+	body := map[string]any{}
+	err := json.Unmarshal(requests[len(requests)-1].Body, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body["QUANTITY"] != float64(3) {
+		t.Errorf("got QUANTITY %v, want 3", body["QUANTITY"])
+	}
+	for _, field := range []string{"UNIT_PRICE", "DISCOUNT"} {
+		if value, ok := body[field]; ok {
+			t.Errorf("got %s %v, want it omitted", field, value)
+		}
+	}
+}
@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // OpportunityStateReason stores OpportunityStateReason from Service
@@ -17,66 +15,51 @@ type OpportunityStateReason struct {
 	ForOpportunityState string `json:"FOR_OPPORTUNITY_STATE"`
 }
 
-type GetOpportunityStateReasonsConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var opportunityStateReasonResource = &Resource[OpportunityStateReason]{
+	Endpoint: "OpportunityStateReasons",
+	ID:       func(o *OpportunityStateReason) int64 { return o.StateReasonID },
 }
 
+type GetOpportunityStateReasonsConfig = PagingConfig
+
 // GetOpportunityStateReasons returns all opportunityStateReasons
 //
 func (service *Service) GetOpportunityStateReasons(config *GetOpportunityStateReasonsConfig) (*[]OpportunityStateReason, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "OpportunityStateReasons"
-	opportunityStateReasons := []OpportunityStateReason{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		opportunityStateReasonsBatch := []OpportunityStateReason{}
+	return service.GetOpportunityStateReasonsWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &opportunityStateReasonsBatch,
-		}
+// GetOpportunityStateReasonsWithContext is the context-aware variant of GetOpportunityStateReasons
+//
+func (service *Service) GetOpportunityStateReasonsWithContext(ctx context.Context, config *GetOpportunityStateReasonsConfig) (*[]OpportunityStateReason, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityStateReasons")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return opportunityStateReasonResource.List(ctx, service, config)
+}
 
-		opportunityStateReasons = append(opportunityStateReasons, opportunityStateReasonsBatch...)
+// GetOpportunityStateReasonsPage returns a single page of opportunityStateReasons and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetOpportunityStateReasonsPage(config *GetOpportunityStateReasonsConfig) (*[]OpportunityStateReason, *Cursor, *errortools.Error) {
+	return service.GetOpportunityStateReasonsPageWithContext(context.Background(), config)
+}
 
-		if len(opportunityStateReasonsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetOpportunityStateReasonsPageWithContext is the context-aware variant of GetOpportunityStateReasonsPage
+//
+func (service *Service) GetOpportunityStateReasonsPageWithContext(ctx context.Context, config *GetOpportunityStateReasonsConfig) (*[]OpportunityStateReason, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOpportunityStateReasonsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return opportunityStateReasonResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &opportunityStateReasons, nil
-		}
-	}
+// OpportunityStateReasonsSeq returns an iterator over all opportunityStateReasons, fetching pages on demand
+//
+func (service *Service) OpportunityStateReasonsSeq(config *GetOpportunityStateReasonsConfig) iter.Seq2[OpportunityStateReason, *errortools.Error] {
+	return service.OpportunityStateReasonsSeqWithContext(context.Background(), config)
+}
 
-	return &opportunityStateReasons, nil
+// OpportunityStateReasonsSeqWithContext is the context-aware variant of OpportunityStateReasonsSeq
+//
+func (service *Service) OpportunityStateReasonsSeqWithContext(ctx context.Context, config *GetOpportunityStateReasonsConfig) iter.Seq2[OpportunityStateReason, *errortools.Error] {
+	return seq(ctx, service, "OpportunityStateReasonsSeq", opportunityStateReasonResource, config)
 }
@@ -1,10 +1,10 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"net/http"
-	"net/url"
-	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -46,181 +46,161 @@ type Organisation struct {
 	Links                  *[]Link                 `json:"LINKS,omitempty"`
 }
 
+var organisationResource = &Resource[Organisation]{
+	Endpoint: "Organisations",
+	ID:       func(o *Organisation) int64 { return o.OrganisationID },
+}
+
 // GetOrganisation returns a specific organisation
 func (service *Service) GetOrganisation(organisationID int64) (*Organisation, *errortools.Error) {
-	organisation := Organisation{}
+	return service.GetOrganisationWithContext(context.Background(), organisationID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Organisations/%v", organisationID)),
-		ResponseModel: &organisation,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetOrganisationWithContext is the context-aware variant of GetOrganisation
+func (service *Service) GetOrganisationWithContext(ctx context.Context, organisationID int64) (*Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOrganisation")
+	defer span.End()
 
-	return &organisation, nil
+	return organisationResource.Get(ctx, service, organisationID)
 }
 
-type GetOrganisationsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetOrganisationsConfig = ListConfig
 
 // GetOrganisations returns all organisations
 func (service *Service) GetOrganisations(config *GetOrganisationsConfig) (*[]Organisation, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Organisations"
-	organisations := []Organisation{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetOrganisationsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetOrganisationsWithContext is the context-aware variant of GetOrganisations
+func (service *Service) GetOrganisationsWithContext(ctx context.Context, config *GetOrganisationsConfig) (*[]Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOrganisations")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return organisationResource.List(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		organisationsBatch := []Organisation{}
+// GetOrganisationsPage returns a single page of organisations and the Cursor of the next page, which is nil after the last page
+func (service *Service) GetOrganisationsPage(config *GetOrganisationsConfig) (*[]Organisation, *Cursor, *errortools.Error) {
+	return service.GetOrganisationsPageWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &organisationsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// GetOrganisationsPageWithContext is the context-aware variant of GetOrganisationsPage
+func (service *Service) GetOrganisationsPageWithContext(ctx context.Context, config *GetOrganisationsConfig) (*[]Organisation, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOrganisationsPage")
+	defer span.End()
 
-		organisations = append(organisations, organisationsBatch...)
+	return organisationResource.Page(ctx, service, config)
+}
 
-		if len(organisationsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// OrganisationsSeq returns an iterator over all organisations, fetching pages on demand
+func (service *Service) OrganisationsSeq(config *GetOrganisationsConfig) iter.Seq2[Organisation, *errortools.Error] {
+	return service.OrganisationsSeqWithContext(context.Background(), config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// OrganisationsSeqWithContext is the context-aware variant of OrganisationsSeq
+func (service *Service) OrganisationsSeqWithContext(ctx context.Context, config *GetOrganisationsConfig) iter.Seq2[Organisation, *errortools.Error] {
+	return seq(ctx, service, "OrganisationsSeq", organisationResource, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &organisations, nil
-		}
-	}
+// SearchOrganisationsByTag returns the organisations that have a specific tag
+func (service *Service) SearchOrganisationsByTag(tagName string, config *SearchByTagConfig) (*[]Organisation, *errortools.Error) {
+	return service.SearchOrganisationsByTagWithContext(context.Background(), tagName, config)
+}
 
-	return &organisations, nil
+// SearchOrganisationsByTagWithContext is the context-aware variant of SearchOrganisationsByTag
+func (service *Service) SearchOrganisationsByTagWithContext(ctx context.Context, tagName string, config *SearchByTagConfig) (*[]Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SearchOrganisationsByTag")
+	defer span.End()
+
+	return organisationResource.ListByTag(ctx, service, tagName, config)
+}
+
+// QueryOrganisations returns the organisations that match a Query
+func (service *Service) QueryOrganisations(query *Query) (*[]Organisation, *errortools.Error) {
+	return service.QueryOrganisationsWithContext(context.Background(), query)
+}
+
+// QueryOrganisationsWithContext is the context-aware variant of QueryOrganisations
+func (service *Service) QueryOrganisationsWithContext(ctx context.Context, query *Query) (*[]Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "QueryOrganisations")
+	defer span.End()
+
+	return organisationResource.Query(ctx, service, query)
+}
+
+// QueryOrganisationsSeq returns an iterator over the organisations that match a Query, fetching pages on demand
+func (service *Service) QueryOrganisationsSeq(query *Query) iter.Seq2[Organisation, *errortools.Error] {
+	return service.QueryOrganisationsSeqWithContext(context.Background(), query)
+}
+
+// QueryOrganisationsSeqWithContext is the context-aware variant of QueryOrganisationsSeq
+func (service *Service) QueryOrganisationsSeqWithContext(ctx context.Context, query *Query) iter.Seq2[Organisation, *errortools.Error] {
+	return querySeq[Organisation](ctx, service, "QueryOrganisationsSeq", organisationResource.Endpoint, query)
 }
 
 // CreateOrganisation creates a new contract
 func (service *Service) CreateOrganisation(organisation *Organisation) (*Organisation, *errortools.Error) {
-	if organisation == nil {
-		return nil, nil
-	}
-
-	organisationNew := Organisation{}
+	return service.CreateOrganisationWithContext(context.Background(), organisation)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Organisations"),
-		BodyModel:     organisation,
-		ResponseModel: &organisationNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateOrganisationWithContext is the context-aware variant of CreateOrganisation
+func (service *Service) CreateOrganisationWithContext(ctx context.Context, organisation *Organisation) (*Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateOrganisation")
+	defer span.End()
 
-	return &organisationNew, nil
+	return organisationResource.Create(ctx, service, organisation)
 }
 
-// UpdateOrganisation updates an existing contract
+// UpdateOrganisation updates an existing contract, an organisation without OrganisationID
+// is rejected without sending a request
 func (service *Service) UpdateOrganisation(organisation *Organisation) (*Organisation, *errortools.Error) {
-	if organisation == nil {
-		return nil, nil
-	}
-
-	organisationUpdated := Organisation{}
+	return service.UpdateOrganisationWithContext(context.Background(), organisation)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Organisations"),
-		BodyModel:     organisation,
-		ResponseModel: &organisationUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateOrganisationWithContext is the context-aware variant of UpdateOrganisation
+func (service *Service) UpdateOrganisationWithContext(ctx context.Context, organisation *Organisation) (*Organisation, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateOrganisation")
+	defer span.End()
 
-	return &organisationUpdated, nil
+	return organisationResource.Update(ctx, service, organisation)
 }
 
 // DeleteOrganisation deletes a specific organisation
 func (service *Service) DeleteOrganisation(organisationID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Organisations/%v", organisationID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteOrganisationWithContext(context.Background(), organisationID)
+}
 
-	return nil
+// DeleteOrganisationWithContext is the context-aware variant of DeleteOrganisation
+func (service *Service) DeleteOrganisationWithContext(ctx context.Context, organisationID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteOrganisation")
+	defer span.End()
+
+	return organisationResource.Delete(ctx, service, organisationID)
 }
 
 // GetOrganisationLinks returns links for a specific organisation
 func (service *Service) GetOrganisationLinks(organisationID int64) (*[]Link, *errortools.Error) {
-	links := []Link{}
+	return service.GetOrganisationLinksWithContext(context.Background(), organisationID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Organisations/%v/Links", organisationID)),
-		ResponseModel: &links,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetOrganisationLinksWithContext is the context-aware variant of GetOrganisationLinks
+func (service *Service) GetOrganisationLinksWithContext(ctx context.Context, organisationID int64) (*[]Link, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOrganisationLinks")
+	defer span.End()
 
-	return &links, nil
+	return getLinks(ctx, service, ObjectNameOrganisation, organisationID)
 }
 
 // GetOrganisationFileAttachments returns the file attachments of a specific email
 func (service *Service) GetOrganisationFileAttachments(id int64) (*[]FileAttachment, *errortools.Error) {
+	return service.GetOrganisationFileAttachmentsWithContext(context.Background(), id)
+}
+
+// GetOrganisationFileAttachmentsWithContext is the context-aware variant of GetOrganisationFileAttachments
+func (service *Service) GetOrganisationFileAttachmentsWithContext(ctx context.Context, id int64) (*[]FileAttachment, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetOrganisationFileAttachments")
+	defer span.End()
+
 	var fileAttachments []FileAttachment
 
 	requestConfig := go_http.RequestConfig{
@@ -229,7 +209,7 @@ func (service *Service) GetOrganisationFileAttachments(id int64) (*[]FileAttachm
 		ResponseModel: &fileAttachments,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
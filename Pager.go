@@ -0,0 +1,264 @@
+package insightly
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	go_http "github.com/leapforce-libraries/go_http"
+)
+
+// pageConfig describes a paged list request
+//
+type pageConfig struct {
+	endpoint string
+	params   url.Values
+	skip     uint64
+	top      uint64
+	isSearch bool
+	cursor   *Cursor
+	// nextCursor receives the cursor of the first row not returned by a crawl that was
+	// truncated by the service's maxRowCount
+	nextCursor **Cursor
+}
+
+func newPageConfig(endpoint string, skip *uint64, top *uint64, countTotal *bool) *pageConfig {
+	p := pageConfig{
+		endpoint: endpoint,
+		params:   url.Values{},
+		top:      defaultTop,
+	}
+
+	if skip != nil {
+		p.skip = *skip
+	}
+	if top != nil {
+		p.top = *top
+	}
+	if countTotal != nil {
+		p.params.Set("count_total", fmt.Sprintf("%v", *countTotal))
+	}
+
+	return &p
+}
+
+// newCursorPageConfig is newPageConfig starting at cursor if set, the cursor of a
+// truncated crawl is reported to nextCursor
+//
+func newCursorPageConfig(endpoint string, skip *uint64, top *uint64, countTotal *bool, cursor *Cursor, nextCursor **Cursor) *pageConfig {
+	p := newPageConfig(endpoint, skip, top, countTotal)
+	p.setCursor(cursor)
+	p.setNextCursor(nextCursor)
+
+	return p
+}
+
+func (p *pageConfig) setBrief(brief *bool) {
+	if brief != nil {
+		p.params.Set("brief", fmt.Sprintf("%v", *brief))
+	}
+}
+
+func (p *pageConfig) setSearch(updatedAfter *time.Time, fieldFilter *FieldFilter) {
+	if updatedAfter != nil {
+		p.isSearch = true
+		p.params.Set("updated_after_utc", fmt.Sprintf("%v", updatedAfter.Format(dateTimeFormat)))
+	}
+	if fieldFilter != nil {
+		p.isSearch = true
+		p.params.Set("field_name", fieldFilter.FieldName)
+		p.params.Set("field_value", fieldFilter.FieldValue)
+	}
+}
+
+func (p *pageConfig) setCursor(cursor *Cursor) {
+	p.cursor = cursor
+}
+
+func (p *pageConfig) setNextCursor(nextCursor **Cursor) {
+	p.nextCursor = nextCursor
+}
+
+// reportNextCursor stores the cursor a truncated crawl can be resumed with, nil if the
+// crawl was complete
+//
+func (p *pageConfig) reportNextCursor(cursor *Cursor) {
+	if p.nextCursor != nil {
+		*p.nextCursor = cursor
+	}
+}
+
+func (p *pageConfig) fullEndpoint() string {
+	if p.isSearch {
+		return p.endpoint + "/Search"
+	}
+
+	return p.endpoint
+}
+
+// query identifies the rows selected by the request, options that only affect
+// the shape of the response are left out
+//
+func (p *pageConfig) query() string {
+	params := url.Values{}
+	for key, values := range p.params {
+		if key == "brief" || key == "count_total" {
+			continue
+		}
+		params[key] = values
+	}
+
+	return params.Encode()
+}
+
+// start returns the skip of the first page, taken from the cursor if one is set
+//
+func (p *pageConfig) start() (uint64, *errortools.Error) {
+	if p.cursor == nil {
+		return p.skip, nil
+	}
+
+	if p.cursor.Endpoint != p.fullEndpoint() || p.cursor.Query != p.query() {
+		return 0, errortools.ErrorMessagef("Cursor does not belong to this %s request", p.endpoint)
+	}
+
+	return p.cursor.Skip, nil
+}
+
+// next returns the cursor pointing at the page starting at skip
+//
+func (p *pageConfig) next(skip uint64) *Cursor {
+	return &Cursor{
+		Endpoint: p.fullEndpoint(),
+		Query:    p.query(),
+		Skip:     skip,
+	}
+}
+
+// url returns the url of the page starting at skip
+//
+func (p *pageConfig) url(service *Service, skip uint64) string {
+	params := url.Values{}
+	for key, values := range p.params {
+		params[key] = values
+	}
+	params.Set("top", fmt.Sprintf("%v", p.top))
+	params.Set("skip", fmt.Sprintf("%v", skip))
+
+	return service.url(fmt.Sprintf("%s?%s", p.fullEndpoint(), params.Encode()))
+}
+
+// list returns all rows of a paged list request, limited by the service's maxRowCount,
+// see iterate
+//
+func list[T any](ctx context.Context, service *Service, config *pageConfig) (*[]T, *errortools.Error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	rows := []T{}
+
+	for row, e := range iterate[T](ctx, service, config) {
+		if e != nil {
+			return nil, e
+		}
+
+		rows = append(rows, row)
+	}
+
+	return &rows, nil
+}
+
+// page returns a single page of a paged list request and the cursor of the next page,
+// the cursor is nil if there are no more pages
+//
+func page[T any](ctx context.Context, service *Service, config *pageConfig) (*[]T, *Cursor, *errortools.Error) {
+	if config == nil {
+		return nil, nil, nil
+	}
+
+	skip, e := config.start()
+	if e != nil {
+		return nil, nil, e
+	}
+
+	rows := []T{}
+
+	requestConfig := go_http.RequestConfig{
+		Method:        http.MethodGet,
+		Url:           config.url(service, skip),
+		ResponseModel: &rows,
+	}
+	_, _, e = service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	if len(rows) < int(config.top) {
+		return &rows, nil, nil
+	}
+
+	return &rows, config.next(skip + config.top), nil
+}
+
+// iterate returns an iterator over all rows of a paged list request, fetching the next
+// page only when the consumer asks for more rows; iteration ends after the first error.
+// Iteration also ends once maxRowCount rows have been read, the cursor of the next page
+// is then reported to the config's nextCursor.
+//
+func iterate[T any](ctx context.Context, service *Service, config *pageConfig) iter.Seq2[T, *errortools.Error] {
+	return func(yield func(T, *errortools.Error) bool) {
+		if config == nil {
+			return
+		}
+
+		config.reportNextCursor(nil)
+
+		skip, e := config.start()
+		if e != nil {
+			var zero T
+			yield(zero, e)
+			return
+		}
+
+		rowCount := uint64(0)
+
+		for {
+			batch := []T{}
+
+			requestConfig := go_http.RequestConfig{
+				Method:        http.MethodGet,
+				Url:           config.url(service, skip),
+				ResponseModel: &batch,
+			}
+			_, _, e := service.httpRequest(ctx, &requestConfig)
+			if e != nil {
+				var zero T
+				yield(zero, e)
+				return
+			}
+
+			for _, row := range batch {
+				if !yield(row, nil) {
+					return
+				}
+			}
+
+			if len(batch) < int(config.top) {
+				return
+			}
+
+			skip += config.top
+			rowCount += config.top
+
+			if rowCount >= service.maxRowCount {
+				config.reportNextCursor(config.next(skip))
+				return
+			}
+		}
+	}
+}
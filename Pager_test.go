@@ -0,0 +1,79 @@
+package insightly_test
+
+import (
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestListTruncatedByMaxRowCountSetsNextCursor(t *testing.T) {
+	maxRowCount := uint64(10)
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.MaxRowCount = &maxRowCount
+	})
+
+	for i := 0; i < 25; i++ {
+		server.Seed("Contacts", insightly.Contact{})
+	}
+
+	top := uint64(4)
+	config := insightly.GetContactsConfig{Top: &top}
+
+	counts := []int{}
+	for {
+		contacts, e := service.GetContacts(&config)
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+		counts = append(counts, len(*contacts))
+
+		if config.NextCursor == nil {
+			break
+		}
+		if len(counts) > 3 {
+			t.Fatalf("crawl does not end, read %v", counts)
+		}
+		config.Cursor = config.NextCursor
+	}
+
+	// whole pages are read until maxRowCount is reached
+	if len(counts) != 3 || counts[0] != 12 || counts[1] != 12 || counts[2] != 1 {
+		t.Errorf("got pages of %v rows, want [12 12 1]", counts)
+	}
+}
+
+func TestSeqTruncatedByMaxRowCountSetsNextCursor(t *testing.T) {
+	maxRowCount := uint64(5)
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.MaxRowCount = &maxRowCount
+	})
+
+	for i := 0; i < 8; i++ {
+		server.Seed("Leads", insightly.Lead{})
+	}
+
+	top := uint64(5)
+	config := insightly.GetLeadsConfig{Top: &top}
+
+	count := 0
+	for _, e := range service.LeadsSeq(&config) {
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+		count++
+	}
+	if count != 5 || config.NextCursor == nil || config.NextCursor.Skip != 5 {
+		t.Fatalf("got %v leads and cursor %+v, want 5 leads and a cursor at 5", count, config.NextCursor)
+	}
+
+	config.Cursor = config.NextCursor
+	for _, e := range service.LeadsSeq(&config) {
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+		count++
+	}
+	if count != 8 || config.NextCursor != nil {
+		t.Errorf("got %v leads and cursor %+v after resuming, want 8 leads and no cursor", count, config.NextCursor)
+	}
+}
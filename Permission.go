@@ -1,6 +1,7 @@
 package insightly
 
 import (
+	"context"
 	"net/http"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
@@ -27,6 +28,15 @@ type ObjectPermission struct {
 //
 
 func (service *Service) GetPermissions() (*[]Permission, *errortools.Error) {
+	return service.GetPermissionsWithContext(context.Background())
+}
+
+// GetPermissionsWithContext is the context-aware variant of GetPermissions
+//
+func (service *Service) GetPermissionsWithContext(ctx context.Context) (*[]Permission, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPermissions")
+	defer span.End()
+
 	permissions := []Permission{}
 	requestConfig := go_http.RequestConfig{
 		Method:        http.MethodGet,
@@ -34,7 +44,7 @@ func (service *Service) GetPermissions() (*[]Permission, *errortools.Error) {
 		ResponseModel: &permissions,
 	}
 
-	_, _, e := service.httpRequest(&requestConfig)
+	_, _, e := service.httpRequest(ctx, &requestConfig)
 	if e != nil {
 		return nil, e
 	}
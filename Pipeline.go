@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // Pipeline stores Pipeline from Service
@@ -19,66 +17,51 @@ type Pipeline struct {
 	OwnerUserID      int64  `json:"OWNER_USER_ID"`
 }
 
-type GetPipelinesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var pipelineResource = &Resource[Pipeline]{
+	Endpoint: "Pipelines",
+	ID:       func(p *Pipeline) int64 { return p.PipelineID },
 }
 
+type GetPipelinesConfig = PagingConfig
+
 // GetPipelines returns all pipelines
 //
 func (service *Service) GetPipelines(config *GetPipelinesConfig) (*[]Pipeline, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Pipelines"
-	pipelines := []Pipeline{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		pipelinesBatch := []Pipeline{}
+	return service.GetPipelinesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &pipelinesBatch,
-		}
+// GetPipelinesWithContext is the context-aware variant of GetPipelines
+//
+func (service *Service) GetPipelinesWithContext(ctx context.Context, config *GetPipelinesConfig) (*[]Pipeline, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPipelines")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return pipelineResource.List(ctx, service, config)
+}
 
-		pipelines = append(pipelines, pipelinesBatch...)
+// GetPipelinesPage returns a single page of pipelines and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetPipelinesPage(config *GetPipelinesConfig) (*[]Pipeline, *Cursor, *errortools.Error) {
+	return service.GetPipelinesPageWithContext(context.Background(), config)
+}
 
-		if len(pipelinesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetPipelinesPageWithContext is the context-aware variant of GetPipelinesPage
+//
+func (service *Service) GetPipelinesPageWithContext(ctx context.Context, config *GetPipelinesConfig) (*[]Pipeline, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPipelinesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return pipelineResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &pipelines, nil
-		}
-	}
+// PipelinesSeq returns an iterator over all pipelines, fetching pages on demand
+//
+func (service *Service) PipelinesSeq(config *GetPipelinesConfig) iter.Seq2[Pipeline, *errortools.Error] {
+	return service.PipelinesSeqWithContext(context.Background(), config)
+}
 
-	return &pipelines, nil
+// PipelinesSeqWithContext is the context-aware variant of PipelinesSeq
+//
+func (service *Service) PipelinesSeqWithContext(ctx context.Context, config *GetPipelinesConfig) iter.Seq2[Pipeline, *errortools.Error] {
+	return seq(ctx, service, "PipelinesSeq", pipelineResource, config)
 }
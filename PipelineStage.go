@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // PipelineStage stores PipelineStage from Service
@@ -20,66 +18,51 @@ type PipelineStage struct {
 	OwnerUserID   int64  `json:"OWNER_USER_ID"`
 }
 
-type GetPipelineStagesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var pipelineStageResource = &Resource[PipelineStage]{
+	Endpoint: "PipelineStages",
+	ID:       func(p *PipelineStage) int64 { return p.StageID },
 }
 
+type GetPipelineStagesConfig = PagingConfig
+
 // GetPipelineStages returns all pipelineStages
 //
 func (service *Service) GetPipelineStages(config *GetPipelineStagesConfig) (*[]PipelineStage, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "PipelineStages"
-	pipelineStages := []PipelineStage{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		pipelineStagesBatch := []PipelineStage{}
+	return service.GetPipelineStagesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &pipelineStagesBatch,
-		}
+// GetPipelineStagesWithContext is the context-aware variant of GetPipelineStages
+//
+func (service *Service) GetPipelineStagesWithContext(ctx context.Context, config *GetPipelineStagesConfig) (*[]PipelineStage, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPipelineStages")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return pipelineStageResource.List(ctx, service, config)
+}
 
-		pipelineStages = append(pipelineStages, pipelineStagesBatch...)
+// GetPipelineStagesPage returns a single page of pipelineStages and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetPipelineStagesPage(config *GetPipelineStagesConfig) (*[]PipelineStage, *Cursor, *errortools.Error) {
+	return service.GetPipelineStagesPageWithContext(context.Background(), config)
+}
 
-		if len(pipelineStagesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetPipelineStagesPageWithContext is the context-aware variant of GetPipelineStagesPage
+//
+func (service *Service) GetPipelineStagesPageWithContext(ctx context.Context, config *GetPipelineStagesConfig) (*[]PipelineStage, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPipelineStagesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return pipelineStageResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &pipelineStages, nil
-		}
-	}
+// PipelineStagesSeq returns an iterator over all pipelineStages, fetching pages on demand
+//
+func (service *Service) PipelineStagesSeq(config *GetPipelineStagesConfig) iter.Seq2[PipelineStage, *errortools.Error] {
+	return service.PipelineStagesSeqWithContext(context.Background(), config)
+}
 
-	return &pipelineStages, nil
+// PipelineStagesSeqWithContext is the context-aware variant of PipelineStagesSeq
+//
+func (service *Service) PipelineStagesSeqWithContext(ctx context.Context, config *GetPipelineStagesConfig) iter.Seq2[PipelineStage, *errortools.Error] {
+	return seq(ctx, service, "PipelineStagesSeq", pipelineStageResource, config)
 }
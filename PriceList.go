@@ -0,0 +1,191 @@
+package insightly
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// PriceList holds the prices of products per currency, e.g.
+//
+//	insightly.PriceList{
+//		"EUR": {productID: 19.95},
+//		"USD": {productID: 24.95},
+//	}
+//
+type PriceList map[string]map[int64]float64
+
+type PriceListAction string
+
+const (
+	PriceListActionCreate     PriceListAction = "create"
+	PriceListActionUpdate     PriceListAction = "update"
+	PriceListActionDeactivate PriceListAction = "deactivate"
+)
+
+// PriceListChange describes a change of a pricebook entry made by ApplyPriceList
+//
+type PriceListChange struct {
+	Action       PriceListAction
+	ProductID    int64
+	CurrencyCode string
+	// PricebookEntryID is zero for an entry that is yet to be created
+	PricebookEntryID int64
+	// OldPrice is nil for a created entry
+	OldPrice *float64
+	// NewPrice is nil for a deactivated entry
+	NewPrice *float64
+	// Applied is false in a dry run and for changes after a failed one
+	Applied bool
+}
+
+// PriceListReport lists the changes made by ApplyPriceList, in order of currency and product
+//
+type PriceListReport struct {
+	DryRun    bool
+	Changes   []PriceListChange
+	Unchanged int
+}
+
+type ApplyPriceListConfig struct {
+	PricebookID int64
+	Prices      PriceList
+	// DryRun reports the changes without making them
+	DryRun bool
+}
+
+// ApplyPriceList makes the entries of a pricebook match a PriceList: entries missing from
+// the pricebook are created, entries with a different price, an inactive entry or one
+// that uses the standard price are updated, and active entries of the listed currencies
+// for products that are not listed are deactivated. Entries in other currencies are left
+// alone. If a change fails the report of the changes made so far is returned along with
+// the error. Nothing is changed if ServiceConfig.MaxRowCount truncates the read of the
+// entries of the pricebook.
+//
+func (service *Service) ApplyPriceList(config *ApplyPriceListConfig) (*PriceListReport, *errortools.Error) {
+	return service.ApplyPriceListWithContext(context.Background(), config)
+}
+
+// ApplyPriceListWithContext is the context-aware variant of ApplyPriceList
+//
+func (service *Service) ApplyPriceListWithContext(ctx context.Context, config *ApplyPriceListConfig) (*PriceListReport, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "ApplyPriceList")
+	defer span.End()
+
+	if config == nil {
+		return nil, errortools.ErrorMessage("ApplyPriceListConfig must not be nil")
+	}
+
+	pricebookEntries, e := pricebookEntryResource.listAll(ctx, service, &ListConfig{
+		FieldFilter: &FieldFilter{
+			FieldName:  "PRICEBOOK_ID",
+			FieldValue: fmt.Sprintf("%v", config.PricebookID),
+		},
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	type entryKey struct {
+		currencyCode string
+		productID    int64
+	}
+
+	existing := make(map[entryKey]*PricebookEntry)
+	for i := range *pricebookEntries {
+		entry := &(*pricebookEntries)[i]
+		existing[entryKey{entry.CurrencyCode, entry.ProductID}] = entry
+	}
+
+	report := PriceListReport{DryRun: config.DryRun}
+	changed := []*PricebookEntry{}
+
+	for _, currencyCode := range slices.Sorted(maps.Keys(config.Prices)) {
+		prices := config.Prices[currencyCode]
+
+		productIDs := slices.Collect(maps.Keys(prices))
+		for key := range existing {
+			if key.currencyCode == currencyCode {
+				if _, ok := prices[key.productID]; !ok {
+					productIDs = append(productIDs, key.productID)
+				}
+			}
+		}
+		slices.Sort(productIDs)
+
+		for _, productID := range productIDs {
+			entry := existing[entryKey{currencyCode, productID}]
+			price, listed := prices[productID]
+
+			change := PriceListChange{
+				ProductID:    productID,
+				CurrencyCode: currencyCode,
+			}
+
+			switch {
+			case entry == nil:
+				change.Action = PriceListActionCreate
+				change.NewPrice = &price
+				entry = &PricebookEntry{
+					PricebookID:  config.PricebookID,
+					ProductID:    productID,
+					CurrencyCode: currencyCode,
+					Price:        price,
+					Active:       true,
+				}
+			case !listed:
+				if !entry.Active {
+					report.Unchanged++
+					continue
+				}
+				change.Action = PriceListActionDeactivate
+				change.PricebookEntryID = entry.PricebookEntryID
+				change.OldPrice = &entry.Price
+				entry.Active = false
+			case entry.Price != price || !entry.Active || entry.UseStandardPrice:
+				oldPrice := entry.Price
+				change.Action = PriceListActionUpdate
+				change.PricebookEntryID = entry.PricebookEntryID
+				change.OldPrice = &oldPrice
+				change.NewPrice = &price
+				entry.Price = price
+				entry.Active = true
+				entry.UseStandardPrice = false
+			default:
+				report.Unchanged++
+				continue
+			}
+
+			report.Changes = append(report.Changes, change)
+			changed = append(changed, entry)
+		}
+	}
+
+	if config.DryRun {
+		return &report, nil
+	}
+
+	for i, entry := range changed {
+		change := &report.Changes[i]
+
+		if change.Action == PriceListActionCreate {
+			created, e := pricebookEntryResource.Create(ctx, service, entry)
+			if e != nil {
+				return &report, e
+			}
+			change.PricebookEntryID = created.PricebookEntryID
+		} else {
+			_, e := pricebookEntryResource.Update(ctx, service, entry)
+			if e != nil {
+				return &report, e
+			}
+		}
+
+		change.Applied = true
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,156 @@
+package insightly_test
+
+import (
+	"net/http"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// seedPriceList seeds a pricebook with an entry to keep, one to update, one to deactivate,
+// an inactive one to leave alone and one in another currency
+//
+func seedPriceList(server *insightlytest.Server) (pricebookID int64, entryIDs []int64) {
+	pricebookID = server.Seed("Pricebook", &insightly.Pricebook{Name: "Partners", Active: true})[0]
+	entryIDs = server.Seed("PricebookEntry",
+		map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 1, "CURRENCY_CODE": "EUR", "PRICE": 10, "ACTIVE": true},
+		map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 2, "CURRENCY_CODE": "EUR", "PRICE": 20, "ACTIVE": true},
+		map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 3, "CURRENCY_CODE": "EUR", "PRICE": 30, "ACTIVE": true},
+		map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 5, "CURRENCY_CODE": "EUR", "PRICE": 50, "ACTIVE": false},
+		map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 3, "CURRENCY_CODE": "USD", "PRICE": 35, "ACTIVE": true},
+	)
+
+	return pricebookID, entryIDs
+}
+
+func priceListConfig(pricebookID int64, dryRun bool) *insightly.ApplyPriceListConfig {
+	return &insightly.ApplyPriceListConfig{
+		PricebookID: pricebookID,
+		Prices: insightly.PriceList{
+			"EUR": {1: 10, 2: 25, 4: 40},
+		},
+		DryRun: dryRun,
+	}
+}
+
+func TestApplyPriceListPlan(t *testing.T) {
+	service, server := newTestService(t, nil)
+	pricebookID, entryIDs := seedPriceList(server)
+
+	report, e := service.ApplyPriceList(priceListConfig(pricebookID, true))
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if !report.DryRun || report.Unchanged != 2 {
+		t.Errorf("got a dry run %v with %v unchanged, want a dry run with 2 unchanged", report.DryRun, report.Unchanged)
+	}
+
+	want := []struct {
+		action           insightly.PriceListAction
+		productID        int64
+		pricebookEntryID int64
+	}{
+		{insightly.PriceListActionUpdate, 2, entryIDs[1]},
+		{insightly.PriceListActionDeactivate, 3, entryIDs[2]},
+		{insightly.PriceListActionCreate, 4, 0},
+	}
+	if len(report.Changes) != len(want) {
+		t.Fatalf("got %v changes, want %v", len(report.Changes), len(want))
+	}
+	for i, change := range report.Changes {
+		if change.Action != want[i].action || change.ProductID != want[i].productID || change.PricebookEntryID != want[i].pricebookEntryID || change.CurrencyCode != "EUR" || change.Applied {
+			t.Errorf("got change %+v, want %s of product %v", change, want[i].action, want[i].productID)
+		}
+	}
+
+	update, deactivate, create := report.Changes[0], report.Changes[1], report.Changes[2]
+	if *update.OldPrice != 20 || *update.NewPrice != 25 {
+		t.Errorf("got update from %v to %v, want from 20 to 25", *update.OldPrice, *update.NewPrice)
+	}
+	if *deactivate.OldPrice != 30 || deactivate.NewPrice != nil {
+		t.Errorf("got deactivation of price %v to %v, want of 30 to none", *deactivate.OldPrice, deactivate.NewPrice)
+	}
+	if create.OldPrice != nil || *create.NewPrice != 40 {
+		t.Errorf("got creation of price %v, want 40", *create.NewPrice)
+	}
+
+	for _, request := range server.Requests() {
+		if request.Method != http.MethodGet {
+			t.Errorf("got %s %s in a dry run, want reads only", request.Method, request.Path)
+		}
+	}
+}
+
+func TestApplyPriceList(t *testing.T) {
+	service, server := newTestService(t, nil)
+	pricebookID, entryIDs := seedPriceList(server)
+
+	report, e := service.ApplyPriceList(priceListConfig(pricebookID, false))
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	for _, change := range report.Changes {
+		if !change.Applied {
+			t.Errorf("got change %+v not applied", change)
+		}
+	}
+	created := report.Changes[2].PricebookEntryID
+	if created == 0 {
+		t.Fatal("expected the id of the created entry")
+	}
+
+	entry := insightly.PricebookEntry{}
+	server.Get("PricebookEntry", entryIDs[1], &entry)
+	if entry.Price != 25 || !entry.Active {
+		t.Errorf("got price %v active %v, want the updated price 25", entry.Price, entry.Active)
+	}
+	entry = insightly.PricebookEntry{}
+	server.Get("PricebookEntry", entryIDs[2], &entry)
+	if entry.Active {
+		t.Error("expected the unlisted entry to be deactivated")
+	}
+	entry = insightly.PricebookEntry{}
+	server.Get("PricebookEntry", entryIDs[4], &entry)
+	if !entry.Active || entry.Price != 35 {
+		t.Error("expected the entry in another currency to be left alone")
+	}
+	entry = insightly.PricebookEntry{}
+	server.Get("PricebookEntry", created, &entry)
+	if entry.PricebookID != pricebookID || entry.ProductID != 4 || entry.CurrencyCode != "EUR" || entry.Price != 40 || !entry.Active {
+		t.Errorf("got created entry %+v, want product 4 at 40 EUR", entry)
+	}
+
+	// applying again changes nothing
+	report, e = service.ApplyPriceList(priceListConfig(pricebookID, false))
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(report.Changes) != 0 || report.Unchanged != 5 {
+		t.Errorf("got %v changes and %v unchanged, want 5 unchanged", len(report.Changes), report.Unchanged)
+	}
+}
+
+func TestApplyPriceListTruncated(t *testing.T) {
+	service, server := truncatingTestService(t)
+	pricebookID, _ := seedPriceList(server)
+
+	entries := []any{}
+	for i := range pageSize {
+		entries = append(entries, map[string]any{"PRICEBOOK_ID": pricebookID, "PRODUCT_ID": 100 + i, "CURRENCY_CODE": "USD", "PRICE": 10, "ACTIVE": true})
+	}
+	server.Seed("PricebookEntry", entries...)
+
+	_, e := service.ApplyPriceList(priceListConfig(pricebookID, false))
+	if e == nil {
+		t.Fatal("expected an error for truncated pricebook entries")
+	}
+
+	for _, request := range server.Requests() {
+		if request.Method != http.MethodGet {
+			t.Errorf("got %s %s, want nothing changed", request.Method, request.Path)
+		}
+	}
+}
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -26,102 +23,134 @@ type Pricebook struct {
 	DateUpdatedUTC i_types.DateTimeString `json:"DATE_UPDATED_UTC"`
 }
 
+func (p *Pricebook) prepareMarshal() interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return &struct {
+		PricebookID  *int64  `json:"PRICEBOOK_ID,omitempty"`
+		Name         *string `json:"NAME,omitempty"`
+		Description  *string `json:"DESCRIPTION,omitempty"`
+		CurrencyCode *string `json:"CURRENCY_CODE,omitempty"`
+		Active       *bool   `json:"ACTIVE,omitempty"`
+		OwnerUserID  *int64  `json:"OWNER_USER_ID,omitempty"`
+	}{
+		nonZero(p.PricebookID),
+		&p.Name,
+		&p.Description,
+		nonZero(p.CurrencyCode),
+		&p.Active,
+		nonZero(p.OwnerUserID),
+	}
+}
+
+var pricebookResource = &Resource[Pricebook]{
+	Endpoint: "Pricebook",
+	ID:       func(p *Pricebook) int64 { return p.PricebookID },
+	Marshal:  func(p *Pricebook) interface{} { return p.prepareMarshal() },
+}
+
 // GetPricebook returns a specific pricebook
 //
 func (service *Service) GetPricebook(pricebookID int64) (*Pricebook, *errortools.Error) {
-	pricebook := Pricebook{}
+	return service.GetPricebookWithContext(context.Background(), pricebookID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Pricebook/%v", pricebookID)),
-		ResponseModel: &pricebook,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetPricebookWithContext is the context-aware variant of GetPricebook
+//
+func (service *Service) GetPricebookWithContext(ctx context.Context, pricebookID int64) (*Pricebook, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebook")
+	defer span.End()
 
-	return &pricebook, nil
+	return pricebookResource.Get(ctx, service, pricebookID)
 }
 
-type GetPricebooksConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetPricebooksConfig = ListConfig
 
 // GetPricebooks returns all pricebooks
 //
 func (service *Service) GetPricebooks(config *GetPricebooksConfig) (*[]Pricebook, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Pricebook"
-	pricebooks := []Pricebook{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetPricebooksWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetPricebooksWithContext is the context-aware variant of GetPricebooks
+//
+func (service *Service) GetPricebooksWithContext(ctx context.Context, config *GetPricebooksConfig) (*[]Pricebook, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebooks")
+	defer span.End()
+
+	return pricebookResource.List(ctx, service, config)
+}
+
+// GetPricebooksPage returns a single page of pricebooks and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetPricebooksPage(config *GetPricebooksConfig) (*[]Pricebook, *Cursor, *errortools.Error) {
+	return service.GetPricebooksPageWithContext(context.Background(), config)
+}
+
+// GetPricebooksPageWithContext is the context-aware variant of GetPricebooksPage
+//
+func (service *Service) GetPricebooksPageWithContext(ctx context.Context, config *GetPricebooksConfig) (*[]Pricebook, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebooksPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return pricebookResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		pricebooksBatch := []Pricebook{}
+// PricebooksSeq returns an iterator over all pricebooks, fetching pages on demand
+//
+func (service *Service) PricebooksSeq(config *GetPricebooksConfig) iter.Seq2[Pricebook, *errortools.Error] {
+	return service.PricebooksSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &pricebooksBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// PricebooksSeqWithContext is the context-aware variant of PricebooksSeq
+//
+func (service *Service) PricebooksSeqWithContext(ctx context.Context, config *GetPricebooksConfig) iter.Seq2[Pricebook, *errortools.Error] {
+	return seq(ctx, service, "PricebooksSeq", pricebookResource, config)
+}
 
-		pricebooks = append(pricebooks, pricebooksBatch...)
+// CreatePricebook creates a new pricebook
+//
+func (service *Service) CreatePricebook(pricebook *Pricebook) (*Pricebook, *errortools.Error) {
+	return service.CreatePricebookWithContext(context.Background(), pricebook)
+}
 
-		if len(pricebooksBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreatePricebookWithContext is the context-aware variant of CreatePricebook
+//
+func (service *Service) CreatePricebookWithContext(ctx context.Context, pricebook *Pricebook) (*Pricebook, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreatePricebook")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return pricebookResource.Create(ctx, service, pricebook)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &pricebooks, nil
-		}
-	}
+// UpdatePricebook updates an existing pricebook
+//
+func (service *Service) UpdatePricebook(pricebook *Pricebook) (*Pricebook, *errortools.Error) {
+	return service.UpdatePricebookWithContext(context.Background(), pricebook)
+}
+
+// UpdatePricebookWithContext is the context-aware variant of UpdatePricebook
+//
+func (service *Service) UpdatePricebookWithContext(ctx context.Context, pricebook *Pricebook) (*Pricebook, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdatePricebook")
+	defer span.End()
+
+	return pricebookResource.Update(ctx, service, pricebook)
+}
+
+// DeletePricebook deletes a specific pricebook
+//
+func (service *Service) DeletePricebook(pricebookID int64) *errortools.Error {
+	return service.DeletePricebookWithContext(context.Background(), pricebookID)
+}
+
+// DeletePricebookWithContext is the context-aware variant of DeletePricebook
+//
+func (service *Service) DeletePricebookWithContext(ctx context.Context, pricebookID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeletePricebook")
+	defer span.End()
 
-	return &pricebooks, nil
+	return pricebookResource.Delete(ctx, service, pricebookID)
 }
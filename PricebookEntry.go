@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -27,102 +24,138 @@ type PricebookEntry struct {
 	CustomFields     *CustomFields          `json:"CUSTOMFIELDS"`
 }
 
+func (p *PricebookEntry) prepareMarshal() interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return &struct {
+		PricebookEntryID *int64        `json:"PRICEBOOK_ENTRY_ID,omitempty"`
+		PricebookID      *int64        `json:"PRICEBOOK_ID,omitempty"`
+		ProductID        *int64        `json:"PRODUCT_ID,omitempty"`
+		CurrencyCode     *string       `json:"CURRENCY_CODE,omitempty"`
+		Price            *float64      `json:"PRICE,omitempty"`
+		UseStandardPrice *bool         `json:"USE_STANDARD_PRICE,omitempty"`
+		Active           *bool         `json:"ACTIVE,omitempty"`
+		CustomFields     *CustomFields `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(p.PricebookEntryID),
+		nonZero(p.PricebookID),
+		nonZero(p.ProductID),
+		nonZero(p.CurrencyCode),
+		&p.Price,
+		&p.UseStandardPrice,
+		&p.Active,
+		p.CustomFields,
+	}
+}
+
+var pricebookEntryResource = &Resource[PricebookEntry]{
+	Endpoint: "PricebookEntry",
+	ID:       func(p *PricebookEntry) int64 { return p.PricebookEntryID },
+	Marshal:  func(p *PricebookEntry) interface{} { return p.prepareMarshal() },
+}
+
 // GetPricebookEntry returns a specific pricebookEntry
 //
 func (service *Service) GetPricebookEntry(pricebookEntryID int64) (*PricebookEntry, *errortools.Error) {
-	pricebookEntry := PricebookEntry{}
+	return service.GetPricebookEntryWithContext(context.Background(), pricebookEntryID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("PricebookEntry/%v", pricebookEntryID)),
-		ResponseModel: &pricebookEntry,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetPricebookEntryWithContext is the context-aware variant of GetPricebookEntry
+//
+func (service *Service) GetPricebookEntryWithContext(ctx context.Context, pricebookEntryID int64) (*PricebookEntry, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebookEntry")
+	defer span.End()
 
-	return &pricebookEntry, nil
+	return pricebookEntryResource.Get(ctx, service, pricebookEntryID)
 }
 
-type GetPricebookEntriesConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetPricebookEntriesConfig = ListConfig
 
 // GetPricebookEntries returns all PricebookEntries
 //
 func (service *Service) GetPricebookEntries(config *GetPricebookEntriesConfig) (*[]PricebookEntry, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "PricebookEntry"
-	pricebookEntries := []PricebookEntry{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetPricebookEntriesWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetPricebookEntriesWithContext is the context-aware variant of GetPricebookEntries
+//
+func (service *Service) GetPricebookEntriesWithContext(ctx context.Context, config *GetPricebookEntriesConfig) (*[]PricebookEntry, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebookEntries")
+	defer span.End()
+
+	return pricebookEntryResource.List(ctx, service, config)
+}
+
+// GetPricebookEntriesPage returns a single page of pricebookEntries and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetPricebookEntriesPage(config *GetPricebookEntriesConfig) (*[]PricebookEntry, *Cursor, *errortools.Error) {
+	return service.GetPricebookEntriesPageWithContext(context.Background(), config)
+}
+
+// GetPricebookEntriesPageWithContext is the context-aware variant of GetPricebookEntriesPage
+//
+func (service *Service) GetPricebookEntriesPageWithContext(ctx context.Context, config *GetPricebookEntriesConfig) (*[]PricebookEntry, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetPricebookEntriesPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return pricebookEntryResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		pricebookEntriesBatch := []PricebookEntry{}
+// PricebookEntriesSeq returns an iterator over all pricebookEntries, fetching pages on demand
+//
+func (service *Service) PricebookEntriesSeq(config *GetPricebookEntriesConfig) iter.Seq2[PricebookEntry, *errortools.Error] {
+	return service.PricebookEntriesSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &pricebookEntriesBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// PricebookEntriesSeqWithContext is the context-aware variant of PricebookEntriesSeq
+//
+func (service *Service) PricebookEntriesSeqWithContext(ctx context.Context, config *GetPricebookEntriesConfig) iter.Seq2[PricebookEntry, *errortools.Error] {
+	return seq(ctx, service, "PricebookEntriesSeq", pricebookEntryResource, config)
+}
 
-		pricebookEntries = append(pricebookEntries, pricebookEntriesBatch...)
+// CreatePricebookEntry creates a new pricebookEntry
+//
+func (service *Service) CreatePricebookEntry(pricebookEntry *PricebookEntry) (*PricebookEntry, *errortools.Error) {
+	return service.CreatePricebookEntryWithContext(context.Background(), pricebookEntry)
+}
 
-		if len(pricebookEntriesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreatePricebookEntryWithContext is the context-aware variant of CreatePricebookEntry
+//
+func (service *Service) CreatePricebookEntryWithContext(ctx context.Context, pricebookEntry *PricebookEntry) (*PricebookEntry, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreatePricebookEntry")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return pricebookEntryResource.Create(ctx, service, pricebookEntry)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &pricebookEntries, nil
-		}
-	}
+// UpdatePricebookEntry updates an existing pricebookEntry
+//
+func (service *Service) UpdatePricebookEntry(pricebookEntry *PricebookEntry) (*PricebookEntry, *errortools.Error) {
+	return service.UpdatePricebookEntryWithContext(context.Background(), pricebookEntry)
+}
+
+// UpdatePricebookEntryWithContext is the context-aware variant of UpdatePricebookEntry
+//
+func (service *Service) UpdatePricebookEntryWithContext(ctx context.Context, pricebookEntry *PricebookEntry) (*PricebookEntry, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdatePricebookEntry")
+	defer span.End()
+
+	return pricebookEntryResource.Update(ctx, service, pricebookEntry)
+}
+
+// DeletePricebookEntry deletes a specific pricebookEntry
+//
+func (service *Service) DeletePricebookEntry(pricebookEntryID int64) *errortools.Error {
+	return service.DeletePricebookEntryWithContext(context.Background(), pricebookEntryID)
+}
+
+// DeletePricebookEntryWithContext is the context-aware variant of DeletePricebookEntry
+//
+func (service *Service) DeletePricebookEntryWithContext(ctx context.Context, pricebookEntryID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeletePricebookEntry")
+	defer span.End()
 
-	return &pricebookEntries, nil
+	return pricebookEntryResource.Delete(ctx, service, pricebookEntryID)
 }
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -65,163 +62,113 @@ func (p *Product) prepareMarshal() interface{} {
 	}
 }
 
+var productResource = &Resource[Product]{
+	Endpoint: "Product",
+	ID:       func(p *Product) int64 { return p.ProductID },
+	Marshal:  func(p *Product) interface{} { return p.prepareMarshal() },
+}
+
 // GetProduct returns a specific product
 //
 func (service *Service) GetProduct(productID int64) (*Product, *errortools.Error) {
-	product := Product{}
+	return service.GetProductWithContext(context.Background(), productID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Product/%v", productID)),
-		ResponseModel: &product,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetProductWithContext is the context-aware variant of GetProduct
+//
+func (service *Service) GetProductWithContext(ctx context.Context, productID int64) (*Product, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProduct")
+	defer span.End()
 
-	return &product, nil
+	return productResource.Get(ctx, service, productID)
 }
 
-type GetProductsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetProductsConfig = ListConfig
 
 // GetProducts returns all products
 //
 func (service *Service) GetProducts(config *GetProductsConfig) (*[]Product, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Product"
-	products := []Product{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
-
-	if isSearch {
-		endpoint += "/Search"
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
+	return service.GetProductsWithContext(context.Background(), config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		productsBatch := []Product{}
+// GetProductsWithContext is the context-aware variant of GetProducts
+//
+func (service *Service) GetProductsWithContext(ctx context.Context, config *GetProductsConfig) (*[]Product, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProducts")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &productsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return productResource.List(ctx, service, config)
+}
 
-		products = append(products, productsBatch...)
+// GetProductsPage returns a single page of products and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetProductsPage(config *GetProductsConfig) (*[]Product, *Cursor, *errortools.Error) {
+	return service.GetProductsPageWithContext(context.Background(), config)
+}
 
-		if len(productsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetProductsPageWithContext is the context-aware variant of GetProductsPage
+//
+func (service *Service) GetProductsPageWithContext(ctx context.Context, config *GetProductsConfig) (*[]Product, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProductsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return productResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &products, nil
-		}
-	}
+// ProductsSeq returns an iterator over all products, fetching pages on demand
+//
+func (service *Service) ProductsSeq(config *GetProductsConfig) iter.Seq2[Product, *errortools.Error] {
+	return service.ProductsSeqWithContext(context.Background(), config)
+}
 
-	return &products, nil
+// ProductsSeqWithContext is the context-aware variant of ProductsSeq
+//
+func (service *Service) ProductsSeqWithContext(ctx context.Context, config *GetProductsConfig) iter.Seq2[Product, *errortools.Error] {
+	return seq(ctx, service, "ProductsSeq", productResource, config)
 }
 
 // CreateProduct creates a new contract
 //
 func (service *Service) CreateProduct(product *Product) (*Product, *errortools.Error) {
-	if product == nil {
-		return nil, nil
-	}
-
-	productNew := Product{}
+	return service.CreateProductWithContext(context.Background(), product)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Products"),
-		BodyModel:     product.prepareMarshal(),
-		ResponseModel: &productNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateProductWithContext is the context-aware variant of CreateProduct
+//
+func (service *Service) CreateProductWithContext(ctx context.Context, product *Product) (*Product, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateProduct")
+	defer span.End()
 
-	return &productNew, nil
+	return productResource.Create(ctx, service, product)
 }
 
-// UpdateProduct updates an existing contract
+// UpdateProduct updates an existing contract, a product without ProductID is rejected
+// without sending a request
 //
 func (service *Service) UpdateProduct(product *Product) (*Product, *errortools.Error) {
-	if product == nil {
-		return nil, nil
-	}
-
-	productUpdated := Product{}
+	return service.UpdateProductWithContext(context.Background(), product)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Products"),
-		BodyModel:     product.prepareMarshal(),
-		ResponseModel: &productUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateProductWithContext is the context-aware variant of UpdateProduct
+//
+func (service *Service) UpdateProductWithContext(ctx context.Context, product *Product) (*Product, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateProduct")
+	defer span.End()
 
-	return &productUpdated, nil
+	return productResource.Update(ctx, service, product)
 }
 
 // DeleteProduct deletes a specific product
 //
 func (service *Service) DeleteProduct(productID int64) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Products/%v", productID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteProductWithContext(context.Background(), productID)
+}
+
+// DeleteProductWithContext is the context-aware variant of DeleteProduct
+//
+func (service *Service) DeleteProductWithContext(ctx context.Context, productID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteProduct")
+	defer span.End()
 
-	return nil
+	return productResource.Delete(ctx, service, productID)
 }
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -35,102 +32,186 @@ type Project struct {
 	Links               *[]Link                 `json:"LINKS"`
 }
 
+func (p *Project) prepareMarshal() interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return &struct {
+		ProjectID         *int64                  `json:"PROJECT_ID,omitempty"`
+		ProjectName       *string                 `json:"PROJECT_NAME,omitempty"`
+		Status            *string                 `json:"STATUS,omitempty"`
+		ProjectDetails    *string                 `json:"PROJECT_DETAILS,omitempty"`
+		StartedDate       *i_types.DateTimeString `json:"STARTED_DATE,omitempty"`
+		CompletedDate     *i_types.DateTimeString `json:"COMPLETED_DATE,omitempty"`
+		OpportunityID     *int64                  `json:"OPPORTUNITY_ID,omitempty"`
+		CategoryID        *int64                  `json:"CATEGORY_ID,omitempty"`
+		PipelineID        *int64                  `json:"PIPELINE_ID,omitempty"`
+		StageID           *int64                  `json:"STAGE_ID,omitempty"`
+		ImageUrl          *string                 `json:"IMAGE_URL,omitempty"`
+		OwnerUserID       *int64                  `json:"OWNER_USER_ID,omitempty"`
+		ResponsibleUserID *int64                  `json:"RESPONSIBLE_USER_ID,omitempty"`
+		CustomFields      *CustomFields           `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(p.ProjectID),
+		&p.ProjectName,
+		&p.Status,
+		p.ProjectDetails,
+		p.StartedDate,
+		p.CompletedDate,
+		p.OpportunityID,
+		nonZero(p.CategoryID),
+		nonZero(p.PipelineID),
+		nonZero(p.StageID),
+		p.ImageUrl,
+		nonZero(p.OwnerUserID),
+		p.ResponsibleUserID,
+		p.CustomFields,
+	}
+}
+
+var projectResource = &Resource[Project]{
+	Endpoint: "Projects",
+	ID:       func(p *Project) int64 { return p.ProjectID },
+	Marshal:  func(p *Project) interface{} { return p.prepareMarshal() },
+}
+
 // GetProject returns a specific project
 //
 func (service *Service) GetProject(projectID int64) (*Project, *errortools.Error) {
-	project := Project{}
+	return service.GetProjectWithContext(context.Background(), projectID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Project/%v", projectID)),
-		ResponseModel: &project,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetProjectWithContext is the context-aware variant of GetProject
+//
+func (service *Service) GetProjectWithContext(ctx context.Context, projectID int64) (*Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProject")
+	defer span.End()
 
-	return &project, nil
+	return projectResource.Get(ctx, service, projectID)
 }
 
-type GetProjectsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetProjectsConfig = ListConfig
 
 // GetProjects returns all projects
 //
 func (service *Service) GetProjects(config *GetProjectsConfig) (*[]Project, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Project"
-	projects := []Project{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetProjectsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetProjectsWithContext is the context-aware variant of GetProjects
+//
+func (service *Service) GetProjectsWithContext(ctx context.Context, config *GetProjectsConfig) (*[]Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProjects")
+	defer span.End()
+
+	return projectResource.List(ctx, service, config)
+}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+// GetProjectsPage returns a single page of projects and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetProjectsPage(config *GetProjectsConfig) (*[]Project, *Cursor, *errortools.Error) {
+	return service.GetProjectsPageWithContext(context.Background(), config)
+}
+
+// GetProjectsPageWithContext is the context-aware variant of GetProjectsPage
+//
+func (service *Service) GetProjectsPageWithContext(ctx context.Context, config *GetProjectsConfig) (*[]Project, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProjectsPage")
+	defer span.End()
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		projectsBatch := []Project{}
+	return projectResource.Page(ctx, service, config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &projectsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// ProjectsSeq returns an iterator over all projects, fetching pages on demand
+//
+func (service *Service) ProjectsSeq(config *GetProjectsConfig) iter.Seq2[Project, *errortools.Error] {
+	return service.ProjectsSeqWithContext(context.Background(), config)
+}
 
-		projects = append(projects, projectsBatch...)
+// ProjectsSeqWithContext is the context-aware variant of ProjectsSeq
+//
+func (service *Service) ProjectsSeqWithContext(ctx context.Context, config *GetProjectsConfig) iter.Seq2[Project, *errortools.Error] {
+	return seq(ctx, service, "ProjectsSeq", projectResource, config)
+}
 
-		if len(projectsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// SearchProjectsByTag returns the projects that have a specific tag
+func (service *Service) SearchProjectsByTag(tagName string, config *SearchByTagConfig) (*[]Project, *errortools.Error) {
+	return service.SearchProjectsByTagWithContext(context.Background(), tagName, config)
+}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+// SearchProjectsByTagWithContext is the context-aware variant of SearchProjectsByTag
+func (service *Service) SearchProjectsByTagWithContext(ctx context.Context, tagName string, config *SearchByTagConfig) (*[]Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SearchProjectsByTag")
+	defer span.End()
 
-		if rowCount >= service.maxRowCount {
-			return &projects, nil
-		}
-	}
+	return projectResource.ListByTag(ctx, service, tagName, config)
+}
+
+// QueryProjects returns the projects that match a Query
+func (service *Service) QueryProjects(query *Query) (*[]Project, *errortools.Error) {
+	return service.QueryProjectsWithContext(context.Background(), query)
+}
+
+// QueryProjectsWithContext is the context-aware variant of QueryProjects
+func (service *Service) QueryProjectsWithContext(ctx context.Context, query *Query) (*[]Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "QueryProjects")
+	defer span.End()
+
+	return projectResource.Query(ctx, service, query)
+}
+
+// QueryProjectsSeq returns an iterator over the projects that match a Query, fetching pages on demand
+func (service *Service) QueryProjectsSeq(query *Query) iter.Seq2[Project, *errortools.Error] {
+	return service.QueryProjectsSeqWithContext(context.Background(), query)
+}
+
+// QueryProjectsSeqWithContext is the context-aware variant of QueryProjectsSeq
+func (service *Service) QueryProjectsSeqWithContext(ctx context.Context, query *Query) iter.Seq2[Project, *errortools.Error] {
+	return querySeq[Project](ctx, service, "QueryProjectsSeq", projectResource.Endpoint, query)
+}
+
+// CreateProject creates a new project
+//
+func (service *Service) CreateProject(project *Project) (*Project, *errortools.Error) {
+	return service.CreateProjectWithContext(context.Background(), project)
+}
+
+// CreateProjectWithContext is the context-aware variant of CreateProject
+//
+func (service *Service) CreateProjectWithContext(ctx context.Context, project *Project) (*Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateProject")
+	defer span.End()
+
+	return projectResource.Create(ctx, service, project)
+}
+
+// UpdateProject updates an existing project
+//
+func (service *Service) UpdateProject(project *Project) (*Project, *errortools.Error) {
+	return service.UpdateProjectWithContext(context.Background(), project)
+}
+
+// UpdateProjectWithContext is the context-aware variant of UpdateProject
+//
+func (service *Service) UpdateProjectWithContext(ctx context.Context, project *Project) (*Project, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateProject")
+	defer span.End()
+
+	return projectResource.Update(ctx, service, project)
+}
+
+// DeleteProject deletes a specific project
+//
+func (service *Service) DeleteProject(projectID int64) *errortools.Error {
+	return service.DeleteProjectWithContext(context.Background(), projectID)
+}
+
+// DeleteProjectWithContext is the context-aware variant of DeleteProject
+//
+func (service *Service) DeleteProjectWithContext(ctx context.Context, projectID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteProject")
+	defer span.End()
 
-	return &projects, nil
+	return projectResource.Delete(ctx, service, projectID)
 }
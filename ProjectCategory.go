@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // ProjectCategory stores ProjectCategory from Service
@@ -18,66 +16,51 @@ type ProjectCategory struct {
 	BackgroundColor string `json:"BACKGROUND_COLOR"`
 }
 
-type GetProjectCategoriesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var projectCategoryResource = &Resource[ProjectCategory]{
+	Endpoint: "ProjectCategories",
+	ID:       func(p *ProjectCategory) int64 { return p.CategoryID },
 }
 
+type GetProjectCategoriesConfig = PagingConfig
+
 // GetProjectCategories returns all projectCategories
 //
 func (service *Service) GetProjectCategories(config *GetProjectCategoriesConfig) (*[]ProjectCategory, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "ProjectCategories"
-	projectCategories := []ProjectCategory{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		projectCategoriesBatch := []ProjectCategory{}
+	return service.GetProjectCategoriesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &projectCategoriesBatch,
-		}
+// GetProjectCategoriesWithContext is the context-aware variant of GetProjectCategories
+//
+func (service *Service) GetProjectCategoriesWithContext(ctx context.Context, config *GetProjectCategoriesConfig) (*[]ProjectCategory, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProjectCategories")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return projectCategoryResource.List(ctx, service, config)
+}
 
-		projectCategories = append(projectCategories, projectCategoriesBatch...)
+// GetProjectCategoriesPage returns a single page of projectCategories and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetProjectCategoriesPage(config *GetProjectCategoriesConfig) (*[]ProjectCategory, *Cursor, *errortools.Error) {
+	return service.GetProjectCategoriesPageWithContext(context.Background(), config)
+}
 
-		if len(projectCategoriesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetProjectCategoriesPageWithContext is the context-aware variant of GetProjectCategoriesPage
+//
+func (service *Service) GetProjectCategoriesPageWithContext(ctx context.Context, config *GetProjectCategoriesConfig) (*[]ProjectCategory, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProjectCategoriesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return projectCategoryResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &projectCategories, nil
-		}
-	}
+// ProjectCategoriesSeq returns an iterator over all projectCategories, fetching pages on demand
+//
+func (service *Service) ProjectCategoriesSeq(config *GetProjectCategoriesConfig) iter.Seq2[ProjectCategory, *errortools.Error] {
+	return service.ProjectCategoriesSeqWithContext(context.Background(), config)
+}
 
-	return &projectCategories, nil
+// ProjectCategoriesSeqWithContext is the context-aware variant of ProjectCategoriesSeq
+//
+func (service *Service) ProjectCategoriesSeqWithContext(ctx context.Context, config *GetProjectCategoriesConfig) iter.Seq2[ProjectCategory, *errortools.Error] {
+	return seq(ctx, service, "ProjectCategoriesSeq", projectCategoryResource, config)
 }
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -53,102 +50,184 @@ type Prospect struct {
 	Tags                  *[]Tag                  `json:"TAGS"`
 }
 
+func (p *Prospect) prepareMarshal() interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return &struct {
+		ProspectID       *int64        `json:"PROSPECT_ID,omitempty"`
+		LeadID           *int64        `json:"LEAD_ID,omitempty"`
+		ContactID        *int64        `json:"CONTACT_ID,omitempty"`
+		OrganisationID   *int64        `json:"ORGANISATION_ID,omitempty"`
+		Salutation       *string       `json:"SALUTATION,omitempty"`
+		FirstName        *string       `json:"FIRST_NAME,omitempty"`
+		LastName         *string       `json:"LAST_NAME,omitempty"`
+		OrganisationName *string       `json:"ORGANISATION_NAME,omitempty"`
+		Title            *string       `json:"TITLE,omitempty"`
+		EmailAddress     *string       `json:"EMAIL_ADDRESS,omitempty"`
+		Phone            *string       `json:"PHONE,omitempty"`
+		Mobile           *string       `json:"MOBILE,omitempty"`
+		Fax              *string       `json:"FAX,omitempty"`
+		Website          *string       `json:"WEBSITE,omitempty"`
+		AddressStreet    *string       `json:"ADDRESS_STREET,omitempty"`
+		AddressCity      *string       `json:"ADDRESS_CITY,omitempty"`
+		AddressState     *string       `json:"ADDRESS_STATE,omitempty"`
+		AddressPostcode  *string       `json:"ADDRESS_POSTCODE,omitempty"`
+		AddressCountry   *string       `json:"ADDRESS_COUNTRY,omitempty"`
+		Industry         *string       `json:"INDUSTRY,omitempty"`
+		EmployeeCount    *int64        `json:"EMPLOYEE_COUNT,omitempty"`
+		Description      *string       `json:"DESCRIPTION,omitempty"`
+		DoNotEmail       *bool         `json:"DO_NOT_EMAIL,omitempty"`
+		DoNotCall        *bool         `json:"DO_NOT_CALL,omitempty"`
+		OptedOut         *bool         `json:"OPTED_OUT,omitempty"`
+		OwnerUserID      *int64        `json:"OWNER_USER_ID,omitempty"`
+		VisibleTo        *string       `json:"VISIBLE_TO,omitempty"`
+		VisibleTeamID    *int64        `json:"VISIBLE_TEAM_ID,omitempty"`
+		DoNotSync        *bool         `json:"DO_NOT_SYNC,omitempty"`
+		GradeProfileID   *int64        `json:"GRADE_PROFILE_ID,omitempty"`
+		CustomFields     *CustomFields `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(p.ProspectID),
+		p.LeadID,
+		p.ContactID,
+		p.OrganisationID,
+		p.Salutation,
+		&p.FirstName,
+		&p.LastName,
+		p.OrganisationName,
+		p.Title,
+		p.EmailAddress,
+		p.Phone,
+		p.Mobile,
+		p.Fax,
+		p.Website,
+		p.AddressStreet,
+		p.AddressCity,
+		p.AddressState,
+		p.AddressPostcode,
+		p.AddressCountry,
+		p.Industry,
+		p.EmployeeCount,
+		p.Description,
+		&p.DoNotEmail,
+		&p.DoNotCall,
+		&p.OptedOut,
+		nonZero(p.OwnerUserID),
+		nonZero(p.VisibleTo),
+		p.VisibleTeamID,
+		&p.DoNotSync,
+		p.GradeProfileID,
+		p.CustomFields,
+	}
+}
+
+var prospectResource = &Resource[Prospect]{
+	Endpoint: "Prospect",
+	ID:       func(p *Prospect) int64 { return p.ProspectID },
+	Marshal:  func(p *Prospect) interface{} { return p.prepareMarshal() },
+}
+
 // GetProspect returns a specific prospect
 //
 func (service *Service) GetProspect(prospectID int64) (*Prospect, *errortools.Error) {
-	prospect := Prospect{}
+	return service.GetProspectWithContext(context.Background(), prospectID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Prospect/%v", prospectID)),
-		ResponseModel: &prospect,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetProspectWithContext is the context-aware variant of GetProspect
+//
+func (service *Service) GetProspectWithContext(ctx context.Context, prospectID int64) (*Prospect, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProspect")
+	defer span.End()
 
-	return &prospect, nil
+	return prospectResource.Get(ctx, service, prospectID)
 }
 
-type GetProspectsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetProspectsConfig = ListConfig
 
 // GetProspects returns all prospects
 //
 func (service *Service) GetProspects(config *GetProspectsConfig) (*[]Prospect, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Prospect"
-	prospects := []Prospect{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetProspectsWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetProspectsWithContext is the context-aware variant of GetProspects
+//
+func (service *Service) GetProspectsWithContext(ctx context.Context, config *GetProspectsConfig) (*[]Prospect, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProspects")
+	defer span.End()
+
+	return prospectResource.List(ctx, service, config)
+}
+
+// GetProspectsPage returns a single page of prospects and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetProspectsPage(config *GetProspectsConfig) (*[]Prospect, *Cursor, *errortools.Error) {
+	return service.GetProspectsPageWithContext(context.Background(), config)
+}
+
+// GetProspectsPageWithContext is the context-aware variant of GetProspectsPage
+//
+func (service *Service) GetProspectsPageWithContext(ctx context.Context, config *GetProspectsConfig) (*[]Prospect, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetProspectsPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return prospectResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		prospectsBatch := []Prospect{}
+// ProspectsSeq returns an iterator over all prospects, fetching pages on demand
+//
+func (service *Service) ProspectsSeq(config *GetProspectsConfig) iter.Seq2[Prospect, *errortools.Error] {
+	return service.ProspectsSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &prospectsBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// ProspectsSeqWithContext is the context-aware variant of ProspectsSeq
+//
+func (service *Service) ProspectsSeqWithContext(ctx context.Context, config *GetProspectsConfig) iter.Seq2[Prospect, *errortools.Error] {
+	return seq(ctx, service, "ProspectsSeq", prospectResource, config)
+}
 
-		prospects = append(prospects, prospectsBatch...)
+// CreateProspect creates a new prospect
+//
+func (service *Service) CreateProspect(prospect *Prospect) (*Prospect, *errortools.Error) {
+	return service.CreateProspectWithContext(context.Background(), prospect)
+}
 
-		if len(prospectsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreateProspectWithContext is the context-aware variant of CreateProspect
+//
+func (service *Service) CreateProspectWithContext(ctx context.Context, prospect *Prospect) (*Prospect, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateProspect")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return prospectResource.Create(ctx, service, prospect)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &prospects, nil
-		}
-	}
+// UpdateProspect updates an existing prospect
+//
+func (service *Service) UpdateProspect(prospect *Prospect) (*Prospect, *errortools.Error) {
+	return service.UpdateProspectWithContext(context.Background(), prospect)
+}
+
+// UpdateProspectWithContext is the context-aware variant of UpdateProspect
+//
+func (service *Service) UpdateProspectWithContext(ctx context.Context, prospect *Prospect) (*Prospect, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateProspect")
+	defer span.End()
+
+	return prospectResource.Update(ctx, service, prospect)
+}
+
+// DeleteProspect deletes a specific prospect
+//
+func (service *Service) DeleteProspect(prospectID int64) *errortools.Error {
+	return service.DeleteProspectWithContext(context.Background(), prospectID)
+}
+
+// DeleteProspectWithContext is the context-aware variant of DeleteProspect
+//
+func (service *Service) DeleteProspectWithContext(ctx context.Context, prospectID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteProspect")
+	defer span.End()
 
-	return &prospects, nil
+	return prospectResource.Delete(ctx, service, prospectID)
 }
@@ -0,0 +1,152 @@
+package insightly
+
+import (
+	"context"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// Consent holds the marketing consent flags of a prospect, flags that are nil are left
+// unchanged
+//
+type Consent struct {
+	DoNotEmail *bool
+	DoNotCall  *bool
+	OptedOut   *bool
+	DoNotSync  *bool
+}
+
+// emailOptedOut returns the EMAIL_OPTED_OUT value of a contact or lead linked to the
+// prospect, which is opted out of email if either DoNotEmail or OptedOut is set
+//
+func emailOptedOut(prospect *Prospect) bool {
+	return prospect.DoNotEmail || prospect.OptedOut
+}
+
+type SetProspectConsentConfig struct {
+	ProspectID int64
+	Consent    Consent
+	// Source identifies the system that changed the consent, e.g. the name of a consent platform
+	Source string
+	// SourceField is the name of the text custom field Source is recorded in, e.g.
+	// "CONSENT_SOURCE__c", it must exist on prospects as well as on contacts and leads
+	// that are linked to prospects; Source is not recorded if SourceField is empty
+	SourceField string
+}
+
+// ProspectConsent holds the records updated by SetProspectConsent, Contact and Lead are
+// nil if the prospect is not linked to one
+//
+type ProspectConsent struct {
+	Prospect *Prospect
+	Contact  *Contact
+	Lead     *Lead
+}
+
+// SetProspectConsent sets or clears the consent flags of a prospect and updates the
+// EmailOptedOut flag of the contact and lead it is linked to accordingly, contacts and
+// leads have no other consent flags. The linked records are updated even if the flags
+// of the prospect were already set, so they can be brought back in sync. If updating the
+// contact or lead fails, the records updated so far are returned along with the error.
+//
+func (service *Service) SetProspectConsent(config *SetProspectConsentConfig) (*ProspectConsent, *errortools.Error) {
+	return service.SetProspectConsentWithContext(context.Background(), config)
+}
+
+// SetProspectConsentWithContext is the context-aware variant of SetProspectConsent
+//
+func (service *Service) SetProspectConsentWithContext(ctx context.Context, config *SetProspectConsentConfig) (*ProspectConsent, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SetProspectConsent")
+	defer span.End()
+
+	if config == nil {
+		return nil, errortools.ErrorMessage("SetProspectConsentConfig must not be nil")
+	}
+
+	prospect, e := prospectResource.Get(ctx, service, config.ProspectID)
+	if e != nil {
+		return nil, e
+	}
+
+	if config.Consent.DoNotEmail != nil {
+		prospect.DoNotEmail = *config.Consent.DoNotEmail
+	}
+	if config.Consent.DoNotCall != nil {
+		prospect.DoNotCall = *config.Consent.DoNotCall
+	}
+	if config.Consent.OptedOut != nil {
+		prospect.OptedOut = *config.Consent.OptedOut
+	}
+	if config.Consent.DoNotSync != nil {
+		prospect.DoNotSync = *config.Consent.DoNotSync
+	}
+	prospect.CustomFields, e = config.recordSource(prospect.CustomFields)
+	if e != nil {
+		return nil, e
+	}
+
+	result := ProspectConsent{}
+
+	result.Prospect, e = prospectResource.Update(ctx, service, prospect)
+	if e != nil {
+		return nil, e
+	}
+
+	optedOut := emailOptedOut(result.Prospect)
+
+	if prospect.ContactID != nil {
+		contact, e := contactResource.Get(ctx, service, *prospect.ContactID)
+		if e != nil {
+			return &result, e
+		}
+
+		contact.EmailOptedOut = &optedOut
+		contact.CustomFields, e = config.recordSource(contact.CustomFields)
+		if e != nil {
+			return &result, e
+		}
+
+		result.Contact, e = contactResource.Update(ctx, service, contact)
+		if e != nil {
+			return &result, e
+		}
+	}
+
+	if prospect.LeadID != nil {
+		lead, e := leadResource.Get(ctx, service, *prospect.LeadID)
+		if e != nil {
+			return &result, e
+		}
+
+		lead.EmailOptedOut = optedOut
+		lead.CustomFields, e = config.recordSource(lead.CustomFields)
+		if e != nil {
+			return &result, e
+		}
+
+		result.Lead, e = leadResource.Update(ctx, service, lead)
+		if e != nil {
+			return &result, e
+		}
+	}
+
+	return &result, nil
+}
+
+// recordSource sets the source custom field, if configured
+//
+func (config *SetProspectConsentConfig) recordSource(customFields *CustomFields) (*CustomFields, *errortools.Error) {
+	if config.SourceField == "" {
+		return customFields, nil
+	}
+
+	if customFields == nil {
+		customFields = &CustomFields{}
+	}
+	e := customFields.SetText(config.SourceField, config.Source)
+	if e != nil {
+		return nil, e
+	}
+
+	return customFields, nil
+}
@@ -0,0 +1,38 @@
+package insightly_test
+
+import (
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestSetProspectConsentReturnsPartialResult(t *testing.T) {
+	service, server := newTestService(t, nil)
+	contactIDs := server.Seed("Contacts", &insightly.Contact{})
+	missingLeadID := int64(404)
+	prospectIDs := server.Seed("Prospect", &insightly.Prospect{ContactID: &contactIDs[0], LeadID: &missingLeadID})
+
+	doNotEmail := true
+	consent, e := service.SetProspectConsent(&insightly.SetProspectConsentConfig{
+		ProspectID:  prospectIDs[0],
+		Consent:     insightly.Consent{DoNotEmail: &doNotEmail},
+		Source:      "consent-platform",
+		SourceField: "CONSENT_SOURCE__c",
+	})
+	if e == nil {
+		t.Fatal("expected an error for the missing lead")
+	}
+
+	if consent == nil || consent.Prospect == nil || consent.Contact == nil {
+		t.Fatal("expected the updated prospect and contact along with the error")
+	}
+	if consent.Lead != nil {
+		t.Error("expected no lead")
+	}
+	if !consent.Prospect.DoNotEmail || consent.Contact.EmailOptedOut == nil || !*consent.Contact.EmailOptedOut {
+		t.Error("expected the prospect and contact to be opted out of email")
+	}
+	if source := consent.Contact.CustomFields.GetText("CONSENT_SOURCE__c"); source == nil || *source != "consent-platform" {
+		t.Errorf("got source %v, want consent-platform", source)
+	}
+}
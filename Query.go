@@ -0,0 +1,355 @@
+package insightly
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	go_http "github.com/leapforce-libraries/go_http"
+	i_types "github.com/leapforce-libraries/go_insightly/types"
+)
+
+// SearchByTagConfig selects the page of records returned by the SearchByTag methods
+//
+type SearchByTagConfig struct {
+	Skip       *uint64
+	Top        *uint64
+	Cursor     *Cursor
+	NextCursor *Cursor
+	Brief      *bool
+	CountTotal *bool
+}
+
+func (config *SearchByTagConfig) pageConfig(endpoint string, tagName string) *pageConfig {
+	if config == nil {
+		return byTagPageConfig(endpoint, tagName)
+	}
+
+	p := newCursorPageConfig(endpoint+"/SearchByTag", config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.params.Set("tagName", tagName)
+	p.setBrief(config.Brief)
+
+	return p
+}
+
+type conditionKind int
+
+const (
+	conditionField conditionKind = iota + 1
+	conditionTag
+	conditionAnd
+	conditionOr
+)
+
+// Condition is a criterion of a Query, built with Field, HasTag, And and Or
+//
+type Condition struct {
+	kind       conditionKind
+	name       string
+	value      string
+	conditions []Condition
+}
+
+// Field matches records of which the field, or custom field, fieldName equals value,
+// ignoring case like the /Search endpoints do
+//
+func Field(fieldName string, value string) Condition {
+	return Condition{kind: conditionField, name: fieldName, value: value}
+}
+
+// HasTag matches records that have a specific tag
+//
+func HasTag(tagName string) Condition {
+	return Condition{kind: conditionTag, name: tagName}
+}
+
+// And matches records that match all conditions
+//
+func And(conditions ...Condition) Condition {
+	return Condition{kind: conditionAnd, conditions: conditions}
+}
+
+// Or matches records that match at least one of the conditions
+//
+func Or(conditions ...Condition) Condition {
+	return Condition{kind: conditionOr, conditions: conditions}
+}
+
+// Query selects records by a combination of conditions and the time they were last
+// updated. One criterion that all matching records must meet is sent to the server, if
+// there are several the one matching the fewest records is chosen, which costs one
+// request per criterion. The remaining conditions are applied while paging.
+//
+type Query struct {
+	// Where is the condition records must match, all records match the zero Condition
+	Where        Condition
+	UpdatedAfter *time.Time
+	// Cursor resumes a query truncated by ServiceConfig.MaxRowCount with the criterion
+	// it was sent with, NextCursor receives the cursor of a truncated query
+	Cursor     *Cursor
+	NextCursor *Cursor
+}
+
+// queryRecord is a record as returned by the api, by field name
+//
+type queryRecord map[string]json.RawMessage
+
+func (c Condition) matches(record queryRecord) bool {
+	switch c.kind {
+	case conditionField:
+		value, ok := record.fieldValue(c.name)
+		return ok && strings.EqualFold(value, c.value)
+	case conditionTag:
+		tags := []Tag{}
+		_ = json.Unmarshal(record.get("TAGS"), &tags)
+		for _, tag := range tags {
+			if strings.EqualFold(tag.TagName, c.name) {
+				return true
+			}
+		}
+		return false
+	case conditionAnd:
+		for _, condition := range c.conditions {
+			if !condition.matches(record) {
+				return false
+			}
+		}
+		return true
+	case conditionOr:
+		for _, condition := range c.conditions {
+			if condition.matches(record) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// required returns the field and tag conditions every matching record meets
+//
+func (c Condition) required() []Condition {
+	switch c.kind {
+	case conditionField, conditionTag:
+		return []Condition{c}
+	case conditionAnd:
+		required := []Condition{}
+		for _, condition := range c.conditions {
+			required = append(required, condition.required()...)
+		}
+		return required
+	case conditionOr:
+		if len(c.conditions) == 1 {
+			return c.conditions[0].required()
+		}
+	}
+
+	return nil
+}
+
+func (record queryRecord) get(fieldName string) json.RawMessage {
+	if value, ok := record[fieldName]; ok {
+		return value
+	}
+
+	for key, value := range record {
+		if strings.EqualFold(key, fieldName) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+// fieldValue returns the value of a field or custom field as text, false if it is not set
+//
+func (record queryRecord) fieldValue(fieldName string) (string, bool) {
+	value := record.get(fieldName)
+
+	if value == nil {
+		customFields := CustomFields{}
+		_ = json.Unmarshal(record.get("CUSTOMFIELDS"), &customFields)
+
+		customFieldRecord := customFields.get(fieldName)
+		if customFieldRecord == nil {
+			return "", false
+		}
+		value = customFieldRecord.FieldValue
+	}
+
+	if len(value) == 0 || string(value) == "null" {
+		return "", false
+	}
+
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		return text, true
+	}
+
+	return string(value), true
+}
+
+func (record queryRecord) updatedAfter(t time.Time) bool {
+	var dateUpdated i_types.DateTimeString
+	err := json.Unmarshal(record.get("DATE_UPDATED_UTC"), &dateUpdated)
+
+	return err == nil && time.Time(dateUpdated).After(t)
+}
+
+// plan returns the request for the criterion sent to the server, and whether
+// UpdatedAfter still has to be applied while paging. A resumed query keeps the
+// criterion of its Cursor.
+//
+func (query *Query) plan(ctx context.Context, service *Service, endpoint string) (*pageConfig, bool, *errortools.Error) {
+	candidates := []*pageConfig{}
+	onTag := []bool{}
+
+	for _, condition := range query.Where.required() {
+		if condition.kind == conditionField {
+			p := newPageConfig(endpoint, nil, nil, nil)
+			p.setSearch(query.UpdatedAfter, &FieldFilter{FieldName: condition.name, FieldValue: condition.value})
+			candidates = append(candidates, p)
+			onTag = append(onTag, false)
+		} else {
+			candidates = append(candidates, byTagPageConfig(endpoint, condition.name))
+			onTag = append(onTag, true)
+		}
+	}
+
+	if len(candidates) == 0 {
+		p := newPageConfig(endpoint, nil, nil, nil)
+		p.setSearch(query.UpdatedAfter, nil)
+		candidates = append(candidates, p)
+		onTag = append(onTag, false)
+	}
+
+	best := 0
+	if query.Cursor != nil {
+		best = slices.IndexFunc(candidates, func(candidate *pageConfig) bool {
+			return candidate.fullEndpoint() == query.Cursor.Endpoint && candidate.query() == query.Cursor.Query
+		})
+		if best < 0 {
+			return nil, false, errortools.ErrorMessagef("Cursor does not belong to this %s query", endpoint)
+		}
+	} else if len(candidates) > 1 {
+		bestCount := int64(math.MaxInt64)
+		for i, candidate := range candidates {
+			count, e := countRows(ctx, service, candidate)
+			if e != nil {
+				return nil, false, e
+			}
+			if count < bestCount {
+				best, bestCount = i, count
+			}
+		}
+	}
+
+	p := candidates[best]
+	p.setCursor(query.Cursor)
+	p.setNextCursor(&query.NextCursor)
+
+	return p, onTag[best] && query.UpdatedAfter != nil, nil
+}
+
+// countRows returns the number of rows of a paged list request, math.MaxInt64 if the
+// server does not report it
+//
+func countRows(ctx context.Context, service *Service, config *pageConfig) (int64, *errortools.Error) {
+	probe := *config
+	probe.params = maps.Clone(config.params)
+	probe.params.Set("count_total", "true")
+	probe.top = 1
+
+	rows := []json.RawMessage{}
+
+	requestConfig := go_http.RequestConfig{
+		Method:        http.MethodGet,
+		Url:           probe.url(service, 0),
+		ResponseModel: &rows,
+	}
+	_, response, e := service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return 0, e
+	}
+
+	count, err := strconv.ParseInt(response.Header.Get("X-Total-Count"), 10, 64)
+	if err != nil {
+		return math.MaxInt64, nil
+	}
+
+	return count, nil
+}
+
+// query returns an iterator over the records that match a Query
+//
+func query[T any](ctx context.Context, service *Service, endpoint string, q *Query) iter.Seq2[T, *errortools.Error] {
+	return func(yield func(T, *errortools.Error) bool) {
+		if q == nil {
+			q = &Query{}
+		}
+
+		var zero T
+
+		p, filterUpdatedAfter, e := q.plan(ctx, service, endpoint)
+		if e != nil {
+			yield(zero, e)
+			return
+		}
+
+		for row, e := range iterate[json.RawMessage](ctx, service, p) {
+			if e != nil {
+				yield(zero, e)
+				return
+			}
+
+			record := queryRecord{}
+			err := json.Unmarshal(row, &record)
+			if err != nil {
+				yield(zero, errortools.ErrorMessage(err))
+				return
+			}
+
+			if filterUpdatedAfter && !record.updatedAfter(*q.UpdatedAfter) {
+				continue
+			}
+			if !q.Where.matches(record) {
+				continue
+			}
+
+			var t T
+			err = json.Unmarshal(row, &t)
+			if err != nil {
+				yield(zero, errortools.ErrorMessagef("Cannot read %s record: %s", endpoint, err.Error()))
+				return
+			}
+
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// querySeq is query with a span that lasts while the records are iterated
+//
+func querySeq[T any](ctx context.Context, service *Service, name string, endpoint string, q *Query) iter.Seq2[T, *errortools.Error] {
+	return func(yield func(T, *errortools.Error) bool) {
+		ctx, span := service.startSpan(ctx, name)
+		defer span.End()
+
+		for row, e := range query[T](ctx, service, endpoint, q) {
+			if !yield(row, e) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package insightly_test
+
+import (
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestQueryTruncatedByMaxRowCount(t *testing.T) {
+	service, server := truncatingTestService(t)
+	vip := []insightly.Tag{{TagName: "vip"}}
+	firstName := "Ann"
+
+	contacts := []any{}
+	for i := 0; i < pageSize+1; i++ {
+		contacts = append(contacts, &insightly.Contact{FirstName: &firstName, Tags: &vip})
+	}
+	contacts = append(contacts, &insightly.Contact{Tags: &vip})
+	server.Seed("Contacts", contacts...)
+
+	query := insightly.Query{Where: insightly.And(insightly.Field("FIRST_NAME", "ann"), insightly.HasTag("vip"))}
+
+	matches, e := service.QueryContacts(&query)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*matches) != pageSize || query.NextCursor == nil {
+		t.Fatalf("got %v contacts and cursor %v, want %v and a cursor", len(*matches), query.NextCursor, pageSize)
+	}
+
+	server.ResetRequests()
+	query.Cursor = query.NextCursor
+
+	matches, e = service.QueryContacts(&query)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*matches) != 1 || query.NextCursor != nil {
+		t.Errorf("got %v contacts and cursor %v, want the last contact only", len(*matches), query.NextCursor)
+	}
+
+	// the resumed query keeps its criterion without counting the candidates again
+	for _, request := range server.Requests() {
+		if request.Query.Has("count_total") {
+			t.Errorf("got probe %s?%s, want none", request.Path, request.Query.Encode())
+		}
+	}
+}
+
+func TestQueryCursorOfOtherQuery(t *testing.T) {
+	service, _ := newTestService(t, nil)
+
+	cursor := insightly.Cursor{Endpoint: "Contacts/SearchByTag", Query: "tagName=other", Skip: 500}
+	_, e := service.QueryContacts(&insightly.Query{Where: insightly.HasTag("vip"), Cursor: &cursor})
+	if e == nil {
+		t.Fatal("expected an error for a cursor of another query")
+	}
+}
+
+func TestSearchByTagTruncatedByMaxRowCount(t *testing.T) {
+	service, server := truncatingTestService(t)
+	seedTaggedContacts(server, pageSize+1, insightly.Tag{TagName: "vip"})
+
+	config := insightly.SearchByTagConfig{}
+
+	contacts, e := service.SearchContactsByTag("vip", &config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*contacts) != pageSize || config.NextCursor == nil {
+		t.Fatalf("got %v contacts and cursor %v, want %v and a cursor", len(*contacts), config.NextCursor, pageSize)
+	}
+
+	config.Cursor = config.NextCursor
+	contacts, e = service.SearchContactsByTag("vip", &config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(*contacts) != 1 || config.NextCursor != nil {
+		t.Errorf("got %v contacts and cursor %v, want the last contact only", len(*contacts), config.NextCursor)
+	}
+}
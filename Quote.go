@@ -1,13 +1,11 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -52,102 +50,304 @@ type Quote struct {
 	CustomFields            *CustomFields           `json:"CUSTOMFIELDS"`
 }
 
+func (q *Quote) prepareMarshal() interface{} {
+	if q == nil {
+		return nil
+	}
+
+	return &struct {
+		QuoteID                 *int64                  `json:"QUOTE_ID,omitempty"`
+		QuoteName               *string                 `json:"QUOTATION_NAME,omitempty"`
+		OpportunityID           *int64                  `json:"OPPORTUNITY_ID,omitempty"`
+		ContactID               *int64                  `json:"CONTACT_ID,omitempty"`
+		OrganisationID          *int64                  `json:"ORGANISATION_ID,omitempty"`
+		QuotationNumber         *string                 `json:"QUOTATION_NUMBER,omitempty"`
+		QuotationDescription    *string                 `json:"QUOTATION_DESCRIPTION,omitempty"`
+		QuotationPhone          *string                 `json:"QUOTATION_PHONE,omitempty"`
+		QuotationEmail          *string                 `json:"QUOTATION_EMAIL,omitempty"`
+		QuotationFax            *string                 `json:"QUOTATION_FAX,omitempty"`
+		QuoteStatus             *string                 `json:"QUOTE_STATUS,omitempty"`
+		QuotationExpirationDate *i_types.DateTimeString `json:"QUOTATION_EXPIRATION_DATE,omitempty"`
+		IsSyncing               *bool                   `json:"IS_SYNCING,omitempty"`
+		QuotationCurrencyCode   *string                 `json:"QUOTATION_CURRENCY_CODE,omitempty"`
+		Discount                *float64                `json:"DISCOUNT,omitempty"`
+		ShappingHandling        *float64                `json:"SHIPPING_HANDLING,omitempty"`
+		Tax                     *float64                `json:"TAX,omitempty"`
+		AddressBillingName      *string                 `json:"ADDRESS_BILLING_NAME,omitempty"`
+		AddressBillingStreet    *string                 `json:"ADDRESS_BILLING_STREET,omitempty"`
+		AddressBillingCity      *string                 `json:"ADDRESS_BILLING_CITY,omitempty"`
+		AddressBillingState     *string                 `json:"ADDRESS_BILLING_STATE,omitempty"`
+		AddressBillingCountry   *string                 `json:"ADDRESS_BILLING_COUNTRY,omitempty"`
+		AddressBillingPostcode  *string                 `json:"ADDRESS_BILLING_POSTCODE,omitempty"`
+		AddressShippingName     *string                 `json:"ADDRESS_SHIPPING_NAME,omitempty"`
+		AddressShippingStreet   *string                 `json:"ADDRESS_SHIPPING_STREET,omitempty"`
+		AddressShippingCity     *string                 `json:"ADDRESS_SHIPPING_CITY,omitempty"`
+		AddressShippingState    *string                 `json:"ADDRESS_SHIPPING_STATE,omitempty"`
+		AddressShippingCountry  *string                 `json:"ADDRESS_SHIPPING_COUNTRY,omitempty"`
+		AddressShippingPostcode *string                 `json:"ADDRESS_SHIPPING_POSTCODE,omitempty"`
+		OwnerUserID             *int64                  `json:"OWNER_USER_ID,omitempty"`
+		CustomFields            *CustomFields           `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(q.QuoteID),
+		&q.QuoteName,
+		q.OpportunityID,
+		q.ContactID,
+		q.OrganisationID,
+		q.QuotationNumber,
+		q.QuotationDescription,
+		q.QuotationPhone,
+		q.QuotationEmail,
+		q.QuotationFax,
+		nonZero(q.QuoteStatus),
+		q.QuotationExpirationDate,
+		&q.IsSyncing,
+		q.QuotationCurrencyCode,
+		q.Discount,
+		q.ShappingHandling,
+		q.Tax,
+		q.AddressBillingName,
+		q.AddressBillingStreet,
+		q.AddressBillingCity,
+		q.AddressBillingState,
+		q.AddressBillingCountry,
+		q.AddressBillingPostcode,
+		q.AddressShippingName,
+		q.AddressShippingStreet,
+		q.AddressShippingCity,
+		q.AddressShippingState,
+		q.AddressShippingCountry,
+		q.AddressShippingPostcode,
+		nonZero(q.OwnerUserID),
+		q.CustomFields,
+	}
+}
+
+var quoteResource = &Resource[Quote]{
+	Endpoint: "Quotation",
+	ID:       func(q *Quote) int64 { return q.QuoteID },
+	Marshal:  func(q *Quote) interface{} { return q.prepareMarshal() },
+}
+
 // GetQuote returns a specific quote
 //
 func (service *Service) GetQuote(quoteID int64) (*Quote, *errortools.Error) {
-	quote := Quote{}
+	return service.GetQuoteWithContext(context.Background(), quoteID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Quotation/%v", quoteID)),
-		ResponseModel: &quote,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetQuoteWithContext is the context-aware variant of GetQuote
+//
+func (service *Service) GetQuoteWithContext(ctx context.Context, quoteID int64) (*Quote, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetQuote")
+	defer span.End()
 
-	return &quote, nil
+	return quoteResource.Get(ctx, service, quoteID)
 }
 
-type GetQuotesConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetQuotesConfig = ListConfig
 
 // GetQuotes returns all quotes
 //
 func (service *Service) GetQuotes(config *GetQuotesConfig) (*[]Quote, *errortools.Error) {
-	params := url.Values{}
+	return service.GetQuotesWithContext(context.Background(), config)
+}
 
-	endpoint := "Quotation"
-	quotes := []Quote{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
+// GetQuotesWithContext is the context-aware variant of GetQuotes
+//
+func (service *Service) GetQuotesWithContext(ctx context.Context, config *GetQuotesConfig) (*[]Quote, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetQuotes")
+	defer span.End()
 
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
+	return quoteResource.List(ctx, service, config)
+}
+
+// GetQuotesPage returns a single page of quotes and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetQuotesPage(config *GetQuotesConfig) (*[]Quote, *Cursor, *errortools.Error) {
+	return service.GetQuotesPageWithContext(context.Background(), config)
+}
+
+// GetQuotesPageWithContext is the context-aware variant of GetQuotesPage
+//
+func (service *Service) GetQuotesPageWithContext(ctx context.Context, config *GetQuotesConfig) (*[]Quote, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetQuotesPage")
+	defer span.End()
+
+	return quoteResource.Page(ctx, service, config)
+}
+
+// QuotesSeq returns an iterator over all quotes, fetching pages on demand
+//
+func (service *Service) QuotesSeq(config *GetQuotesConfig) iter.Seq2[Quote, *errortools.Error] {
+	return service.QuotesSeqWithContext(context.Background(), config)
+}
+
+// QuotesSeqWithContext is the context-aware variant of QuotesSeq
+//
+func (service *Service) QuotesSeqWithContext(ctx context.Context, config *GetQuotesConfig) iter.Seq2[Quote, *errortools.Error] {
+	return seq(ctx, service, "QuotesSeq", quoteResource, config)
+}
+
+// CreateQuote creates a new quote
+//
+func (service *Service) CreateQuote(quote *Quote) (*Quote, *errortools.Error) {
+	return service.CreateQuoteWithContext(context.Background(), quote)
+}
+
+// CreateQuoteWithContext is the context-aware variant of CreateQuote
+//
+func (service *Service) CreateQuoteWithContext(ctx context.Context, quote *Quote) (*Quote, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateQuote")
+	defer span.End()
+
+	return quoteResource.Create(ctx, service, quote)
+}
+
+// UpdateQuote updates an existing quote
+//
+func (service *Service) UpdateQuote(quote *Quote) (*Quote, *errortools.Error) {
+	return service.UpdateQuoteWithContext(context.Background(), quote)
+}
+
+// UpdateQuoteWithContext is the context-aware variant of UpdateQuote
+//
+func (service *Service) UpdateQuoteWithContext(ctx context.Context, quote *Quote) (*Quote, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateQuote")
+	defer span.End()
+
+	return quoteResource.Update(ctx, service, quote)
+}
+
+// DeleteQuote deletes a specific quote
+//
+func (service *Service) DeleteQuote(quoteID int64) *errortools.Error {
+	return service.DeleteQuoteWithContext(context.Background(), quoteID)
+}
+
+// DeleteQuoteWithContext is the context-aware variant of DeleteQuote
+//
+func (service *Service) DeleteQuoteWithContext(ctx context.Context, quoteID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteQuote")
+	defer span.End()
+
+	return quoteResource.Delete(ctx, service, quoteID)
+}
+
+// SetQuoteSyncing turns syncing of a quote with its opportunity on or off, while a quote
+// is syncing Insightly mirrors its line items to the products of the opportunity
+//
+func (service *Service) SetQuoteSyncing(quoteID int64, isSyncing bool) (*Quote, *errortools.Error) {
+	return service.SetQuoteSyncingWithContext(context.Background(), quoteID, isSyncing)
+}
+
+// SetQuoteSyncingWithContext is the context-aware variant of SetQuoteSyncing
+//
+func (service *Service) SetQuoteSyncingWithContext(ctx context.Context, quoteID int64, isSyncing bool) (*Quote, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "SetQuoteSyncing")
+	defer span.End()
+
+	quote, e := quoteResource.Get(ctx, service, quoteID)
+	if e != nil {
+		return nil, e
 	}
 
-	if isSearch {
-		endpoint += "/Search"
+	if quote.IsSyncing == isSyncing {
+		return quote, nil
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	if isSyncing && quote.OpportunityID == nil {
+		return nil, errortools.ErrorMessagef("Quote %v is not linked to an opportunity", quoteID)
+	}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		quotesBatch := []Quote{}
+	quote.IsSyncing = isSyncing
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &quotesBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return quoteResource.Update(ctx, service, quote)
+}
+
+// CreateQuoteFromOpportunity creates a quote for an opportunity with a line item for each
+// of its products, using the same pricebook entries, quantities, prices and discounts.
+// The fields set in quote are used for the new quote, QuoteName, OrganisationID and
+// QuotationCurrencyCode default to those of the opportunity. If a line item cannot be
+// created the quote is deleted again. Nothing is created if ServiceConfig.MaxRowCount
+// truncates the read of the products of the opportunity.
+//
+func (service *Service) CreateQuoteFromOpportunity(opportunityID int64, quote *Quote) (*Quote, *[]QuoteProduct, *errortools.Error) {
+	return service.CreateQuoteFromOpportunityWithContext(context.Background(), opportunityID, quote)
+}
+
+// CreateQuoteFromOpportunityWithContext is the context-aware variant of CreateQuoteFromOpportunity
+//
+func (service *Service) CreateQuoteFromOpportunityWithContext(ctx context.Context, opportunityID int64, quote *Quote) (*Quote, *[]QuoteProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateQuoteFromOpportunity")
+	defer span.End()
 
-		quotes = append(quotes, quotesBatch...)
+	opportunity, e := opportunityResource.Get(ctx, service, opportunityID)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	opportunityProducts, e := opportunityProductResource.listAll(ctx, service, &ListConfig{
+		FieldFilter: &FieldFilter{
+			FieldName:  "OPPORTUNITY_ID",
+			FieldValue: fmt.Sprintf("%v", opportunityID),
+		},
+	})
+	if e != nil {
+		return nil, nil, e
+	}
 
-		if len(quotesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
+	for _, opportunityProduct := range *opportunityProducts {
+		if opportunityProduct.PricebookEntryID == 0 {
+			return nil, nil, errortools.ErrorMessagef("Opportunity product %v has no pricebook entry", opportunityProduct.OpportunityItemID)
 		}
+	}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	newQuote := Quote{}
+	if quote != nil {
+		newQuote = *quote
+	}
+	newQuote.QuoteID = 0
+	newQuote.OpportunityID = &opportunityID
+	if newQuote.QuoteName == "" && opportunity.OpportunityName != nil {
+		newQuote.QuoteName = *opportunity.OpportunityName
+	}
+	if newQuote.OrganisationID == nil {
+		newQuote.OrganisationID = opportunity.OrganisationID
+	}
+	if newQuote.QuotationCurrencyCode == nil {
+		newQuote.QuotationCurrencyCode = opportunity.BidCurrency
+	}
 
-		if rowCount >= service.maxRowCount {
-			return &quotes, nil
+	createdQuote, e := quoteResource.Create(ctx, service, &newQuote)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	quoteProducts := []QuoteProduct{}
+
+	for i, opportunityProduct := range *opportunityProducts {
+		quoteProduct, e := quoteProductResource.Create(ctx, service, &QuoteProduct{
+			QuoteID:           createdQuote.QuoteID,
+			OpportunityItemID: opportunityProduct.OpportunityItemID,
+			PricebookEntryID:  opportunityProduct.PricebookEntryID,
+			Description:       opportunityProduct.Description,
+			CurrencyCode:      opportunityProduct.CurrencyCode,
+			Quantity:          opportunityProduct.Quantity,
+			ListPrice:         opportunityProduct.ListPrice,
+			UnitPrice:         opportunityProduct.UnitPrice,
+			Discount:          opportunityProduct.Discount,
+			SortOrder:         int64(i + 1),
+			CustomFields:      opportunityProduct.CustomFields,
+		})
+		if e != nil {
+			eDelete := quoteResource.Delete(ctx, service, createdQuote.QuoteID)
+			if eDelete != nil {
+				e.SetMessagef("%s (quote %v could not be deleted: %s)", e.Message(), createdQuote.QuoteID, eDelete.Message())
+			}
+			return nil, nil, e
 		}
+
+		quoteProducts = append(quoteProducts, *quoteProduct)
 	}
 
-	return &quotes, nil
+	return createdQuote, &quoteProducts, nil
 }
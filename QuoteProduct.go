@@ -1,13 +1,13 @@
 package insightly
 
 import (
+	"cmp"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"iter"
+	"slices"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -15,6 +15,7 @@ import (
 //
 type QuoteProduct struct {
 	QuotationItemID   int64                  `json:"QUOTATION_ITEM_ID"`
+	QuoteID           int64                  `json:"QUOTE_ID"`
 	OpportunityItemID int64                  `json:"OPPORTUNITY_ITEM_ID"`
 	PricebookEntryID  int64                  `json:"PRICEBOOK_ENTRY_ID"`
 	Description       string                 `json:"DESCRIPTION"`
@@ -31,86 +32,210 @@ type QuoteProduct struct {
 	CustomFields      *CustomFields          `json:"CUSTOMFIELDS"`
 }
 
-type GetQuoteProductsConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
+func (q *QuoteProduct) prepareMarshal() interface{} {
+	if q == nil {
+		return nil
+	}
+
+	return &struct {
+		QuotationItemID   *int64        `json:"QUOTATION_ITEM_ID,omitempty"`
+		QuoteID           *int64        `json:"QUOTE_ID,omitempty"`
+		OpportunityItemID *int64        `json:"OPPORTUNITY_ITEM_ID,omitempty"`
+		PricebookEntryID  *int64        `json:"PRICEBOOK_ENTRY_ID,omitempty"`
+		Description       *string       `json:"DESCRIPTION,omitempty"`
+		CurrencyCode      *string       `json:"CURRENCY_CODE,omitempty"`
+		Quantity          *int64        `json:"QUANTITY,omitempty"`
+		ListPrice         *float64      `json:"LIST_PRICE,omitempty"`
+		UnitPrice         *float64      `json:"UNIT_PRICE,omitempty"`
+		Discount          *float64      `json:"DISCOUNT,omitempty"`
+		SortOrder         *int64        `json:"SORT_ORDER,omitempty"`
+		CustomFields      *CustomFields `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(q.QuotationItemID),
+		nonZero(q.QuoteID),
+		nonZero(q.OpportunityItemID),
+		nonZero(q.PricebookEntryID),
+		nonZero(q.Description),
+		nonZero(q.CurrencyCode),
+		&q.Quantity,
+		nonZero(q.ListPrice),
+		&q.UnitPrice,
+		&q.Discount,
+		nonZero(q.SortOrder),
+		q.CustomFields,
+	}
+}
+
+var quoteProductResource = &Resource[QuoteProduct]{
+	Endpoint: "QuotationLineItem",
+	ID:       func(q *QuoteProduct) int64 { return q.QuotationItemID },
+	Marshal:  func(q *QuoteProduct) interface{} { return q.prepareMarshal() },
 }
 
+type GetQuoteProductsConfig = ListConfig
+
 // GetQuoteProducts returns all quoteProducts
 //
 func (service *Service) GetQuoteProducts(config *GetQuoteProductsConfig) (*[]QuoteProduct, *errortools.Error) {
-	params := url.Values{}
+	return service.GetQuoteProductsWithContext(context.Background(), config)
+}
 
-	endpoint := "QuotationLineItem"
-	quoteProducts := []QuoteProduct{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
+// GetQuoteProductsWithContext is the context-aware variant of GetQuoteProducts
+//
+func (service *Service) GetQuoteProductsWithContext(ctx context.Context, config *GetQuoteProductsConfig) (*[]QuoteProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetQuoteProducts")
+	defer span.End()
 
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return quoteProductResource.List(ctx, service, config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
+// GetQuoteProductsPage returns a single page of quoteProducts and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetQuoteProductsPage(config *GetQuoteProductsConfig) (*[]QuoteProduct, *Cursor, *errortools.Error) {
+	return service.GetQuoteProductsPageWithContext(context.Background(), config)
+}
+
+// GetQuoteProductsPageWithContext is the context-aware variant of GetQuoteProductsPage
+//
+func (service *Service) GetQuoteProductsPageWithContext(ctx context.Context, config *GetQuoteProductsConfig) (*[]QuoteProduct, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetQuoteProductsPage")
+	defer span.End()
+
+	return quoteProductResource.Page(ctx, service, config)
+}
+
+// QuoteProductsSeq returns an iterator over all quoteProducts, fetching pages on demand
+//
+func (service *Service) QuoteProductsSeq(config *GetQuoteProductsConfig) iter.Seq2[QuoteProduct, *errortools.Error] {
+	return service.QuoteProductsSeqWithContext(context.Background(), config)
+}
+
+// QuoteProductsSeqWithContext is the context-aware variant of QuoteProductsSeq
+//
+func (service *Service) QuoteProductsSeqWithContext(ctx context.Context, config *GetQuoteProductsConfig) iter.Seq2[QuoteProduct, *errortools.Error] {
+	return seq(ctx, service, "QuoteProductsSeq", quoteProductResource, config)
+}
+
+// CreateQuoteProduct adds a line item to a quote
+//
+func (service *Service) CreateQuoteProduct(quoteProduct *QuoteProduct) (*QuoteProduct, *errortools.Error) {
+	return service.CreateQuoteProductWithContext(context.Background(), quoteProduct)
+}
+
+// CreateQuoteProductWithContext is the context-aware variant of CreateQuoteProduct
+//
+func (service *Service) CreateQuoteProductWithContext(ctx context.Context, quoteProduct *QuoteProduct) (*QuoteProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateQuoteProduct")
+	defer span.End()
+
+	if quoteProduct != nil && quoteProduct.QuoteID == 0 {
+		return nil, errortools.ErrorMessage("Cannot create quote product without quote id")
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return quoteProductResource.Create(ctx, service, quoteProduct)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+// UpdateQuoteProduct updates an existing line item of a quote
+//
+func (service *Service) UpdateQuoteProduct(quoteProduct *QuoteProduct) (*QuoteProduct, *errortools.Error) {
+	return service.UpdateQuoteProductWithContext(context.Background(), quoteProduct)
+}
 
-		quoteProductsBatch := []QuoteProduct{}
+// UpdateQuoteProductWithContext is the context-aware variant of UpdateQuoteProduct
+//
+func (service *Service) UpdateQuoteProductWithContext(ctx context.Context, quoteProduct *QuoteProduct) (*QuoteProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateQuoteProduct")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &quoteProductsBatch,
-		}
+	return quoteProductResource.Update(ctx, service, quoteProduct)
+}
+
+// DeleteQuoteProduct removes a line item from a quote
+//
+func (service *Service) DeleteQuoteProduct(quotationItemID int64) *errortools.Error {
+	return service.DeleteQuoteProductWithContext(context.Background(), quotationItemID)
+}
+
+// DeleteQuoteProductWithContext is the context-aware variant of DeleteQuoteProduct
+//
+func (service *Service) DeleteQuoteProductWithContext(ctx context.Context, quotationItemID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteQuoteProduct")
+	defer span.End()
+
+	return quoteProductResource.Delete(ctx, service, quotationItemID)
+}
+
+// ReorderQuoteProducts sets the SortOrder of the line items of a quote to the order of
+// quotationItemIDs, line items that are not listed keep their relative order after the
+// listed ones. Only line items whose SortOrder changes are updated. It returns all line
+// items of the quote in their new order, nothing is updated if ServiceConfig.MaxRowCount
+// truncates the read of the line items.
+//
+func (service *Service) ReorderQuoteProducts(quoteID int64, quotationItemIDs []int64) (*[]QuoteProduct, *errortools.Error) {
+	return service.ReorderQuoteProductsWithContext(context.Background(), quoteID, quotationItemIDs)
+}
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
+// ReorderQuoteProductsWithContext is the context-aware variant of ReorderQuoteProducts
+//
+func (service *Service) ReorderQuoteProductsWithContext(ctx context.Context, quoteID int64, quotationItemIDs []int64) (*[]QuoteProduct, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "ReorderQuoteProducts")
+	defer span.End()
+
+	quoteProducts, e := quoteProductResource.listAll(ctx, service, &ListConfig{
+		FieldFilter: &FieldFilter{
+			FieldName:  "QUOTE_ID",
+			FieldValue: fmt.Sprintf("%v", quoteID),
+		},
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	position := make(map[int64]int, len(quotationItemIDs))
+	for i, quotationItemID := range quotationItemIDs {
+		if _, ok := position[quotationItemID]; ok {
+			return nil, errortools.ErrorMessagef("Quote product %v is listed more than once", quotationItemID)
 		}
+		position[quotationItemID] = i
+	}
 
-		quoteProducts = append(quoteProducts, quoteProductsBatch...)
+	listed := make([]*QuoteProduct, len(quotationItemIDs))
+	unlisted := []*QuoteProduct{}
 
-		if len(quoteProductsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
+	for i := range *quoteProducts {
+		quoteProduct := &(*quoteProducts)[i]
+		if p, ok := position[quoteProduct.QuotationItemID]; ok {
+			listed[p] = quoteProduct
+		} else {
+			unlisted = append(unlisted, quoteProduct)
 		}
+	}
+
+	for i, quoteProduct := range listed {
+		if quoteProduct == nil {
+			return nil, errortools.ErrorMessagef("Quote product %v does not belong to quote %v", quotationItemIDs[i], quoteID)
+		}
+	}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	slices.SortStableFunc(unlisted, func(a, b *QuoteProduct) int {
+		return cmp.Compare(a.SortOrder, b.SortOrder)
+	})
 
-		if rowCount >= service.maxRowCount {
-			return &quoteProducts, nil
+	reordered := []QuoteProduct{}
+
+	for i, quoteProduct := range append(listed, unlisted...) {
+		sortOrder := int64(i + 1)
+		if quoteProduct.SortOrder != sortOrder {
+			quoteProduct.SortOrder = sortOrder
+
+			quoteProduct, e = quoteProductResource.Update(ctx, service, quoteProduct)
+			if e != nil {
+				return nil, e
+			}
 		}
+
+		reordered = append(reordered, *quoteProduct)
 	}
 
-	return &quoteProducts, nil
+	return &reordered, nil
 }
@@ -0,0 +1,255 @@
+package insightly_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// rejectTransport answers the requests that reject matches with 400 instead of sending them
+type rejectTransport struct {
+	reject func(request *http.Request) bool
+}
+
+func (t *rejectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if t.reject(request) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"Message":"Rejected"}`)),
+			Request:    request,
+		}, nil
+	}
+
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+// requestCount returns the number of requests made with method to a path ending in suffix
+func requestCount(server *insightlytest.Server, method string, suffix string) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Method == method && strings.HasSuffix(request.Path, suffix) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// seedOpportunityProducts seeds count line items of an opportunity
+func seedOpportunityProducts(server *insightlytest.Server, opportunityID int64, count int) {
+	items := []any{}
+	for i := 0; i < count; i++ {
+		items = append(items, map[string]any{
+			"OPPORTUNITY_ID":     opportunityID,
+			"PRICEBOOK_ENTRY_ID": 10 + i,
+			"CURRENCY_CODE":      "EUR",
+			"QUANTITY":           i + 1,
+			"LIST_PRICE":         100,
+			"UNIT_PRICE":         90,
+			"DISCOUNT":           10,
+		})
+	}
+	server.Seed("OpportunityLineItem", items...)
+}
+
+// seedQuoteProducts seeds line items of a quote with the sort orders given
+func seedQuoteProducts(server *insightlytest.Server, quoteID int64, sortOrders ...int64) []int64 {
+	items := []any{}
+	for _, sortOrder := range sortOrders {
+		items = append(items, map[string]any{"QUOTE_ID": quoteID, "SORT_ORDER": sortOrder})
+	}
+
+	return server.Seed("QuotationLineItem", items...)
+}
+
+func TestCreateQuoteFromOpportunity(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	name := "Website"
+	currency := "EUR"
+	organisationID := int64(7)
+	opportunityID := server.Seed("Opportunities", &insightly.Opportunity{OpportunityName: &name, BidCurrency: &currency, OrganisationID: &organisationID})[0]
+	otherID := server.Seed("Opportunities", &insightly.Opportunity{})[0]
+	seedOpportunityProducts(server, opportunityID, 2)
+	seedOpportunityProducts(server, otherID, 1)
+
+	quote, quoteProducts, e := service.CreateQuoteFromOpportunity(opportunityID, nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if quote.QuoteName != name || quote.OpportunityID == nil || *quote.OpportunityID != opportunityID {
+		t.Errorf("got quote %q of opportunity %v, want %q of %v", quote.QuoteName, quote.OpportunityID, name, opportunityID)
+	}
+	if quote.OrganisationID == nil || *quote.OrganisationID != organisationID || quote.QuotationCurrencyCode == nil || *quote.QuotationCurrencyCode != currency {
+		t.Error("expected the organisation and currency of the opportunity")
+	}
+
+	if len(*quoteProducts) != 2 {
+		t.Fatalf("got %v quote products, want 2", len(*quoteProducts))
+	}
+	for i, quoteProduct := range *quoteProducts {
+		if quoteProduct.QuoteID != quote.QuoteID || quoteProduct.SortOrder != int64(i+1) {
+			t.Errorf("got quote product of quote %v at %v, want quote %v at %v", quoteProduct.QuoteID, quoteProduct.SortOrder, quote.QuoteID, i+1)
+		}
+		if quoteProduct.PricebookEntryID != int64(10+i) || quoteProduct.Quantity != int64(i+1) || quoteProduct.UnitPrice != 90 || quoteProduct.Discount != 10 {
+			t.Errorf("got quote product %+v, want the pricing of the opportunity product", quoteProduct)
+		}
+	}
+}
+
+func TestCreateQuoteFromOpportunityDeletesQuoteOnFailure(t *testing.T) {
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.Transport = &rejectTransport{reject: func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/QuotationLineItem")
+		}}
+	})
+	opportunityID := server.Seed("Opportunities", &insightly.Opportunity{})[0]
+	seedOpportunityProducts(server, opportunityID, 1)
+
+	_, _, e := service.CreateQuoteFromOpportunity(opportunityID, &insightly.Quote{QuoteName: "Offer"})
+	if e == nil {
+		t.Fatal("expected an error for a rejected line item")
+	}
+
+	if count := server.Count("Quotation"); count != 0 {
+		t.Errorf("got %v quotes, want the quote deleted again", count)
+	}
+}
+
+func TestCreateQuoteFromOpportunityTruncated(t *testing.T) {
+	service, server := truncatingTestService(t)
+	opportunityID := server.Seed("Opportunities", &insightly.Opportunity{})[0]
+	seedOpportunityProducts(server, opportunityID, pageSize+1)
+
+	_, _, e := service.CreateQuoteFromOpportunity(opportunityID, &insightly.Quote{QuoteName: "Offer"})
+	if e == nil {
+		t.Fatal("expected an error for truncated opportunity products")
+	}
+
+	if count := requestCount(server, http.MethodPost, ""); count != 0 {
+		t.Errorf("got %v creates, want none", count)
+	}
+}
+
+func TestReorderQuoteProducts(t *testing.T) {
+	service, server := newTestService(t, nil)
+	quoteID := server.Seed("Quotation", &insightly.Quote{QuoteName: "Offer"})[0]
+	ids := seedQuoteProducts(server, quoteID, 1, 2, 3)
+	seedQuoteProducts(server, quoteID+1, 1)
+
+	reordered, e := service.ReorderQuoteProducts(quoteID, []int64{ids[0], ids[2]})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	want := []int64{ids[0], ids[2], ids[1]}
+	if len(*reordered) != len(want) {
+		t.Fatalf("got %v quote products, want %v", len(*reordered), len(want))
+	}
+	for i, quoteProduct := range *reordered {
+		if quoteProduct.QuotationItemID != want[i] || quoteProduct.SortOrder != int64(i+1) {
+			t.Errorf("got quote product %v at %v, want %v at %v", quoteProduct.QuotationItemID, quoteProduct.SortOrder, want[i], i+1)
+		}
+	}
+
+	// the first line item keeps its sort order
+	if count := requestCount(server, http.MethodPut, "/QuotationLineItem"); count != 2 {
+		t.Errorf("got %v updates, want 2", count)
+	}
+}
+
+func TestReorderQuoteProductsInvalid(t *testing.T) {
+	service, server := newTestService(t, nil)
+	quoteID := server.Seed("Quotation", &insightly.Quote{QuoteName: "Offer"})[0]
+	ids := seedQuoteProducts(server, quoteID, 1, 2)
+	otherIDs := seedQuoteProducts(server, quoteID+1, 1)
+
+	_, e := service.ReorderQuoteProducts(quoteID, []int64{ids[1], ids[1]})
+	if e == nil {
+		t.Error("expected an error for a line item listed twice")
+	}
+	_, e = service.ReorderQuoteProducts(quoteID, []int64{otherIDs[0]})
+	if e == nil {
+		t.Error("expected an error for a line item of another quote")
+	}
+
+	if count := requestCount(server, http.MethodPut, ""); count != 0 {
+		t.Errorf("got %v updates, want none", count)
+	}
+}
+
+func TestReorderQuoteProductsTruncated(t *testing.T) {
+	service, server := truncatingTestService(t)
+	quoteID := server.Seed("Quotation", &insightly.Quote{QuoteName: "Offer"})[0]
+	sortOrders := make([]int64, pageSize+1)
+	for i := range sortOrders {
+		sortOrders[i] = int64(len(sortOrders) - i)
+	}
+	seedQuoteProducts(server, quoteID, sortOrders...)
+
+	_, e := service.ReorderQuoteProducts(quoteID, nil)
+	if e == nil {
+		t.Fatal("expected an error for truncated quote products")
+	}
+
+	if count := requestCount(server, http.MethodPut, ""); count != 0 {
+		t.Errorf("got %v updates, want none", count)
+	}
+}
+
+func TestSetQuoteSyncing(t *testing.T) {
+	service, server := newTestService(t, nil)
+	opportunityID := int64(3)
+	quoteID := server.Seed("Quotation", &insightly.Quote{QuoteName: "Offer", OpportunityID: &opportunityID})[0]
+	unlinkedID := server.Seed("Quotation", &insightly.Quote{QuoteName: "Draft"})[0]
+
+	quote, e := service.SetQuoteSyncing(quoteID, true)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if !quote.IsSyncing {
+		t.Error("expected the quote to be syncing")
+	}
+
+	_, e = service.SetQuoteSyncing(quoteID, true)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if count := requestCount(server, http.MethodPut, "/Quotation"); count != 1 {
+		t.Errorf("got %v updates, want none for a quote that is syncing already", count-1)
+	}
+
+	requests := server.Requests()
+	body := map[string]any{}
+	for _, request := range requests {
+		if request.Method == http.MethodPut {
+			err := json.Unmarshal(request.Body, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if body["IS_SYNCING"] != true {
+		t.Errorf("got IS_SYNCING %v, want true", body["IS_SYNCING"])
+	}
+
+	_, e = service.SetQuoteSyncing(unlinkedID, true)
+	if e == nil {
+		t.Error("expected an error for a quote without opportunity")
+	}
+
+	quote, e = service.SetQuoteSyncing(quoteID, false)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if quote.IsSyncing {
+		t.Error("expected the quote to stop syncing")
+	}
+}
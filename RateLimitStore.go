@@ -0,0 +1,152 @@
+package insightly
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+)
+
+// RateLimitStore holds the RateLimit of an api key so it can be shared by multiple
+// Services, possibly in different processes. The Service loads it before every request
+// and saves it after every response that carries rate limit headers.
+//
+type RateLimitStore interface {
+	Load(ctx context.Context) (RateLimit, *errortools.Error)
+	Save(ctx context.Context, rateLimit RateLimit) *errortools.Error
+}
+
+// MemoryRateLimitStore shares the RateLimit between Services in the same process
+//
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	rateLimit RateLimit
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{}
+}
+
+func (store *MemoryRateLimitStore) Load(ctx context.Context) (RateLimit, *errortools.Error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.rateLimit, nil
+}
+
+func (store *MemoryRateLimitStore) Save(ctx context.Context, rateLimit RateLimit) *errortools.Error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.rateLimit = rateLimit
+
+	return nil
+}
+
+// FileRateLimitStore shares the RateLimit between processes on the same host through
+// a local file, access is coordinated with flock. It is not supported on platforms
+// without flock, such as Windows.
+//
+type FileRateLimitStore struct {
+	path string
+}
+
+// fileRateLimit is the json content of the file of a FileRateLimitStore
+//
+type fileRateLimit struct {
+	Limit     *int64     `json:"limit,omitempty"`
+	Remaining *int64     `json:"remaining,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+}
+
+// NewFileRateLimitStore returns a FileRateLimitStore using the file at path, which is
+// created on first use
+//
+func NewFileRateLimitStore(path string) *FileRateLimitStore {
+	return &FileRateLimitStore{path: path}
+}
+
+func (store *FileRateLimitStore) Load(ctx context.Context) (RateLimit, *errortools.Error) {
+	file, e := store.open(ctx, false)
+	if e != nil {
+		return RateLimit{}, e
+	}
+	defer unlockAndClose(file)
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return RateLimit{}, errortools.ErrorMessage(err)
+	}
+
+	if len(b) == 0 {
+		return RateLimit{}, nil
+	}
+
+	r := fileRateLimit{}
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return RateLimit{}, errortools.ErrorMessagef("Invalid rate limit file %s: %s", store.path, err.Error())
+	}
+
+	return RateLimit{
+		Limit:     r.Limit,
+		Remaining: r.Remaining,
+		RetryAt:   r.RetryAt,
+	}, nil
+}
+
+func (store *FileRateLimitStore) Save(ctx context.Context, rateLimit RateLimit) *errortools.Error {
+	b, err := json.Marshal(fileRateLimit{
+		Limit:     rateLimit.Limit,
+		Remaining: rateLimit.Remaining,
+		RetryAt:   rateLimit.RetryAt,
+	})
+	if err != nil {
+		return errortools.ErrorMessage(err)
+	}
+
+	file, e := store.open(ctx, true)
+	if e != nil {
+		return e
+	}
+	defer unlockAndClose(file)
+
+	err = file.Truncate(0)
+	if err != nil {
+		return errortools.ErrorMessage(err)
+	}
+
+	_, err = file.WriteAt(b, 0)
+	if err != nil {
+		return errortools.ErrorMessage(err)
+	}
+
+	return nil
+}
+
+// open opens and locks the file, exclusively if it is to be written, waiting for a
+// lock held by another Service until ctx is done
+//
+func (store *FileRateLimitStore) open(ctx context.Context, exclusive bool) (*os.File, *errortools.Error) {
+	file, err := os.OpenFile(store.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	err = lockFile(ctx, file, exclusive)
+	if err != nil {
+		file.Close()
+		return nil, errortools.ErrorMessagef("Cannot lock rate limit file %s: %s", store.path, err.Error())
+	}
+
+	return file, nil
+}
+
+func unlockAndClose(file *os.File) {
+	_ = unlockFile(file)
+	_ = file.Close()
+}
@@ -0,0 +1,75 @@
+package insightly_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestRateLimitExhaustedWithoutRetryAfter(t *testing.T) {
+	store := insightly.NewFileRateLimitStore(filepath.Join(t.TempDir(), "ratelimit.json"))
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.RateLimitStore = store
+	})
+	// the limit resets immediately, but the response that exhausts it has no Retry-After
+	server.SetRateLimit(10, 1, 0)
+	server.OmitRetryAfter(true)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	rateLimit, e := store.Load(context.Background())
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if rateLimit.Remaining == nil || *rateLimit.Remaining != 0 || rateLimit.RetryAt != nil {
+		t.Fatalf("expected the store to hold an exhausted rate limit without RetryAt")
+	}
+
+	other, e := insightly.NewService(&insightly.ServiceConfig{
+		ApiKey:         server.ServiceConfig().ApiKey,
+		BaseUrl:        server.ServiceConfig().BaseUrl,
+		RetryPolicy:    &insightly.RetryPolicy{},
+		RateLimitStore: store,
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	for _, s := range []*insightly.Service{service, other} {
+		_, e = s.GetContacts(nil)
+		if e != nil {
+			t.Fatalf("expected the request to be sent, got %s", e.Message())
+		}
+	}
+
+	if len(server.Requests()) != 3 {
+		t.Errorf("got %v requests, want 3", len(server.Requests()))
+	}
+}
+
+func TestRateLimitExhaustedWaitsUntilRetryAt(t *testing.T) {
+	service, server := newTestService(t, nil)
+	server.SetRateLimit(10, 1, time.Second)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	start := time.Now()
+	_, e = service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if time.Since(start) < 500*time.Millisecond {
+		t.Errorf("expected the second request to wait for the rate limit to reset")
+	}
+}
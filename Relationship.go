@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // Relationship stores Relationship from Service
@@ -21,66 +19,51 @@ type Relationship struct {
 	ForOrganisations bool   `json:"FOR_ORGANISATIONS"`
 }
 
-type GetRelationshipsConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var relationshipResource = &Resource[Relationship]{
+	Endpoint: "Relationships",
+	ID:       func(r *Relationship) int64 { return r.RelationshipID },
 }
 
+type GetRelationshipsConfig = PagingConfig
+
 // GetRelationships returns all relationships
 //
 func (service *Service) GetRelationships(config *GetRelationshipsConfig) (*[]Relationship, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Relationships"
-	relationships := []Relationship{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		relationshipsBatch := []Relationship{}
+	return service.GetRelationshipsWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &relationshipsBatch,
-		}
+// GetRelationshipsWithContext is the context-aware variant of GetRelationships
+//
+func (service *Service) GetRelationshipsWithContext(ctx context.Context, config *GetRelationshipsConfig) (*[]Relationship, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetRelationships")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return relationshipResource.List(ctx, service, config)
+}
 
-		relationships = append(relationships, relationshipsBatch...)
+// GetRelationshipsPage returns a single page of relationships and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetRelationshipsPage(config *GetRelationshipsConfig) (*[]Relationship, *Cursor, *errortools.Error) {
+	return service.GetRelationshipsPageWithContext(context.Background(), config)
+}
 
-		if len(relationshipsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetRelationshipsPageWithContext is the context-aware variant of GetRelationshipsPage
+//
+func (service *Service) GetRelationshipsPageWithContext(ctx context.Context, config *GetRelationshipsConfig) (*[]Relationship, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetRelationshipsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return relationshipResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &relationships, nil
-		}
-	}
+// RelationshipsSeq returns an iterator over all relationships, fetching pages on demand
+//
+func (service *Service) RelationshipsSeq(config *GetRelationshipsConfig) iter.Seq2[Relationship, *errortools.Error] {
+	return service.RelationshipsSeqWithContext(context.Background(), config)
+}
 
-	return &relationships, nil
+// RelationshipsSeqWithContext is the context-aware variant of RelationshipsSeq
+//
+func (service *Service) RelationshipsSeqWithContext(ctx context.Context, config *GetRelationshipsConfig) iter.Seq2[Relationship, *errortools.Error] {
+	return seq(ctx, service, "RelationshipsSeq", relationshipResource, config)
 }
@@ -0,0 +1,268 @@
+package insightly
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	go_http "github.com/leapforce-libraries/go_http"
+)
+
+// Resource describes an Insightly entity endpoint, reading and writing a new entity
+// takes no more than declaring its Resource, e.g.
+//
+//	var contactResource = &Resource[Contact]{
+//		Endpoint: "Contacts",
+//		ID:       func(contact *Contact) int64 { return contact.ContactID },
+//	}
+//
+// The methods of Resource do not start a span of their own, the requests they make
+// are traced as children of the span in ctx.
+//
+type Resource[T any] struct {
+	// Endpoint is the path of the entity relative to the api url, e.g. "Contacts"
+	Endpoint string
+	// ID returns the id of a record, Update requires it to be set
+	ID func(record *T) int64
+	// Marshal returns the request body of Create and Update, the record itself if nil
+	Marshal func(record *T) interface{}
+}
+
+// ListOptions selects the records returned by Resource.List, Resource.Page and Resource.Seq,
+// it is implemented by *ListConfig, *PagingConfig and the list configs of endpoints with
+// options of their own, e.g. *GetLeadStatusesConfig
+//
+type ListOptions interface {
+	pageConfig(endpoint string) *pageConfig
+}
+
+// ListConfig selects the records of an endpoint that supports brief records and search,
+// the list configs of such entities are aliases of it, e.g. GetContactsConfig
+//
+type ListConfig struct {
+	Skip         *uint64
+	Top          *uint64
+	Cursor       *Cursor
+	NextCursor   *Cursor
+	Brief        *bool
+	CountTotal   *bool
+	UpdatedAfter *time.Time
+	FieldFilter  *FieldFilter
+}
+
+func (config *ListConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return newPageConfig(endpoint, nil, nil, nil)
+	}
+
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.setBrief(config.Brief)
+	p.setSearch(config.UpdatedAfter, config.FieldFilter)
+
+	return p
+}
+
+// PagingConfig selects the records of an endpoint that only supports paging, the list
+// configs of such entities are aliases of it, e.g. GetPipelinesConfig
+//
+type PagingConfig struct {
+	Skip       *uint64
+	Top        *uint64
+	Cursor     *Cursor
+	NextCursor *Cursor
+	CountTotal *bool
+}
+
+func (config *PagingConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return newPageConfig(endpoint, nil, nil, nil)
+	}
+
+	return newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+}
+
+// Get returns a specific record
+//
+func (r *Resource[T]) Get(ctx context.Context, service *Service, id int64) (*T, *errortools.Error) {
+	record := new(T)
+
+	requestConfig := go_http.RequestConfig{
+		Method:        http.MethodGet,
+		Url:           service.url(fmt.Sprintf("%s/%v", r.Endpoint, id)),
+		ResponseModel: record,
+	}
+	_, _, e := service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return nil, e
+	}
+
+	return record, nil
+}
+
+// List returns all records, limited by the service's maxRowCount
+//
+func (r *Resource[T]) List(ctx context.Context, service *Service, options ListOptions) (*[]T, *errortools.Error) {
+	return list[T](ctx, service, r.pageConfig(options))
+}
+
+// Page returns a single page of records and the Cursor of the next page, which is nil after the last page
+//
+func (r *Resource[T]) Page(ctx context.Context, service *Service, options ListOptions) (*[]T, *Cursor, *errortools.Error) {
+	return page[T](ctx, service, r.pageConfig(options))
+}
+
+// Seq returns an iterator over all records, fetching pages on demand
+//
+func (r *Resource[T]) Seq(ctx context.Context, service *Service, options ListOptions) iter.Seq2[T, *errortools.Error] {
+	return iterate[T](ctx, service, r.pageConfig(options))
+}
+
+// listAll returns all records like List, or an error if the read is truncated by the
+// service's maxRowCount, for operations that act on the complete set of records
+//
+func (r *Resource[T]) listAll(ctx context.Context, service *Service, options ListOptions) (*[]T, *errortools.Error) {
+	p := r.pageConfig(options)
+	if p == nil {
+		return nil, nil
+	}
+
+	var next *Cursor
+	p.setNextCursor(&next)
+
+	rows, e := list[T](ctx, service, p)
+	if e != nil {
+		return nil, e
+	}
+
+	if next != nil {
+		return nil, errortools.ErrorMessagef("Cannot read all %s records, the read is truncated by ServiceConfig.MaxRowCount", r.Endpoint)
+	}
+
+	return rows, nil
+}
+
+func (r *Resource[T]) pageConfig(options ListOptions) *pageConfig {
+	if options == nil {
+		return newPageConfig(r.Endpoint, nil, nil, nil)
+	}
+
+	return options.pageConfig(r.Endpoint)
+}
+
+// seq returns an iterator over all records of r like Resource.Seq, the span of the
+// logical call name is started when iteration starts
+//
+func seq[T any](ctx context.Context, service *Service, name string, r *Resource[T], options ListOptions) iter.Seq2[T, *errortools.Error] {
+	return func(yield func(T, *errortools.Error) bool) {
+		ctx, span := service.startSpan(ctx, name)
+		defer span.End()
+
+		for row, e := range r.Seq(ctx, service, options) {
+			if !yield(row, e) {
+				return
+			}
+		}
+	}
+}
+
+// ListByTag returns the records that have a specific tag, limited by the service's maxRowCount
+//
+func (r *Resource[T]) ListByTag(ctx context.Context, service *Service, tagName string, config *SearchByTagConfig) (*[]T, *errortools.Error) {
+	return list[T](ctx, service, config.pageConfig(r.Endpoint, tagName))
+}
+
+// Query returns the records that match a Query, limited by the service's maxRowCount
+// applied to the records read, a truncated query sets q.NextCursor
+//
+func (r *Resource[T]) Query(ctx context.Context, service *Service, q *Query) (*[]T, *errortools.Error) {
+	rows := []T{}
+
+	for row, e := range query[T](ctx, service, r.Endpoint, q) {
+		if e != nil {
+			return nil, e
+		}
+
+		rows = append(rows, row)
+	}
+
+	return &rows, nil
+}
+
+// QuerySeq returns an iterator over the records that match a Query, fetching pages on demand
+//
+func (r *Resource[T]) QuerySeq(ctx context.Context, service *Service, q *Query) iter.Seq2[T, *errortools.Error] {
+	return query[T](ctx, service, r.Endpoint, q)
+}
+
+// Create creates a new record and returns it as stored by Insightly
+//
+func (r *Resource[T]) Create(ctx context.Context, service *Service, record *T) (*T, *errortools.Error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	return r.write(ctx, service, http.MethodPost, record)
+}
+
+// Update updates an existing record and returns it as stored by Insightly. A record of
+// which ID returns 0 is rejected with an error without sending a request, Insightly
+// needs the id to find the record to update.
+//
+func (r *Resource[T]) Update(ctx context.Context, service *Service, record *T) (*T, *errortools.Error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	if r.ID != nil && r.ID(record) == 0 {
+		return nil, errortools.ErrorMessagef("Cannot update %s record without id", r.Endpoint)
+	}
+
+	return r.write(ctx, service, http.MethodPut, record)
+}
+
+// Delete deletes a specific record
+//
+func (r *Resource[T]) Delete(ctx context.Context, service *Service, id int64) *errortools.Error {
+	requestConfig := go_http.RequestConfig{
+		Method: http.MethodDelete,
+		Url:    service.url(fmt.Sprintf("%s/%v", r.Endpoint, id)),
+	}
+	_, _, e := service.httpRequest(ctx, &requestConfig)
+
+	return e
+}
+
+func (r *Resource[T]) write(ctx context.Context, service *Service, method string, record *T) (*T, *errortools.Error) {
+	var body interface{} = record
+	if r.Marshal != nil {
+		body = r.Marshal(record)
+	}
+
+	written := new(T)
+
+	requestConfig := go_http.RequestConfig{
+		Method:        method,
+		Url:           service.url(r.Endpoint),
+		BodyModel:     body,
+		ResponseModel: written,
+	}
+	_, _, e := service.httpRequest(ctx, &requestConfig)
+	if e != nil {
+		return nil, e
+	}
+
+	return written, nil
+}
+
+// nonZero returns nil for the zero value, so it is left out of request bodies
+func nonZero[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+
+	return &v
+}
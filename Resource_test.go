@@ -0,0 +1,148 @@
+package insightly_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+func TestProjectEndpoint(t *testing.T) {
+	service, server := newTestService(t, nil)
+	ids := server.Seed("Projects", insightly.Project{ProjectName: "Website"})
+
+	_, e := service.GetProject(ids[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.GetProjects(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	e = service.AddTag(insightly.ObjectNameProject, ids[0], "vip")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.GetLinks(insightly.ObjectNameProject, ids[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	for _, request := range server.Requests() {
+		if !strings.HasPrefix(request.Path, "/v3.1/Projects") {
+			t.Errorf("got %s %s, want the Projects endpoint", request.Method, request.Path)
+		}
+	}
+}
+
+func TestUpdateWithoutID(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	_, e := service.UpdateContact(&insightly.Contact{})
+	if e == nil {
+		t.Fatal("expected an error for a contact without id")
+	}
+
+	if len(server.Requests()) != 0 {
+		t.Errorf("got %v requests, want none", len(server.Requests()))
+	}
+}
+
+func TestCreateOmitsUnsetIDs(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	_, e := service.CreateTask(&insightly.Task{Title: "Call back"})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.CreateProject(&insightly.Project{ProjectName: "Website"})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.CreateMilestone(&insightly.Milestone{Title: "Launch"})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.CreateNote(&insightly.Note{Title: "Minutes"})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.CreateEvent(&insightly.Event{Title: "Kick-off"})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	for _, request := range server.Requests() {
+		body := map[string]any{}
+		err := json.Unmarshal(request.Body, &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range body {
+			if strings.HasSuffix(key, "_ID") {
+				t.Errorf("%s %s sends %s %v, want it omitted", request.Method, request.Path, key, value)
+			}
+		}
+	}
+}
+
+func TestPagingConfigResumesAtNextCursor(t *testing.T) {
+	maxRowCount := uint64(1)
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.MaxRowCount = &maxRowCount
+	})
+	server.Seed("Pipelines", &insightly.Pipeline{PipelineName: "a"}, &insightly.Pipeline{PipelineName: "b"}, &insightly.Pipeline{PipelineName: "c"})
+
+	top := uint64(1)
+	names := []string{}
+	config := &insightly.GetPipelinesConfig{Top: &top}
+	for {
+		pipelines, e := service.GetPipelines(config)
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+		for _, pipeline := range *pipelines {
+			names = append(names, pipeline.PipelineName)
+		}
+		if config.NextCursor == nil {
+			break
+		}
+		config = &insightly.GetPipelinesConfig{Top: &top, Cursor: config.NextCursor}
+	}
+
+	if strings.Join(names, "") != "abc" {
+		t.Errorf("got pipelines %v, want a, b and c", names)
+	}
+}
+
+func TestListOptionsOfTheirOwn(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	includeConverted := true
+	brief := true
+	_, e := service.GetLeadStatuses(&insightly.GetLeadStatusesConfig{IncludeConverted: &includeConverted})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	_, e = service.GetTeams(&insightly.GetTeamsConfig{Brief: &brief})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %v requests, want 2", len(requests))
+	}
+	if requests[0].Path != "/v3.1/LeadStatuses" || requests[0].Query.Get("include_converted") != "true" {
+		t.Errorf("got %s?%s, want LeadStatuses including converted", requests[0].Path, requests[0].Query.Encode())
+	}
+	if requests[1].Path != "/v3.1/Teams" || requests[1].Query.Get("brief") != "true" {
+		t.Errorf("got %s?%s, want brief Teams", requests[1].Path, requests[1].Query.Encode())
+	}
+
+	records, e := service.GetCustomObjectRecords(nil)
+	if e != nil || records != nil || len(server.Requests()) != 2 {
+		t.Error("expected no custom object records without a config")
+	}
+}
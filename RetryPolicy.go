@@ -0,0 +1,92 @@
+package insightly
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy controls which failed requests are retried and how long to wait in between.
+// GET, HEAD and DELETE requests are retried by default, POST and PUT requests only if
+// RetryNonIdempotent is set since they may have been processed despite the failure.
+//
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries per request
+	MaxRetries int
+	// InitialInterval is the wait before the first retry, it is multiplied by
+	// Multiplier for every next retry up to MaxInterval
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// MaxElapsedTime stops retrying once the request including its retries would take
+	// longer, zero means no limit
+	MaxElapsedTime time.Duration
+	// StatusCodes lists the http status codes that are retried
+	StatusCodes []int
+	// RetryNetworkErrors retries requests that failed without a response
+	RetryNetworkErrors bool
+	// RetryNonIdempotent also retries POST and PUT requests
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the retry policy used if ServiceConfig.RetryPolicy is nil
+//
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:         10,
+		InitialInterval:    time.Second,
+		MaxInterval:        time.Minute,
+		Multiplier:         2,
+		MaxElapsedTime:     15 * time.Minute,
+		StatusCodes:        []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors: true,
+	}
+}
+
+// wait returns how long to wait before the next retry of a request, and false if
+// the request should not be retried
+//
+func (policy *RetryPolicy) wait(method string, sent bool, response *http.Response, retryAfter *time.Duration, retries int, elapsed time.Duration) (time.Duration, bool) {
+	if policy == nil || retries >= policy.MaxRetries {
+		return 0, false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+	default:
+		if !policy.RetryNonIdempotent {
+			return 0, false
+		}
+	}
+
+	if response == nil {
+		// requests that could not be built are not retried
+		if !sent || !policy.RetryNetworkErrors {
+			return 0, false
+		}
+	} else if !slices.Contains(policy.StatusCodes, response.StatusCode) {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if retryAfter != nil && *retryAfter > 0 {
+		// add a second since Retry-After is rounded down to whole seconds
+		wait = *retryAfter + time.Second
+	} else {
+		multiplier := math.Max(policy.Multiplier, 1)
+		interval := float64(policy.InitialInterval) * math.Pow(multiplier, float64(retries))
+		if policy.MaxInterval > 0 {
+			interval = math.Min(interval, float64(policy.MaxInterval))
+		}
+		// equal jitter: wait at least half the interval
+		wait = time.Duration(interval/2 + rand.Float64()*interval/2)
+	}
+
+	if policy.MaxElapsedTime > 0 && elapsed+wait > policy.MaxElapsedTime {
+		return 0, false
+	}
+
+	return wait, true
+}
@@ -0,0 +1,140 @@
+package insightly
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:         10,
+		InitialInterval:    time.Second,
+		MaxInterval:        10 * time.Second,
+		Multiplier:         2,
+		StatusCodes:        []int{http.StatusServiceUnavailable},
+		RetryNetworkErrors: true,
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := testRetryPolicy()
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	// the interval doubles from InitialInterval and is capped at MaxInterval, equal
+	// jitter waits between half the interval and the interval
+	intervals := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+
+	for retries, interval := range intervals {
+		for range 100 {
+			wait, ok := policy.wait(http.MethodGet, true, response, nil, retries, 0)
+			if !ok {
+				t.Fatalf("expected retry %v to be allowed", retries)
+			}
+			if wait < interval/2 || wait > interval {
+				t.Fatalf("got wait %v for retry %v, want between %v and %v", wait, retries, interval/2, interval)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := testRetryPolicy()
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	waits := map[time.Duration]bool{}
+	for range 100 {
+		wait, _ := policy.wait(http.MethodGet, true, response, nil, 0, 0)
+		waits[wait] = true
+	}
+
+	if len(waits) < 2 {
+		t.Errorf("got %v distinct waits, want jittered waits", len(waits))
+	}
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	policy := testRetryPolicy()
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	retryAfter := 30 * time.Second
+
+	wait, ok := policy.wait(http.MethodGet, true, response, &retryAfter, 0, 0)
+	if !ok || wait != retryAfter+time.Second {
+		t.Errorf("got wait %v, want Retry-After plus a second", wait)
+	}
+}
+
+func TestRetryPolicyMaxRetries(t *testing.T) {
+	policy := testRetryPolicy()
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	if _, ok := policy.wait(http.MethodGet, true, response, nil, policy.MaxRetries, 0); ok {
+		t.Error("expected no retry after MaxRetries")
+	}
+}
+
+func TestRetryPolicyMaxElapsedTime(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.MaxElapsedTime = time.Minute
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	if _, ok := policy.wait(http.MethodGet, true, response, nil, 0, 58*time.Second); !ok {
+		t.Error("expected a retry that ends within MaxElapsedTime")
+	}
+	if _, ok := policy.wait(http.MethodGet, true, response, nil, 0, time.Minute); ok {
+		t.Error("expected no retry once MaxElapsedTime has passed")
+	}
+
+	retryAfter := 2 * time.Minute
+	if _, ok := policy.wait(http.MethodGet, true, response, &retryAfter, 0, 0); ok {
+		t.Error("expected no retry when Retry-After exceeds MaxElapsedTime")
+	}
+}
+
+func TestRetryPolicyMethods(t *testing.T) {
+	policy := testRetryPolicy()
+	response := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodDelete} {
+		if _, ok := policy.wait(method, true, response, nil, 0, 0); !ok {
+			t.Errorf("expected %s to be retried", method)
+		}
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		if _, ok := policy.wait(method, true, response, nil, 0, 0); ok {
+			t.Errorf("expected %s not to be retried", method)
+		}
+	}
+
+	policy.RetryNonIdempotent = true
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		if _, ok := policy.wait(method, true, response, nil, 0, 0); !ok {
+			t.Errorf("expected %s to be retried with RetryNonIdempotent", method)
+		}
+	}
+}
+
+func TestRetryPolicyResponses(t *testing.T) {
+	policy := testRetryPolicy()
+
+	if _, ok := policy.wait(http.MethodGet, true, &http.Response{StatusCode: http.StatusBadRequest}, nil, 0, 0); ok {
+		t.Error("expected a status code not in StatusCodes not to be retried")
+	}
+	if _, ok := policy.wait(http.MethodGet, true, nil, nil, 0, 0); !ok {
+		t.Error("expected a network error to be retried")
+	}
+	if _, ok := policy.wait(http.MethodGet, false, nil, nil, 0, 0); ok {
+		t.Error("expected a request that was not sent not to be retried")
+	}
+
+	policy.RetryNetworkErrors = false
+	if _, ok := policy.wait(http.MethodGet, true, nil, nil, 0, 0); ok {
+		t.Error("expected no retry of network errors without RetryNetworkErrors")
+	}
+
+	var none *RetryPolicy
+	if _, ok := none.wait(http.MethodGet, true, nil, nil, 0, 0); ok {
+		t.Error("expected a nil policy not to retry")
+	}
+}
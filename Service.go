@@ -1,14 +1,22 @@
 package insightly
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -19,7 +27,6 @@ const (
 	dateFormat                string = "2006-01-02"
 	defaultMaxRowCount        uint64 = ^uint64(0)
 	defaultTop                uint64 = 500 //max 500, see: https://api.insightly.com/v3.1/Help#!/Overview/Introduction
-	maxRetries                int    = 10
 )
 
 type RateLimit struct {
@@ -28,20 +35,52 @@ type RateLimit struct {
 	RetryAt   *time.Time
 }
 
+// Service is safe for concurrent use by multiple goroutines.
+// The rate limit state is shared by all calls, so concurrent calls together
+// respect the rate limit; list calls keep their paging state per call. List calls
+// write the NextCursor of their config, so a config must not be shared by concurrent calls.
 type Service struct {
-	pod         string
-	apiKey      string
-	token       string
-	maxRowCount uint64
-	httpService *go_http.Service
-	rateLimit   RateLimit
-	nextSkips   map[string]uint64
+	baseUrl        string
+	apiKey         string
+	token          string
+	maxRowCount    uint64
+	httpClient     *http.Client
+	requestCount   atomic.Int64
+	retryPolicy    *RetryPolicy
+	limiter        *Limiter
+	rateLimit      RateLimit
+	rateLimitMutex sync.Mutex
+	rateLimitStore RateLimitStore
+	logger         *slog.Logger
+	beforeRequest  BeforeRequestHook
+	afterResponse  AfterResponseHook
+	telemetry      *telemetry
 }
 
+// errortoolsInit initializes the context map of go_errortools, which is created
+// lazily without locking on first use by go_http
+var errortoolsInit sync.Once
+
 type ServiceConfig struct {
 	Pod         string
 	ApiKey      string
-	MaxRowCount *uint64
+	MaxRowCount *uint64      // rows per list call, a truncated call sets the NextCursor of its config
+	RetryPolicy *RetryPolicy // DefaultRetryPolicy() if nil
+	Limiter     *Limiter     // paces requests on the client side if set
+	// RateLimitStore shares the rate limit with other Services using the same api key,
+	// e.g. NewFileRateLimitStore for cron jobs on the same host
+	RateLimitStore RateLimitStore
+	Transport      http.RoundTripper // http.DefaultTransport if nil
+	BaseUrl        *string           // overrides the api url of the Pod, e.g. to point at a test server
+	Logger         *slog.Logger      // slog.Default() if nil
+	// BeforeRequest and AfterResponse are called around every attempt of an api request,
+	// e.g. to collect metrics
+	BeforeRequest BeforeRequestHook
+	AfterResponse AfterResponseHook
+	// TracerProvider and MeterProvider default to the global OpenTelemetry providers,
+	// which are no-ops unless set by the application
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
 }
 
 func NewService(serviceConfig *ServiceConfig) (*Service, *errortools.Error) {
@@ -49,7 +88,7 @@ func NewService(serviceConfig *ServiceConfig) (*Service, *errortools.Error) {
 		return nil, errortools.ErrorMessage("ServiceConfig must not be a nil pointer")
 	}
 
-	if serviceConfig.Pod == "" {
+	if serviceConfig.Pod == "" && serviceConfig.BaseUrl == nil {
 		return nil, errortools.ErrorMessage("Service Pod not provided")
 	}
 
@@ -57,46 +96,105 @@ func NewService(serviceConfig *ServiceConfig) (*Service, *errortools.Error) {
 		return nil, errortools.ErrorMessage("Service Api Key not provided")
 	}
 
-	httpService, e := go_http.NewService(&go_http.ServiceConfig{})
-	if e != nil {
-		return nil, e
-	}
+	errortoolsInit.Do(func() {
+		errortools.SetContext("http_url", "")
+		errortools.RemoveContext("http_url")
+	})
 
 	maxRowCount := defaultMaxRowCount
 	if serviceConfig.MaxRowCount != nil {
 		maxRowCount = *serviceConfig.MaxRowCount
 	}
 
+	baseUrl := fmt.Sprintf(apiURL, serviceConfig.Pod)
+	if serviceConfig.BaseUrl != nil {
+		baseUrl = strings.TrimRight(*serviceConfig.BaseUrl, "/")
+	}
+
+	retryPolicy := DefaultRetryPolicy()
+	if serviceConfig.RetryPolicy != nil {
+		retryPolicy = serviceConfig.RetryPolicy
+	}
+
+	logger := slog.Default()
+	if serviceConfig.Logger != nil {
+		logger = serviceConfig.Logger
+	}
+
+	telemetry, e := newTelemetry(serviceConfig.TracerProvider, serviceConfig.MeterProvider)
+	if e != nil {
+		return nil, e
+	}
+
 	return &Service{
-		pod:         serviceConfig.Pod,
-		apiKey:      serviceConfig.ApiKey,
-		token:       base64.URLEncoding.EncodeToString([]byte(serviceConfig.ApiKey)),
-		maxRowCount: maxRowCount,
-		httpService: httpService,
-		nextSkips:   make(map[string]uint64),
+		baseUrl:        baseUrl,
+		apiKey:         serviceConfig.ApiKey,
+		token:          base64.URLEncoding.EncodeToString([]byte(serviceConfig.ApiKey)),
+		maxRowCount:    maxRowCount,
+		httpClient:     &http.Client{Transport: serviceConfig.Transport},
+		retryPolicy:    retryPolicy,
+		limiter:        serviceConfig.Limiter,
+		rateLimitStore: serviceConfig.RateLimitStore,
+		logger:         logger,
+		beforeRequest:  serviceConfig.BeforeRequest,
+		afterResponse:  serviceConfig.AfterResponse,
+		telemetry:      telemetry,
 	}, nil
 }
 
-func (service *Service) httpRequest(requestConfig *go_http.RequestConfig) (*http.Request, *http.Response, *errortools.Error) {
+func (service *Service) httpRequest(ctx context.Context, requestConfig *go_http.RequestConfig) (*http.Request, *http.Response, *errortools.Error) {
+	endpoint := service.endpoint(requestConfig.Url)
+
+	requestCtx, span := service.telemetry.startRequestSpan(ctx, requestConfig.Method, endpoint, requestConfig.Url)
+	request, response, retries, e := service.sendRequest(requestCtx, endpoint, requestConfig)
+	service.telemetry.endRequestSpan(ctx, span, response, retries, e)
+
+	return request, response, e
+}
+
+// sendRequest sends a request, waiting for the rate limit and retrying according to
+// the RetryPolicy, and returns the number of retries made
+func (service *Service) sendRequest(ctx context.Context, endpoint string, requestConfig *go_http.RequestConfig) (*http.Request, *http.Response, int, *errortools.Error) {
+	start := time.Now()
 	retries := 0
 
+	// retries are handled by the RetryPolicy
+	noRetries := uint(0)
+	(*requestConfig).MaxRetries = &noRetries
+
 retry:
-	// check rate limit
-	if service.rateLimit.Remaining != nil {
-		if *service.rateLimit.Remaining <= 0 {
-			if service.rateLimit.RetryAt == nil {
-				return nil, nil, errortools.ErrorMessage("Rate limit exceeded but RetryAt unknown.")
-			}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, retries, errortools.ErrorMessage(err)
+	}
 
-			duration := time.Until(*service.rateLimit.RetryAt)
+	// check rate limit, without RetryAt it is unknown when the limit resets so the request
+	// is sent and a 429 response tells when to retry
+	rateLimit := service.loadRateLimit(ctx)
+	if rateLimit.Remaining != nil && rateLimit.RetryAt != nil {
+		if *rateLimit.Remaining <= 0 {
+			duration := time.Until(*rateLimit.RetryAt)
 
 			if duration > 0 {
-				errortools.CaptureInfo(fmt.Sprintf("Rate limit exceeded, waiting %v ms.", duration.Milliseconds()))
-				time.Sleep(duration)
+				service.logger.InfoContext(ctx, "insightly: rate limit exceeded, waiting",
+					slog.String("method", requestConfig.Method),
+					slog.String("endpoint", endpoint),
+					slog.Duration("wait", duration),
+				)
+				err := sleepWithContext(ctx, duration)
+				if err != nil {
+					return nil, nil, retries, errortools.ErrorMessage(err)
+				}
 			}
 		}
 	}
 
+	if service.limiter != nil {
+		e := service.limiter.Wait(ctx)
+		if e != nil {
+			return nil, nil, retries, e
+		}
+	}
+
 	// add authentication header
 	header := http.Header{}
 	header.Set("Authorization", fmt.Sprintf("Basic %s", service.token))
@@ -106,58 +204,186 @@ retry:
 	errorResponse := ErrorResponse{}
 	(*requestConfig).ErrorModel = &errorResponse
 
-	request, response, e := service.httpService.HttpRequest(requestConfig)
+	// bind the request to ctx, go_http does not accept a context itself
+	httpService, e := go_http.NewService(&go_http.ServiceConfig{
+		HttpClient: &http.Client{
+			Transport:     &contextTransport{ctx: ctx, base: service.httpClient.Transport},
+			CheckRedirect: service.httpClient.CheckRedirect,
+			Jar:           service.httpClient.Jar,
+			Timeout:       service.httpClient.Timeout,
+		},
+	})
+	if e != nil {
+		return nil, nil, retries, e
+	}
+
+	if service.beforeRequest != nil {
+		service.beforeRequest(ctx, RequestInfo{
+			Method:    requestConfig.Method,
+			Endpoint:  endpoint,
+			Retry:     retries,
+			RateLimit: rateLimit,
+		})
+	}
+
+	service.requestCount.Add(1)
+	attemptStart := time.Now()
+
+	request, response, e := httpService.HttpRequest(requestConfig)
+	duration := time.Since(attemptStart)
+	if e == nil && response == nil && request != nil {
+		e = errortools.ErrorMessage("No response received")
+	}
 	if errorResponse.Message != "" {
 		e.SetMessage(errorResponse.Message)
 	}
 
+	var retryAfter *time.Duration
+
 	if response != nil {
 		// Read RateLimit headers
+		rateLimit := RateLimit{}
 		rateLimitLimit, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Limit"), 10, 64)
 		if err == nil {
-			service.rateLimit.Limit = &rateLimitLimit
-		} else {
-			service.rateLimit.Limit = nil
+			rateLimit.Limit = &rateLimitLimit
 		}
 		rateLimitRemaining, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Remaining"), 10, 64)
 		if err == nil {
-			service.rateLimit.Remaining = &rateLimitRemaining
-		} else {
-			service.rateLimit.Remaining = nil
+			rateLimit.Remaining = &rateLimitRemaining
 		}
-		retryAfter, err := strconv.ParseInt(response.Header.Get("Retry-After"), 10, 64)
-		if err == nil {
-			retryAt := time.Now().Add(time.Duration(retryAfter) * time.Second)
-			service.rateLimit.RetryAt = &retryAt
-		} else {
-			service.rateLimit.RetryAt = nil
+		retryAt := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+		if retryAt != nil {
+			_retryAfter := max(time.Until(*retryAt), 0)
+			retryAfter = &_retryAfter
+			rateLimit.RetryAt = retryAt
 		}
+		service.saveRateLimit(ctx, rateLimit)
 
-		if response.StatusCode == http.StatusTooManyRequests {
-			if retryAfter > 0 {
-				if retries < maxRetries {
-					retryAfter++
-					fmt.Printf("waiting %v seconds...\n", retryAfter)
-					// wait 2 seconds
-					time.Sleep(time.Duration(retryAfter) * time.Second)
-					retries++
-					goto retry
-				}
+		if service.limiter != nil {
+			service.limiter.update(rateLimit.Limit, rateLimit.Remaining)
+		}
+	}
+
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+
+	if service.logger.Enabled(ctx, slog.LevelDebug) {
+		service.logger.DebugContext(ctx, "insightly: request",
+			slog.String("method", requestConfig.Method),
+			slog.String("endpoint", endpoint),
+			slog.Int("status", statusCode),
+			slog.Duration("duration", duration),
+			slog.Int("retry", retries),
+		)
+	}
+
+	if service.afterResponse != nil {
+		service.afterResponse(ctx, ResponseInfo{
+			Method:     requestConfig.Method,
+			Endpoint:   endpoint,
+			StatusCode: statusCode,
+			Duration:   duration,
+			Retry:      retries,
+			RateLimit:  service.RateLimit(),
+			Error:      e,
+		})
+	}
+
+	service.telemetry.recordAttempt(ctx, requestConfig.Method, endpoint, statusCode, duration, service.RateLimit())
+
+	if e != nil && ctx.Err() == nil {
+		wait, ok := service.retryPolicy.wait(requestConfig.Method, request != nil, response, retryAfter, retries, time.Since(start))
+		if ok {
+			service.logger.WarnContext(ctx, "insightly: request failed, retrying",
+				slog.String("method", requestConfig.Method),
+				slog.String("endpoint", endpoint),
+				slog.Int("status", statusCode),
+				slog.String("error", e.Message()),
+				slog.Int("retry", retries+1),
+				slog.Duration("wait", wait),
+			)
+			trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+				attribute.Int("http.response.status_code", statusCode),
+				attribute.String("error", e.Message()),
+				attribute.Int64("insightly.wait_ms", wait.Milliseconds()),
+			))
+			err := sleepWithContext(ctx, wait)
+			if err != nil {
+				return request, response, retries, errortools.ErrorMessage(err)
 			}
+			retries++
+			goto retry
 		}
 	}
 
-	return request, response, e
+	return request, response, retries, e
 }
 
 func (service *Service) url(path string) string {
-	return fmt.Sprintf("%s/%s", fmt.Sprintf(apiURL, service.pod), path)
+	return fmt.Sprintf("%s/%s", service.baseUrl, path)
+}
+
+// endpoint returns the path of url relative to the api url, without query
+func (service *Service) endpoint(url string) string {
+	endpoint := strings.TrimPrefix(url, service.baseUrl+"/")
+	endpoint, _, _ = strings.Cut(endpoint, "?")
+
+	return endpoint
 }
 
+// RateLimit returns a snapshot of the rate limit as reported by the last response
 func (service *Service) RateLimit() RateLimit {
+	service.rateLimitMutex.Lock()
+	defer service.rateLimitMutex.Unlock()
+
 	return service.rateLimit
 }
 
+func (service *Service) setRateLimit(rateLimit RateLimit) {
+	service.rateLimitMutex.Lock()
+	defer service.rateLimitMutex.Unlock()
+
+	service.rateLimit = rateLimit
+}
+
+// loadRateLimit refreshes the rate limit from the RateLimitStore, if the store cannot
+// be read the last known rate limit is used
+func (service *Service) loadRateLimit(ctx context.Context) RateLimit {
+	if service.rateLimitStore == nil {
+		return service.RateLimit()
+	}
+
+	rateLimit, e := service.rateLimitStore.Load(ctx)
+	if e != nil {
+		service.logger.WarnContext(ctx, "insightly: cannot load rate limit", slog.String("error", e.Message()))
+		return service.RateLimit()
+	}
+
+	service.setRateLimit(rateLimit)
+
+	return rateLimit
+}
+
+// saveRateLimit stores the rate limit read from a response, responses without rate
+// limit headers do not overwrite the state shared through the RateLimitStore
+func (service *Service) saveRateLimit(ctx context.Context, rateLimit RateLimit) {
+	service.setRateLimit(rateLimit)
+
+	if service.rateLimitStore == nil {
+		return
+	}
+	if rateLimit.Limit == nil && rateLimit.Remaining == nil && rateLimit.RetryAt == nil {
+		return
+	}
+
+	e := service.rateLimitStore.Save(ctx, rateLimit)
+	if e != nil {
+		service.logger.WarnContext(ctx, "insightly: cannot save rate limit", slog.String("error", e.Message()))
+	}
+}
+
 func (service *Service) ApiName() string {
 	return apiName
 }
@@ -167,9 +393,37 @@ func (service *Service) ApiKey() string {
 }
 
 func (service *Service) ApiCallCount() int64 {
-	return service.httpService.RequestCount()
+	return service.requestCount.Load()
 }
 
 func (service *Service) ApiReset() {
-	service.httpService.ResetRequestCount()
+	service.requestCount.Store(0)
+}
+
+// contextTransport attaches ctx to every request it sends
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(request.WithContext(t.ctx))
+}
+
+// sleepWithContext waits for duration d unless ctx is done first
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -0,0 +1,31 @@
+package insightly_test
+
+import (
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// newTestService returns a Service pointing at a new fake server, retries are disabled
+// unless configure sets a RetryPolicy
+//
+func newTestService(t *testing.T, configure func(config *insightly.ServiceConfig)) (*insightly.Service, *insightlytest.Server) {
+	t.Helper()
+
+	server := insightlytest.NewServer()
+	t.Cleanup(server.Close)
+
+	config := server.ServiceConfig()
+	config.RetryPolicy = &insightly.RetryPolicy{}
+	if configure != nil {
+		configure(config)
+	}
+
+	service, e := insightly.NewService(config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	return service, server
+}
@@ -1,9 +1,14 @@
 package insightly
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
-	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
 	go_http "github.com/leapforce-libraries/go_http"
@@ -15,72 +20,381 @@ type Tag struct {
 	TagName string `json:"TAG_NAME"`
 }
 
+var tagResource = &Resource[Tag]{
+	Endpoint: "Tags",
+}
+
 type GetTagsConfig struct {
 	Skip       *uint64
 	Top        *uint64
+	Cursor     *Cursor
+	NextCursor *Cursor
 	CountTotal *bool
 	RecordType string
 }
 
+func (config *GetTagsConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return nil
+	}
+
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.params.Set("record_type", config.RecordType)
+
+	return p
+}
+
 // GetTags returns all tags
 //
 func (service *Service) GetTags(config *GetTagsConfig) (*[]Tag, *errortools.Error) {
+	return service.GetTagsWithContext(context.Background(), config)
+}
+
+// GetTagsWithContext is the context-aware variant of GetTags
+//
+func (service *Service) GetTagsWithContext(ctx context.Context, config *GetTagsConfig) (*[]Tag, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTags")
+	defer span.End()
+
+	return tagResource.List(ctx, service, config)
+}
+
+// GetTagsPage returns a single page of tags and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetTagsPage(config *GetTagsConfig) (*[]Tag, *Cursor, *errortools.Error) {
+	return service.GetTagsPageWithContext(context.Background(), config)
+}
+
+// GetTagsPageWithContext is the context-aware variant of GetTagsPage
+//
+func (service *Service) GetTagsPageWithContext(ctx context.Context, config *GetTagsConfig) (*[]Tag, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTagsPage")
+	defer span.End()
+
+	return tagResource.Page(ctx, service, config)
+}
+
+// TagsSeq returns an iterator over all tags, fetching pages on demand
+//
+func (service *Service) TagsSeq(config *GetTagsConfig) iter.Seq2[Tag, *errortools.Error] {
+	return service.TagsSeqWithContext(context.Background(), config)
+}
+
+// TagsSeqWithContext is the context-aware variant of TagsSeq
+//
+func (service *Service) TagsSeqWithContext(ctx context.Context, config *GetTagsConfig) iter.Seq2[Tag, *errortools.Error] {
+	return seq(ctx, service, "TagsSeq", tagResource, config)
+}
+
+var taggableObjectNames = []ObjectName{
+	ObjectNameContact,
+	ObjectNameLead,
+	ObjectNameOrganisation,
+	ObjectNameOpportunity,
+	ObjectNameProject,
+	ObjectNameProspect,
+	ObjectNameEmail,
+}
+
+var objectNameIDFields = map[ObjectName]string{
+	ObjectNameContact:      "CONTACT_ID",
+	ObjectNameLead:         "LEAD_ID",
+	ObjectNameOrganisation: "ORGANISATION_ID",
+	ObjectNameOpportunity:  "OPPORTUNITY_ID",
+	ObjectNameProject:      "PROJECT_ID",
+	ObjectNameProspect:     "PROSPECT_ID",
+	ObjectNameEmail:        "EMAIL_ID",
+}
+
+// tagsEndpoint returns the endpoint of the records of the object type if they can be tagged
+//
+func (objectName ObjectName) tagsEndpoint() (string, *errortools.Error) {
+	if slices.Contains(taggableObjectNames, objectName) {
+		return objectNameEndpoints[objectName], nil
+	}
+
+	return "", errortools.ErrorMessagef("Object %s cannot be tagged", objectName)
+}
+
+// taggedRecord holds the id and tags of a record of any taggable type
+//
+type taggedRecord struct {
+	id   int64
+	tags []Tag
+}
+
+func (r *taggedRecord) hasTag(tagName string) bool {
+	return slices.ContainsFunc(r.tags, func(tag Tag) bool {
+		return strings.EqualFold(tag.TagName, tagName)
+	})
+}
+
+// taggedRecords returns an iterator over the id and tags of the records selected by config
+//
+func taggedRecords(ctx context.Context, service *Service, objectName ObjectName, config *pageConfig) iter.Seq2[taggedRecord, *errortools.Error] {
+	return func(yield func(taggedRecord, *errortools.Error) bool) {
+		for row, e := range iterate[map[string]json.RawMessage](ctx, service, config) {
+			if e != nil {
+				yield(taggedRecord{}, e)
+				return
+			}
+
+			record := taggedRecord{}
+			err := json.Unmarshal(row[objectNameIDFields[objectName]], &record.id)
+			if err == nil && row["TAGS"] != nil {
+				err = json.Unmarshal(row["TAGS"], &record.tags)
+			}
+			if err != nil {
+				yield(taggedRecord{}, errortools.ErrorMessagef("Cannot read %s record: %s", objectName, err.Error()))
+				return
+			}
+
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// byTagPageConfig returns the pageConfig of the records of a type that have a specific tag
+//
+func byTagPageConfig(endpoint string, tagName string) *pageConfig {
+	p := newPageConfig(endpoint+"/SearchByTag", nil, nil, nil)
+	p.params.Set("tagName", tagName)
+
+	return p
+}
+
+// AddTag adds a tag to a specific record
+//
+func (service *Service) AddTag(objectName ObjectName, objectID int64, tagName string) *errortools.Error {
+	return service.AddTagWithContext(context.Background(), objectName, objectID, tagName)
+}
+
+// AddTagWithContext is the context-aware variant of AddTag
+//
+func (service *Service) AddTagWithContext(ctx context.Context, objectName ObjectName, objectID int64, tagName string) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "AddTag")
+	defer span.End()
+
+	return writeTag(ctx, service, http.MethodPost, objectName, objectID, tagName)
+}
+
+// RemoveTag removes a tag from a specific record
+//
+func (service *Service) RemoveTag(objectName ObjectName, objectID int64, tagName string) *errortools.Error {
+	return service.RemoveTagWithContext(context.Background(), objectName, objectID, tagName)
+}
+
+// RemoveTagWithContext is the context-aware variant of RemoveTag
+//
+func (service *Service) RemoveTagWithContext(ctx context.Context, objectName ObjectName, objectID int64, tagName string) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "RemoveTag")
+	defer span.End()
+
+	return writeTag(ctx, service, http.MethodDelete, objectName, objectID, tagName)
+}
+
+func writeTag(ctx context.Context, service *Service, method string, objectName ObjectName, objectID int64, tagName string) *errortools.Error {
+	if tagName == "" {
+		return errortools.ErrorMessage("Tag name must not be empty")
+	}
+
+	endpoint, e := objectName.tagsEndpoint()
+	if e != nil {
+		return e
+	}
+
+	requestConfig := go_http.RequestConfig{
+		Method:    method,
+		Url:       service.url(fmt.Sprintf("%s/%v/Tags", endpoint, objectID)),
+		BodyModel: Tag{TagName: tagName},
+	}
+	_, _, e = service.httpRequest(ctx, &requestConfig)
+
+	return e
+}
+
+type BulkTagConfig struct {
+	ObjectName ObjectName
+	TagName    string
+	// Remove strips the tag from the records instead of applying it
+	Remove bool
+	// UpdatedAfter and FieldFilter select the records, all records are selected if both are nil
+	UpdatedAfter *time.Time
+	FieldFilter  *FieldFilter
+	// Cursor resumes a selection truncated by ServiceConfig.MaxRowCount, as reported in
+	// BulkTagResult.NextCursor
+	Cursor *Cursor
+}
+
+// BulkTagResult reports the records changed by BulkTag
+//
+type BulkTagResult struct {
+	// Changed holds the ids of the records the tag was applied to or stripped from
+	Changed []int64
+	// Unchanged counts the selected records that already had, or did not have, the tag
+	Unchanged int
+	// NextCursor is set if ServiceConfig.MaxRowCount truncated the selection, pass it as
+	// BulkTagConfig.Cursor to change the remaining records
+	NextCursor *Cursor
+}
+
+// BulkTag applies a tag to, or strips it from, all records of a type that match a filter.
+// The records are selected before any of them is changed. If a change fails the result
+// of the changes made so far is returned along with the error.
+//
+func (service *Service) BulkTag(config *BulkTagConfig) (*BulkTagResult, *errortools.Error) {
+	return service.BulkTagWithContext(context.Background(), config)
+}
+
+// BulkTagWithContext is the context-aware variant of BulkTag
+//
+func (service *Service) BulkTagWithContext(ctx context.Context, config *BulkTagConfig) (*BulkTagResult, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "BulkTag")
+	defer span.End()
+
 	if config == nil {
-		return nil, nil
+		return nil, errortools.ErrorMessage("BulkTagConfig must not be nil")
+	}
+	if config.TagName == "" {
+		return nil, errortools.ErrorMessage("TagName must not be empty")
+	}
+
+	endpoint, e := config.ObjectName.tagsEndpoint()
+	if e != nil {
+		return nil, e
 	}
 
-	params := url.Values{}
+	result := BulkTagResult{}
 
-	endpoint := "Tags"
-	tags := []Tag{}
-	rowCount := uint64(0)
-	top := defaultTop
+	p := newPageConfig(endpoint, nil, nil, nil)
+	p.setSearch(config.UpdatedAfter, config.FieldFilter)
+	p.setCursor(config.Cursor)
+	p.setNextCursor(&result.NextCursor)
+	ids := []int64{}
 
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
+	for record, e := range taggedRecords(ctx, service, config.ObjectName, p) {
+		if e != nil {
+			return nil, e
 		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
+
+		if record.hasTag(config.TagName) == config.Remove {
+			ids = append(ids, record.id)
+		} else {
+			result.Unchanged++
 		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
+	}
+
+	method := http.MethodPost
+	if config.Remove {
+		method = http.MethodDelete
+	}
+
+	for _, id := range ids {
+		e := writeTag(ctx, service, method, config.ObjectName, id, config.TagName)
+		if e != nil {
+			return &result, e
 		}
-		params.Set("record_type", config.RecordType)
+
+		result.Changed = append(result.Changed, id)
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return &result, nil
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+type RenameTagConfig struct {
+	From string
+	To   string
+	// ObjectNames restricts the rename to records of these types, all taggable types if empty
+	ObjectNames []ObjectName
+}
 
-		tagsBatch := []Tag{}
+// RenameTagResult holds the ids of the renamed records per type
+//
+type RenameTagResult struct {
+	Records map[ObjectName][]int64
+	// Truncated is set if ServiceConfig.MaxRowCount left records tagged From, renamed
+	// records no longer have that tag so running RenameTag again renames the rest
+	Truncated bool
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &tagsBatch,
-		}
+// RenameTag moves every record tagged From to the tag To, Insightly has no tag entity
+// of its own so a tag disappears once no record has it anymore. If a change fails the
+// result of the changes made so far is returned along with the error.
+//
+func (service *Service) RenameTag(config *RenameTagConfig) (*RenameTagResult, *errortools.Error) {
+	return service.RenameTagWithContext(context.Background(), config)
+}
+
+// RenameTagWithContext is the context-aware variant of RenameTag
+//
+func (service *Service) RenameTagWithContext(ctx context.Context, config *RenameTagConfig) (*RenameTagResult, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "RenameTag")
+	defer span.End()
+
+	if config == nil {
+		return nil, errortools.ErrorMessage("RenameTagConfig must not be nil")
+	}
+	if config.From == "" || config.To == "" {
+		return nil, errortools.ErrorMessage("Tag names must not be empty")
+	}
+	if config.From == config.To {
+		return &RenameTagResult{Records: map[ObjectName][]int64{}}, nil
+	}
+
+	objectNames := config.ObjectNames
+	if len(objectNames) == 0 {
+		objectNames = taggableObjectNames
+	}
+
+	// a tag that only changes case is removed first, Insightly would otherwise regard it as present
+	caseOnly := strings.EqualFold(config.From, config.To)
 
-		_, _, e := service.httpRequest(&requestConfig)
+	result := RenameTagResult{Records: map[ObjectName][]int64{}}
+
+	for _, objectName := range objectNames {
+		endpoint, e := objectName.tagsEndpoint()
 		if e != nil {
-			return nil, e
+			return &result, e
 		}
 
-		tags = append(tags, tagsBatch...)
+		var nextCursor *Cursor
+		p := byTagPageConfig(endpoint, config.From)
+		p.setNextCursor(&nextCursor)
 
-		if len(tagsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
+		records := []taggedRecord{}
+		for record, e := range taggedRecords(ctx, service, objectName, p) {
+			if e != nil {
+				return &result, e
+			}
+			records = append(records, record)
+		}
+		if nextCursor != nil {
+			result.Truncated = true
 		}
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+		for _, record := range records {
+			if caseOnly {
+				e = writeTag(ctx, service, http.MethodDelete, objectName, record.id, config.From)
+				if e == nil {
+					e = writeTag(ctx, service, http.MethodPost, objectName, record.id, config.To)
+				}
+			} else {
+				if !record.hasTag(config.To) {
+					e = writeTag(ctx, service, http.MethodPost, objectName, record.id, config.To)
+				}
+				if e == nil {
+					e = writeTag(ctx, service, http.MethodDelete, objectName, record.id, config.From)
+				}
+			}
+			if e != nil {
+				return &result, e
+			}
 
-		if rowCount >= service.maxRowCount {
-			return &tags, nil
+			result.Records[objectName] = append(result.Records[objectName], record.id)
 		}
 	}
 
-	return &tags, nil
+	return &result, nil
 }
@@ -0,0 +1,102 @@
+package insightly_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+// pageSize is the number of rows the list requests of BulkTag and RenameTag ask for
+//
+const pageSize = 500
+
+// truncatingTestService returns a Service that stops a crawl after a single page
+//
+func truncatingTestService(t *testing.T) (*insightly.Service, *insightlytest.Server) {
+	maxRowCount := uint64(1)
+
+	return newTestService(t, func(config *insightly.ServiceConfig) {
+		config.MaxRowCount = &maxRowCount
+	})
+}
+
+func seedTaggedContacts(server *insightlytest.Server, count int, tags ...insightly.Tag) {
+	contacts := []any{}
+	for i := 0; i < count; i++ {
+		contacts = append(contacts, &insightly.Contact{Tags: &tags})
+	}
+	server.Seed("Contacts", contacts...)
+}
+
+func TestBulkTagRequiresTagName(t *testing.T) {
+	service, server := newTestService(t, nil)
+
+	_, e := service.BulkTag(&insightly.BulkTagConfig{ObjectName: insightly.ObjectNameContact})
+	if e == nil {
+		t.Fatal("expected an error for an empty tag name")
+	}
+
+	if len(server.Requests()) != 0 {
+		t.Errorf("got %v requests, want none", len(server.Requests()))
+	}
+}
+
+func TestBulkTagTruncatedByMaxRowCount(t *testing.T) {
+	service, server := truncatingTestService(t)
+	seedTaggedContacts(server, pageSize+1)
+
+	config := insightly.BulkTagConfig{ObjectName: insightly.ObjectNameContact, TagName: "vip"}
+
+	result, e := service.BulkTag(&config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(result.Changed) != pageSize || result.NextCursor == nil {
+		t.Fatalf("got %v changed records and cursor %v, want %v and a cursor", len(result.Changed), result.NextCursor, pageSize)
+	}
+
+	config.Cursor = result.NextCursor
+	result, e = service.BulkTag(&config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(result.Changed) != 1 || result.Unchanged != 0 || result.NextCursor != nil {
+		t.Errorf("got %v changed and %v unchanged records and cursor %v, want only the last record", len(result.Changed), result.Unchanged, result.NextCursor)
+	}
+
+	tagged := 0
+	for _, request := range server.Requests() {
+		if request.Method == http.MethodPost && strings.HasSuffix(request.Path, "/Tags") {
+			tagged++
+		}
+	}
+	if tagged != pageSize+1 {
+		t.Errorf("got %v tagged contacts, want %v", tagged, pageSize+1)
+	}
+}
+
+func TestRenameTagTruncatedByMaxRowCount(t *testing.T) {
+	service, server := truncatingTestService(t)
+	seedTaggedContacts(server, pageSize+1, insightly.Tag{TagName: "old"})
+
+	config := insightly.RenameTagConfig{From: "old", To: "new", ObjectNames: []insightly.ObjectName{insightly.ObjectNameContact}}
+
+	result, e := service.RenameTag(&config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(result.Records[insightly.ObjectNameContact]) != pageSize || !result.Truncated {
+		t.Fatalf("got %v renamed records, truncated %v, want %v and truncated", len(result.Records[insightly.ObjectNameContact]), result.Truncated, pageSize)
+	}
+
+	result, e = service.RenameTag(&config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	if len(result.Records[insightly.ObjectNameContact]) != 1 || result.Truncated {
+		t.Errorf("got %v renamed records, truncated %v, want only the last record", len(result.Records[insightly.ObjectNameContact]), result.Truncated)
+	}
+}
@@ -1,13 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -48,102 +45,168 @@ type Task struct {
 	Links             *[]Link                 `json:"LINKS"`
 }
 
+func (t *Task) prepareMarshal() interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return &struct {
+		TaskID            *int64                  `json:"TASK_ID,omitempty"`
+		Title             *string                 `json:"TITLE,omitempty"`
+		CategoryID        *int64                  `json:"CATEGORY_ID,omitempty"`
+		DueDate           *i_types.DateTimeString `json:"DUE_DATE,omitempty"`
+		Completed         *bool                   `json:"COMPLETED,omitempty"`
+		Details           *string                 `json:"DETAILS,omitempty"`
+		Status            *string                 `json:"STATUS,omitempty"`
+		Priority          *int64                  `json:"PRIORITY,omitempty"`
+		PercentComplete   *int64                  `json:"PERCENT_COMPLETE,omitempty"`
+		StartDate         *i_types.DateTimeString `json:"START_DATE,omitempty"`
+		MilestoneID       *int64                  `json:"MILESTONE_ID,omitempty"`
+		PubliclyVisible   *bool                   `json:"PUBLICLY_VISIBLE,omitempty"`
+		ResponsibleUserID *int64                  `json:"RESPONSIBLE_USER_ID,omitempty"`
+		OwnerUserID       *int64                  `json:"OWNER_USER_ID,omitempty"`
+		ProjectID         *int64                  `json:"PROJECT_ID,omitempty"`
+		ReminderDateUTC   *i_types.DateTimeString `json:"REMINDER_DATE_UTC,omitempty"`
+		OwnerVisible      *bool                   `json:"OWNER_VISIBLE,omitempty"`
+		StageID           *int64                  `json:"STAGE_ID,omitempty"`
+		ParentTaskID      *int64                  `json:"PARENT_TASK_ID,omitempty"`
+		Recurrence        *string                 `json:"RECURRENCE,omitempty"`
+		OpportunityID     *int64                  `json:"OPPORTUNITY_ID,omitempty"`
+		AssignedTeamID    *int64                  `json:"ASSIGNED_TEAM_ID,omitempty"`
+		CustomFields      *CustomFields           `json:"CUSTOMFIELDS,omitempty"`
+	}{
+		nonZero(t.TaskID),
+		&t.Title,
+		t.CategoryID,
+		t.DueDate,
+		&t.Completed,
+		t.Details,
+		&t.Status,
+		&t.Priority,
+		&t.PercentComplete,
+		dateTimeStringPtr(t.StartDate),
+		t.MilestoneID,
+		t.PubliclyVisible,
+		nonZero(t.ResponsibleUserID),
+		nonZero(t.OwnerUserID),
+		t.ProjectID,
+		t.ReminderDateUTC,
+		&t.OwnerVisible,
+		t.StageID,
+		t.ParentTaskID,
+		t.Recurrence,
+		t.OpportunityID,
+		t.AssignedTeamID,
+		t.CustomFields,
+	}
+}
+
+var taskResource = &Resource[Task]{
+	Endpoint: "Tasks",
+	ID:       func(t *Task) int64 { return t.TaskID },
+	Marshal:  func(t *Task) interface{} { return t.prepareMarshal() },
+}
+
 // GetTask returns a specific task
 //
 func (service *Service) GetTask(taskID int64) (*Task, *errortools.Error) {
-	task := Task{}
+	return service.GetTaskWithContext(context.Background(), taskID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Tasks/%v", taskID)),
-		ResponseModel: &task,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetTaskWithContext is the context-aware variant of GetTask
+//
+func (service *Service) GetTaskWithContext(ctx context.Context, taskID int64) (*Task, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTask")
+	defer span.End()
 
-	return &task, nil
+	return taskResource.Get(ctx, service, taskID)
 }
 
-type GetTasksConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetTasksConfig = ListConfig
 
 // GetTasks returns all tasks
 //
 func (service *Service) GetTasks(config *GetTasksConfig) (*[]Task, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Tasks"
-	tasks := []Task{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
+	return service.GetTasksWithContext(context.Background(), config)
+}
 
-	if isSearch {
-		endpoint += "/Search"
-	}
+// GetTasksWithContext is the context-aware variant of GetTasks
+//
+func (service *Service) GetTasksWithContext(ctx context.Context, config *GetTasksConfig) (*[]Task, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTasks")
+	defer span.End()
+
+	return taskResource.List(ctx, service, config)
+}
+
+// GetTasksPage returns a single page of tasks and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetTasksPage(config *GetTasksConfig) (*[]Task, *Cursor, *errortools.Error) {
+	return service.GetTasksPageWithContext(context.Background(), config)
+}
+
+// GetTasksPageWithContext is the context-aware variant of GetTasksPage
+//
+func (service *Service) GetTasksPageWithContext(ctx context.Context, config *GetTasksConfig) (*[]Task, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTasksPage")
+	defer span.End()
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	return taskResource.Page(ctx, service, config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		tasksBatch := []Task{}
+// TasksSeq returns an iterator over all tasks, fetching pages on demand
+//
+func (service *Service) TasksSeq(config *GetTasksConfig) iter.Seq2[Task, *errortools.Error] {
+	return service.TasksSeqWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &tasksBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+// TasksSeqWithContext is the context-aware variant of TasksSeq
+//
+func (service *Service) TasksSeqWithContext(ctx context.Context, config *GetTasksConfig) iter.Seq2[Task, *errortools.Error] {
+	return seq(ctx, service, "TasksSeq", taskResource, config)
+}
 
-		tasks = append(tasks, tasksBatch...)
+// CreateTask creates a new task
+//
+func (service *Service) CreateTask(task *Task) (*Task, *errortools.Error) {
+	return service.CreateTaskWithContext(context.Background(), task)
+}
 
-		if len(tasksBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// CreateTaskWithContext is the context-aware variant of CreateTask
+//
+func (service *Service) CreateTaskWithContext(ctx context.Context, task *Task) (*Task, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateTask")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return taskResource.Create(ctx, service, task)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &tasks, nil
-		}
-	}
+// UpdateTask updates an existing task
+//
+func (service *Service) UpdateTask(task *Task) (*Task, *errortools.Error) {
+	return service.UpdateTaskWithContext(context.Background(), task)
+}
+
+// UpdateTaskWithContext is the context-aware variant of UpdateTask
+//
+func (service *Service) UpdateTaskWithContext(ctx context.Context, task *Task) (*Task, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateTask")
+	defer span.End()
+
+	return taskResource.Update(ctx, service, task)
+}
+
+// DeleteTask deletes a specific task
+//
+func (service *Service) DeleteTask(taskID int64) *errortools.Error {
+	return service.DeleteTaskWithContext(context.Background(), taskID)
+}
+
+// DeleteTaskWithContext is the context-aware variant of DeleteTask
+//
+func (service *Service) DeleteTaskWithContext(ctx context.Context, taskID int64) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteTask")
+	defer span.End()
 
-	return &tasks, nil
+	return taskResource.Delete(ctx, service, taskID)
 }
@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // TaskCategory stores TaskCategory from Service
@@ -18,66 +16,51 @@ type TaskCategory struct {
 	BackgroundColor string `json:"BACKGROUND_COLOR"`
 }
 
-type GetTaskCategoriesConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var taskCategoryResource = &Resource[TaskCategory]{
+	Endpoint: "TaskCategories",
+	ID:       func(t *TaskCategory) int64 { return t.CategoryID },
 }
 
+type GetTaskCategoriesConfig = PagingConfig
+
 // GetTaskCategories returns all taskCategories
 //
 func (service *Service) GetTaskCategories(config *GetTaskCategoriesConfig) (*[]TaskCategory, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "TaskCategories"
-	taskCategories := []TaskCategory{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		taskCategoriesBatch := []TaskCategory{}
+	return service.GetTaskCategoriesWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &taskCategoriesBatch,
-		}
+// GetTaskCategoriesWithContext is the context-aware variant of GetTaskCategories
+//
+func (service *Service) GetTaskCategoriesWithContext(ctx context.Context, config *GetTaskCategoriesConfig) (*[]TaskCategory, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTaskCategories")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return taskCategoryResource.List(ctx, service, config)
+}
 
-		taskCategories = append(taskCategories, taskCategoriesBatch...)
+// GetTaskCategoriesPage returns a single page of taskCategories and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetTaskCategoriesPage(config *GetTaskCategoriesConfig) (*[]TaskCategory, *Cursor, *errortools.Error) {
+	return service.GetTaskCategoriesPageWithContext(context.Background(), config)
+}
 
-		if len(taskCategoriesBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetTaskCategoriesPageWithContext is the context-aware variant of GetTaskCategoriesPage
+//
+func (service *Service) GetTaskCategoriesPageWithContext(ctx context.Context, config *GetTaskCategoriesConfig) (*[]TaskCategory, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTaskCategoriesPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return taskCategoryResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &taskCategories, nil
-		}
-	}
+// TaskCategoriesSeq returns an iterator over all taskCategories, fetching pages on demand
+//
+func (service *Service) TaskCategoriesSeq(config *GetTaskCategoriesConfig) iter.Seq2[TaskCategory, *errortools.Error] {
+	return service.TaskCategoriesSeqWithContext(context.Background(), config)
+}
 
-	return &taskCategories, nil
+// TaskCategoriesSeqWithContext is the context-aware variant of TaskCategoriesSeq
+//
+func (service *Service) TaskCategoriesSeqWithContext(ctx context.Context, config *GetTaskCategoriesConfig) iter.Seq2[TaskCategory, *errortools.Error] {
+	return seq(ctx, service, "TaskCategoriesSeq", taskCategoryResource, config)
 }
@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -39,149 +37,131 @@ func (t *Team) prepareMarshal() interface{} {
 	}
 }
 
+var teamResource = &Resource[Team]{
+	Endpoint: "Teams",
+	ID:       func(t *Team) int64 { return t.TeamID },
+	Marshal:  func(t *Team) interface{} { return t.prepareMarshal() },
+}
+
 // GetTeam returns a specific team
 //
 func (service *Service) GetTeam(teamID int64) (*Team, *errortools.Error) {
-	team := Team{}
+	return service.GetTeamWithContext(context.Background(), teamID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Teams/%v", teamID)),
-		ResponseModel: &team,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetTeamWithContext is the context-aware variant of GetTeam
+//
+func (service *Service) GetTeamWithContext(ctx context.Context, teamID int64) (*Team, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTeam")
+	defer span.End()
 
-	return &team, nil
+	return teamResource.Get(ctx, service, teamID)
 }
 
 type GetTeamsConfig struct {
 	Skip       *uint64
 	Top        *uint64
+	Cursor     *Cursor
+	NextCursor *Cursor
 	Brief      *bool
 	CountTotal *bool
 }
 
-// GetTeams returns all teams
-//
-func (service *Service) GetTeams(config *GetTeamsConfig) (*[]Team, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Teams"
-	teams := []Team{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
+func (config *GetTeamsConfig) pageConfig(endpoint string) *pageConfig {
+	if config == nil {
+		return newPageConfig(endpoint, nil, nil, nil)
 	}
 
-	params.Set("top", fmt.Sprintf("%v", top))
+	p := newCursorPageConfig(endpoint, config.Skip, config.Top, config.CountTotal, config.Cursor, &config.NextCursor)
+	p.setBrief(config.Brief)
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
+	return p
+}
 
-		teamsBatch := []Team{}
+// GetTeams returns all teams
+//
+func (service *Service) GetTeams(config *GetTeamsConfig) (*[]Team, *errortools.Error) {
+	return service.GetTeamsWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &teamsBatch,
-		}
+// GetTeamsWithContext is the context-aware variant of GetTeams
+//
+func (service *Service) GetTeamsWithContext(ctx context.Context, config *GetTeamsConfig) (*[]Team, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTeams")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return teamResource.List(ctx, service, config)
+}
 
-		teams = append(teams, teamsBatch...)
+// GetTeamsPage returns a single page of teams and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetTeamsPage(config *GetTeamsConfig) (*[]Team, *Cursor, *errortools.Error) {
+	return service.GetTeamsPageWithContext(context.Background(), config)
+}
 
-		if len(teamsBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetTeamsPageWithContext is the context-aware variant of GetTeamsPage
+//
+func (service *Service) GetTeamsPageWithContext(ctx context.Context, config *GetTeamsConfig) (*[]Team, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTeamsPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return teamResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &teams, nil
-		}
-	}
+// TeamsSeq returns an iterator over all teams, fetching pages on demand
+//
+func (service *Service) TeamsSeq(config *GetTeamsConfig) iter.Seq2[Team, *errortools.Error] {
+	return service.TeamsSeqWithContext(context.Background(), config)
+}
 
-	return &teams, nil
+// TeamsSeqWithContext is the context-aware variant of TeamsSeq
+//
+func (service *Service) TeamsSeqWithContext(ctx context.Context, config *GetTeamsConfig) iter.Seq2[Team, *errortools.Error] {
+	return seq(ctx, service, "TeamsSeq", teamResource, config)
 }
 
 // CreateTeam creates a new contract
 //
 func (service *Service) CreateTeam(team *Team) (*Team, *errortools.Error) {
-	if team == nil {
-		return nil, nil
-	}
-
-	teamNew := Team{}
+	return service.CreateTeamWithContext(context.Background(), team)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPost,
-		Url:           service.url("Teams"),
-		BodyModel:     team.prepareMarshal(),
-		ResponseModel: &teamNew,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// CreateTeamWithContext is the context-aware variant of CreateTeam
+//
+func (service *Service) CreateTeamWithContext(ctx context.Context, team *Team) (*Team, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "CreateTeam")
+	defer span.End()
 
-	return &teamNew, nil
+	return teamResource.Create(ctx, service, team)
 }
 
-// UpdateTeam updates an existing contract
+// UpdateTeam updates an existing contract, a team without TeamID is rejected without
+// sending a request
 //
 func (service *Service) UpdateTeam(team *Team) (*Team, *errortools.Error) {
-	if team == nil {
-		return nil, nil
-	}
-
-	teamUpdated := Team{}
+	return service.UpdateTeamWithContext(context.Background(), team)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodPut,
-		Url:           service.url("Teams"),
-		BodyModel:     team.prepareMarshal(),
-		ResponseModel: &teamUpdated,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// UpdateTeamWithContext is the context-aware variant of UpdateTeam
+//
+func (service *Service) UpdateTeamWithContext(ctx context.Context, team *Team) (*Team, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "UpdateTeam")
+	defer span.End()
 
-	return &teamUpdated, nil
+	return teamResource.Update(ctx, service, team)
 }
 
 // DeleteTeam deletes a specific team
 //
 func (service *Service) DeleteTeam(teamID int) *errortools.Error {
-	requestConfig := go_http.RequestConfig{
-		Method: http.MethodDelete,
-		Url:    service.url(fmt.Sprintf("Teams/%v", teamID)),
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return e
-	}
+	return service.DeleteTeamWithContext(context.Background(), teamID)
+}
+
+// DeleteTeamWithContext is the context-aware variant of DeleteTeam
+//
+func (service *Service) DeleteTeamWithContext(ctx context.Context, teamID int) *errortools.Error {
+	ctx, span := service.startSpan(ctx, "DeleteTeam")
+	defer span.End()
 
-	return nil
+	return teamResource.Delete(ctx, service, int64(teamID))
 }
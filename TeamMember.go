@@ -1,12 +1,10 @@
 package insightly
 
 import (
-	"fmt"
-	"net/http"
-	"net/url"
+	"context"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 )
 
 // TeamMember stores TeamMember from Service
@@ -17,66 +15,51 @@ type TeamMember struct {
 	MemberUserID int64 `json:"MEMBER_USER_ID"`
 }
 
-type GetTeamMembersConfig struct {
-	Skip       *uint64
-	Top        *uint64
-	CountTotal *bool
+var teamMemberResource = &Resource[TeamMember]{
+	Endpoint: "TeamMembers",
+	ID:       func(t *TeamMember) int64 { return t.PermissionID },
 }
 
+type GetTeamMembersConfig = PagingConfig
+
 // GetTeamMembers returns all teamMembers
 //
 func (service *Service) GetTeamMembers(config *GetTeamMembersConfig) (*[]TeamMember, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "TeamMembers"
-	teamMembers := []TeamMember{}
-	rowCount := uint64(0)
-	top := defaultTop
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
-
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-
-		teamMembersBatch := []TeamMember{}
+	return service.GetTeamMembersWithContext(context.Background(), config)
+}
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &teamMembersBatch,
-		}
+// GetTeamMembersWithContext is the context-aware variant of GetTeamMembers
+//
+func (service *Service) GetTeamMembersWithContext(ctx context.Context, config *GetTeamMembersConfig) (*[]TeamMember, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTeamMembers")
+	defer span.End()
 
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return teamMemberResource.List(ctx, service, config)
+}
 
-		teamMembers = append(teamMembers, teamMembersBatch...)
+// GetTeamMembersPage returns a single page of teamMembers and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetTeamMembersPage(config *GetTeamMembersConfig) (*[]TeamMember, *Cursor, *errortools.Error) {
+	return service.GetTeamMembersPageWithContext(context.Background(), config)
+}
 
-		if len(teamMembersBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetTeamMembersPageWithContext is the context-aware variant of GetTeamMembersPage
+//
+func (service *Service) GetTeamMembersPageWithContext(ctx context.Context, config *GetTeamMembersConfig) (*[]TeamMember, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetTeamMembersPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return teamMemberResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &teamMembers, nil
-		}
-	}
+// TeamMembersSeq returns an iterator over all teamMembers, fetching pages on demand
+//
+func (service *Service) TeamMembersSeq(config *GetTeamMembersConfig) iter.Seq2[TeamMember, *errortools.Error] {
+	return service.TeamMembersSeqWithContext(context.Background(), config)
+}
 
-	return &teamMembers, nil
+// TeamMembersSeqWithContext is the context-aware variant of TeamMembersSeq
+//
+func (service *Service) TeamMembersSeqWithContext(ctx context.Context, config *GetTeamMembersConfig) iter.Seq2[TeamMember, *errortools.Error] {
+	return seq(ctx, service, "TeamMembersSeq", teamMemberResource, config)
 }
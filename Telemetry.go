@@ -0,0 +1,158 @@
+package insightly
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName string = "github.com/leapforce-libraries/go_insightly"
+
+// telemetry holds the OpenTelemetry tracer and instruments of a Service
+type telemetry struct {
+	tracer      trace.Tracer
+	requests    metric.Int64Counter
+	duration    metric.Float64Histogram
+	rateLimited metric.Int64Counter
+	remaining   metric.Int64Gauge
+}
+
+func newTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (*telemetry, *errortools.Error) {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("insightly.requests",
+		metric.WithDescription("Number of Insightly api requests, including retries"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	duration, err := meter.Float64Histogram("insightly.request.duration",
+		metric.WithDescription("Duration of Insightly api requests"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	rateLimited, err := meter.Int64Counter("insightly.requests.rate_limited",
+		metric.WithDescription("Number of Insightly api requests answered with 429 Too Many Requests"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	remaining, err := meter.Int64Gauge("insightly.rate_limit.remaining",
+		metric.WithDescription("Remaining Insightly api requests as reported by X-RateLimit-Remaining"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, errortools.ErrorMessage(err)
+	}
+
+	return &telemetry{
+		tracer:      tracerProvider.Tracer(instrumentationName),
+		requests:    requests,
+		duration:    duration,
+		rateLimited: rateLimited,
+		remaining:   remaining,
+	}, nil
+}
+
+// startSpan starts the span of a logical call such as GetContacts, the requests
+// it makes are recorded as child spans
+func (service *Service) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return service.telemetry.tracer.Start(ctx, "insightly."+name)
+}
+
+// startRequestSpan starts the span of a single api request including its retries
+func (t *telemetry) startRequestSpan(ctx context.Context, method string, endpoint string, rawUrl string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.String("insightly.endpoint", endpoint),
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err == nil {
+		query := u.Query()
+		if skip, err := strconv.ParseInt(query.Get("skip"), 10, 64); err == nil {
+			attributes = append(attributes, attribute.Int64("insightly.skip", skip))
+		}
+		if top, err := strconv.ParseInt(query.Get("top"), 10, 64); err == nil {
+			attributes = append(attributes, attribute.Int64("insightly.top", top))
+		}
+	}
+
+	return t.tracer.Start(ctx, method+" "+route(endpoint),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// endRequestSpan ends the span of an api request, a failed request also marks the
+// span of the logical call as failed
+func (t *telemetry) endRequestSpan(parent context.Context, span trace.Span, response *http.Response, retries int, e *errortools.Error) {
+	if response != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+	}
+	span.SetAttributes(attribute.Int("insightly.retries", retries))
+
+	if e != nil {
+		span.SetStatus(codes.Error, e.Message())
+		trace.SpanFromContext(parent).SetStatus(codes.Error, e.Message())
+	}
+
+	span.End()
+}
+
+// recordAttempt records the metrics of a single attempt of an api request
+func (t *telemetry) recordAttempt(ctx context.Context, method string, endpoint string, statusCode int, duration time.Duration, rateLimit RateLimit) {
+	attributes := metric.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("insightly.route", route(endpoint)),
+		attribute.Int("http.response.status_code", statusCode),
+	)
+
+	t.requests.Add(ctx, 1, attributes)
+	t.duration.Record(ctx, duration.Seconds(), attributes)
+
+	if statusCode == http.StatusTooManyRequests {
+		t.rateLimited.Add(ctx, 1, attributes)
+	}
+
+	if rateLimit.Remaining != nil {
+		t.remaining.Record(ctx, *rateLimit.Remaining)
+	}
+}
+
+// route replaces the ids in endpoint by {id} to keep span names and metric
+// attributes low in cardinality, e.g. "Contacts/123/Links" becomes "Contacts/{id}/Links"
+func route(endpoint string) string {
+	segments := strings.Split(endpoint, "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,280 @@
+package insightly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTelemetryService returns a Service recording its spans and metrics, configure
+// may change the config further
+//
+func newTelemetryService(t *testing.T, configure func(config *insightly.ServiceConfig)) (*insightly.Service, *insightlytest.Server, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	spanRecorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	service, server := newTestService(t, func(config *insightly.ServiceConfig) {
+		config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+		config.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		if configure != nil {
+			configure(config)
+		}
+	})
+
+	return service, server, spanRecorder, reader
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	return names
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+// collect returns the metrics recorded so far by name
+//
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	resourceMetrics := metricdata.ResourceMetrics{}
+	err := reader.Collect(context.Background(), &resourceMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	return metrics
+}
+
+// sumByStatus returns the values of a counter by http.response.status_code
+//
+func sumByStatus(t *testing.T, data metricdata.Aggregation) map[int64]int64 {
+	t.Helper()
+
+	sum, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("got %T, want an int64 sum", data)
+	}
+
+	values := map[int64]int64{}
+	for _, point := range sum.DataPoints {
+		statusCode, _ := point.Attributes.Value("http.response.status_code")
+		values[statusCode.AsInt64()] += point.Value
+	}
+
+	return values
+}
+
+func TestTelemetrySpans(t *testing.T) {
+	service, server, spanRecorder, _ := newTelemetryService(t, nil)
+	ids := server.Seed("Contacts", insightly.Contact{})
+
+	_, e := service.GetContact(ids[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	spans := spanRecorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got spans %v, want 2", spanNames(spans))
+	}
+
+	request, call := spans[0], spans[1]
+	if call.Name() != "insightly.GetContact" {
+		t.Errorf("got span %s, want insightly.GetContact", call.Name())
+	}
+	if request.Name() != "GET Contacts/{id}" {
+		t.Errorf("got span %s, want GET Contacts/{id}", request.Name())
+	}
+	if request.SpanKind() != trace.SpanKindClient {
+		t.Errorf("got span kind %v, want client", request.SpanKind())
+	}
+	if request.Parent().SpanID() != call.SpanContext().SpanID() || request.SpanContext().TraceID() != call.SpanContext().TraceID() {
+		t.Error("expected the request span to be a child of the call span")
+	}
+	if call.Parent().IsValid() {
+		t.Error("expected the call span to be a root span")
+	}
+
+	if statusCode, _ := spanAttribute(request, "http.response.status_code"); statusCode.AsInt64() != http.StatusOK {
+		t.Errorf("got status code %v, want 200", statusCode.AsInt64())
+	}
+	if endpoint, _ := spanAttribute(request, "insightly.endpoint"); endpoint.AsString() != "Contacts/1" {
+		t.Errorf("got endpoint %s, want Contacts/1", endpoint.AsString())
+	}
+}
+
+func TestTelemetrySeqSpans(t *testing.T) {
+	service, server, spanRecorder, _ := newTelemetryService(t, nil)
+	server.Seed("Contacts", insightly.Contact{}, insightly.Contact{}, insightly.Contact{})
+
+	top := uint64(2)
+	for _, e := range service.ContactsSeq(&insightly.GetContactsConfig{Top: &top}) {
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+	}
+
+	spans := spanRecorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got spans %v, want 2 requests and the call", spanNames(spans))
+	}
+
+	call := spans[2]
+	if call.Name() != "insightly.ContactsSeq" {
+		t.Errorf("got span %s, want insightly.ContactsSeq", call.Name())
+	}
+
+	for i, request := range spans[:2] {
+		if request.Parent().SpanID() != call.SpanContext().SpanID() {
+			t.Errorf("expected request %v to be a child of the call span", i)
+		}
+		if skip, _ := spanAttribute(request, "insightly.skip"); skip.AsInt64() != int64(i*2) {
+			t.Errorf("got skip %v for request %v, want %v", skip.AsInt64(), i, i*2)
+		}
+	}
+}
+
+func TestTelemetryRetries(t *testing.T) {
+	service, server, spanRecorder, reader := newTelemetryService(t, func(config *insightly.ServiceConfig) {
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:      2,
+			InitialInterval: time.Millisecond,
+			Multiplier:      1,
+			StatusCodes:     []int{http.StatusServiceUnavailable},
+		}
+	})
+	server.FailNext(2, http.StatusServiceUnavailable)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	spans := spanRecorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got spans %v, want 2", spanNames(spans))
+	}
+
+	request := spans[0]
+	if retries, _ := spanAttribute(request, "insightly.retries"); retries.AsInt64() != 2 {
+		t.Errorf("got %v retries, want 2", retries.AsInt64())
+	}
+	retryEvents := 0
+	for _, event := range request.Events() {
+		if event.Name == "retry" {
+			retryEvents++
+		}
+	}
+	if retryEvents != 2 {
+		t.Errorf("got %v retry events, want 2", retryEvents)
+	}
+	if request.Status().Code == codes.Error {
+		t.Error("expected the request span not to fail after a successful retry")
+	}
+
+	metrics := collect(t, reader)
+
+	requests := sumByStatus(t, metrics["insightly.requests"])
+	if requests[http.StatusServiceUnavailable] != 2 || requests[http.StatusOK] != 1 {
+		t.Errorf("got requests %v, want 2 with status 503 and 1 with status 200", requests)
+	}
+
+	histogram, ok := metrics["insightly.request.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("got %T, want a float64 histogram", metrics["insightly.request.duration"])
+	}
+	count := uint64(0)
+	for _, point := range histogram.DataPoints {
+		count += point.Count
+	}
+	if count != 3 {
+		t.Errorf("got %v durations, want 3", count)
+	}
+}
+
+func TestTelemetryRateLimit(t *testing.T) {
+	service, server, _, reader := newTelemetryService(t, func(config *insightly.ServiceConfig) {
+		config.RetryPolicy = &insightly.RetryPolicy{
+			MaxRetries:  1,
+			StatusCodes: []int{http.StatusTooManyRequests},
+		}
+	})
+	server.SetRateLimit(10, 5, time.Minute)
+	server.Throttle(1, 0)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	metrics := collect(t, reader)
+
+	rateLimited := sumByStatus(t, metrics["insightly.requests.rate_limited"])
+	if rateLimited[http.StatusTooManyRequests] != 1 || len(rateLimited) != 1 {
+		t.Errorf("got rate limited requests %v, want 1 with status 429", rateLimited)
+	}
+
+	gauge, ok := metrics["insightly.rate_limit.remaining"].(metricdata.Gauge[int64])
+	if !ok {
+		t.Fatalf("got %T, want an int64 gauge", metrics["insightly.rate_limit.remaining"])
+	}
+	if len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 4 {
+		t.Errorf("got remaining %v, want 4", gauge.DataPoints)
+	}
+}
+
+func TestTelemetryFailedRequest(t *testing.T) {
+	service, _, spanRecorder, reader := newTelemetryService(t, nil)
+
+	_, e := service.GetContact(42)
+	if e == nil {
+		t.Fatal("expected an error for a missing contact")
+	}
+
+	spans := spanRecorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got spans %v, want 2", spanNames(spans))
+	}
+	for _, span := range spans {
+		if span.Status().Code != codes.Error {
+			t.Errorf("expected span %s to have failed", span.Name())
+		}
+	}
+
+	requests := sumByStatus(t, collect(t, reader)["insightly.requests"])
+	if requests[http.StatusNotFound] != 1 {
+		t.Errorf("got requests %v, want 1 with status 404", requests)
+	}
+}
@@ -1,13 +1,11 @@
 package insightly
 
 import (
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
-	"time"
+	"iter"
 
 	errortools "github.com/leapforce-libraries/go_errortools"
-	go_http "github.com/leapforce-libraries/go_http"
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -34,104 +32,68 @@ type User struct {
 	RoleID                 *int64                 `json:"ROLE_ID"`
 }
 
+var userResource = &Resource[User]{
+	Endpoint: "Users",
+	ID:       func(u *User) int64 { return u.UserID },
+}
+
 // GetUser returns a specific user
 //
 func (service *Service) GetUser(userID int64) (*User, *errortools.Error) {
-	user := User{}
+	return service.GetUserWithContext(context.Background(), userID)
+}
 
-	requestConfig := go_http.RequestConfig{
-		Method:        http.MethodGet,
-		Url:           service.url(fmt.Sprintf("Users/%v", userID)),
-		ResponseModel: &user,
-	}
-	_, _, e := service.httpRequest(&requestConfig)
-	if e != nil {
-		return nil, e
-	}
+// GetUserWithContext is the context-aware variant of GetUser
+//
+func (service *Service) GetUserWithContext(ctx context.Context, userID int64) (*User, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetUser")
+	defer span.End()
 
-	return &user, nil
+	return userResource.Get(ctx, service, userID)
 }
 
-type GetUsersConfig struct {
-	Skip         *uint64
-	Top          *uint64
-	Brief        *bool
-	CountTotal   *bool
-	UpdatedAfter *time.Time
-	FieldFilter  *FieldFilter
-}
+type GetUsersConfig = ListConfig
 
 // GetUsers returns all users
 //
 func (service *Service) GetUsers(config *GetUsersConfig) (*[]User, *errortools.Error) {
-	params := url.Values{}
-
-	endpoint := "Users"
-	users := []User{}
-	rowCount := uint64(0)
-	top := defaultTop
-	isSearch := false
-
-	if config != nil {
-		if config.Top != nil {
-			top = *config.Top
-		}
-		if config.Skip != nil {
-			service.nextSkips[endpoint] = *config.Skip
-		}
-		if config.Brief != nil {
-			params.Set("brief", fmt.Sprintf("%v", *config.Brief))
-		}
-		if config.CountTotal != nil {
-			params.Set("count_total", fmt.Sprintf("%v", *config.CountTotal))
-		}
-		if config.UpdatedAfter != nil {
-			isSearch = true
-			params.Set("updated_after_utc", fmt.Sprintf("%v", config.UpdatedAfter.Format(dateTimeFormat)))
-		}
-		if config.FieldFilter != nil {
-			isSearch = true
-			params.Set("field_name", config.FieldFilter.FieldName)
-			params.Set("field_value", config.FieldFilter.FieldValue)
-		}
-	}
-
-	if isSearch {
-		endpoint += "/Search"
-	}
-
-	params.Set("top", fmt.Sprintf("%v", top))
+	return service.GetUsersWithContext(context.Background(), config)
+}
 
-	for {
-		params.Set("skip", fmt.Sprintf("%v", service.nextSkips[endpoint]))
-		usersBatch := []User{}
+// GetUsersWithContext is the context-aware variant of GetUsers
+//
+func (service *Service) GetUsersWithContext(ctx context.Context, config *GetUsersConfig) (*[]User, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetUsers")
+	defer span.End()
 
-		requestConfig := go_http.RequestConfig{
-			Method:        http.MethodGet,
-			Url:           service.url(fmt.Sprintf("%s?%s", endpoint, params.Encode())),
-			ResponseModel: &usersBatch,
-		}
-		_, _, e := service.httpRequest(&requestConfig)
-		if e != nil {
-			return nil, e
-		}
+	return userResource.List(ctx, service, config)
+}
 
-		users = append(users, usersBatch...)
+// GetUsersPage returns a single page of users and the Cursor of the next page, which is nil after the last page
+//
+func (service *Service) GetUsersPage(config *GetUsersConfig) (*[]User, *Cursor, *errortools.Error) {
+	return service.GetUsersPageWithContext(context.Background(), config)
+}
 
-		if len(usersBatch) < int(top) {
-			delete(service.nextSkips, endpoint)
-			break
-		}
+// GetUsersPageWithContext is the context-aware variant of GetUsersPage
+//
+func (service *Service) GetUsersPageWithContext(ctx context.Context, config *GetUsersConfig) (*[]User, *Cursor, *errortools.Error) {
+	ctx, span := service.startSpan(ctx, "GetUsersPage")
+	defer span.End()
 
-		service.nextSkips[endpoint] += top
-		rowCount += top
+	return userResource.Page(ctx, service, config)
+}
 
-		if rowCount >= service.maxRowCount {
-			return &users, nil
-		}
-	}
+// UsersSeq returns an iterator over all users, fetching pages on demand
+//
+func (service *Service) UsersSeq(config *GetUsersConfig) iter.Seq2[User, *errortools.Error] {
+	return service.UsersSeqWithContext(context.Background(), config)
+}
 
-	return &users, nil
+// UsersSeqWithContext is the context-aware variant of UsersSeq
+//
+func (service *Service) UsersSeqWithContext(ctx context.Context, config *GetUsersConfig) iter.Seq2[User, *errortools.Error] {
+	return seq(ctx, service, "UsersSeq", userResource, config)
 }
 
 func (u *User) FullName() string {
@@ -1,6 +1,8 @@
 package insightly
 
 import (
+	"time"
+
 	i_types "github.com/leapforce-libraries/go_insightly/types"
 )
 
@@ -11,3 +13,12 @@ type Date struct {
 	RepeatYearly     bool                   `json:"REPEAT_YEARLY"`
 	CreateTaskYearly bool                   `json:"CREATE_TASK_YEARLY"`
 }
+
+// dateTimeStringPtr returns nil for the zero time, so it is left out of request bodies
+func dateTimeStringPtr(d i_types.DateTimeString) *i_types.DateTimeString {
+	if time.Time(d).IsZero() {
+		return nil
+	}
+
+	return &d
+}
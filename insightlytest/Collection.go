@@ -0,0 +1,89 @@
+package insightlytest
+
+import (
+	"strings"
+)
+
+// collection describes an endpoint of the Insightly API that stores records
+//
+type collection struct {
+	Name       string
+	IdField    string
+	ObjectName string
+	Aliases    []string
+}
+
+var collections = []collection{
+	{Name: "Contacts", IdField: "CONTACT_ID", ObjectName: "Contact"},
+	{Name: "Organisations", IdField: "ORGANISATION_ID", ObjectName: "Organisation"},
+	{Name: "Leads", IdField: "LEAD_ID", ObjectName: "Lead"},
+	{Name: "Opportunities", IdField: "OPPORTUNITY_ID", ObjectName: "Opportunity"},
+	{Name: "Projects", IdField: "PROJECT_ID", ObjectName: "Project", Aliases: []string{"Project"}},
+	{Name: "Tasks", IdField: "TASK_ID", ObjectName: "Task"},
+	{Name: "Events", IdField: "EVENT_ID", ObjectName: "Event"},
+	{Name: "Notes", IdField: "NOTE_ID", ObjectName: "Note"},
+	{Name: "Milestones", IdField: "MILESTONE_ID", ObjectName: "Milestone"},
+	{Name: "Emails", IdField: "EMAIL_ID", ObjectName: "Email"},
+	{Name: "Products", IdField: "PRODUCT_ID", ObjectName: "Product", Aliases: []string{"Product"}},
+	{Name: "Pricebook", IdField: "PRICEBOOK_ID", ObjectName: "Pricebook", Aliases: []string{"Pricebooks"}},
+	{Name: "PricebookEntry", IdField: "PRICEBOOK_ENTRY_ID", ObjectName: "PricebookEntry", Aliases: []string{"PricebookEntries"}},
+	{Name: "Prospect", IdField: "PROSPECT_ID", ObjectName: "Prospect", Aliases: []string{"Prospects"}},
+	{Name: "Quotation", IdField: "QUOTE_ID", ObjectName: "Quotation", Aliases: []string{"Quotations"}},
+	{Name: "QuotationLineItem", IdField: "QUOTATION_ITEM_ID", ObjectName: "QuotationLineItem"},
+	{Name: "OpportunityLineItem", IdField: "OPPORTUNITY_ITEM_ID", ObjectName: "OpportunityLineItem"},
+	{Name: "Teams", IdField: "TEAM_ID", ObjectName: "Team"},
+	{Name: "TeamMembers", IdField: "PERMISSION_ID", ObjectName: "TeamMember"},
+	{Name: "Users", IdField: "USER_ID", ObjectName: "User"},
+	{Name: "ActivitySets", IdField: "ACTIVITYSET_ID", ObjectName: "ActivitySet"},
+	{Name: "LeadSources", IdField: "LEAD_SOURCE_ID", ObjectName: "LeadSource"},
+	{Name: "LeadStatuses", IdField: "LEAD_STATUS_ID", ObjectName: "LeadStatus"},
+	{Name: "Pipelines", IdField: "PIPELINE_ID", ObjectName: "Pipeline"},
+	{Name: "PipelineStages", IdField: "STAGE_ID", ObjectName: "PipelineStage"},
+	{Name: "Relationships", IdField: "RELATIONSHIP_ID", ObjectName: "Relationship"},
+	{Name: "OpportunityCategories", IdField: "CATEGORY_ID", ObjectName: "OpportunityCategory"},
+	{Name: "OpportunityStateReasons", IdField: "STATE_REASON_ID", ObjectName: "OpportunityStateReason"},
+	{Name: "ProjectCategories", IdField: "CATEGORY_ID", ObjectName: "ProjectCategory"},
+	{Name: "TaskCategories", IdField: "CATEGORY_ID", ObjectName: "TaskCategory"},
+	{Name: "FileCategories", IdField: "CATEGORY_ID", ObjectName: "FileCategory"},
+}
+
+// customObjectSuffix marks the endpoints of custom objects
+//
+const customObjectSuffix string = "__c"
+
+// lookupCollection returns the collection for an endpoint name, matched case-insensitively
+//
+func lookupCollection(name string) (*collection, bool) {
+	for i, c := range collections {
+		if strings.EqualFold(c.Name, name) {
+			return &collections[i], true
+		}
+		for _, alias := range c.Aliases {
+			if strings.EqualFold(alias, name) {
+				return &collections[i], true
+			}
+		}
+	}
+
+	if strings.HasSuffix(name, customObjectSuffix) {
+		return &collection{Name: name, IdField: "RECORD_ID", ObjectName: name}, true
+	}
+
+	return nil, false
+}
+
+// lookupObjectName returns the collection for an OBJECT_NAME as used in links
+//
+func lookupObjectName(objectName string) (*collection, bool) {
+	for i, c := range collections {
+		if strings.EqualFold(c.ObjectName, objectName) {
+			return &collections[i], true
+		}
+	}
+
+	return lookupCollection(objectName)
+}
+
+// briefFields are left out of records when brief=true is requested
+//
+var briefFields = []string{"CUSTOMFIELDS", "TAGS", "LINKS", "DATES", "EMAILDOMAINS"}
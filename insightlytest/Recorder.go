@@ -0,0 +1,347 @@
+package insightlytest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	errortools "github.com/leapforce-libraries/go_errortools"
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+// RecorderMode selects whether a Recorder records or replays interactions
+//
+type RecorderMode int
+
+const (
+	// ModeRecord sends requests to the api and writes them to the cassette
+	ModeRecord RecorderMode = iota
+	// ModeReplay serves responses from the cassette without network access
+	ModeReplay
+)
+
+const redacted string = "REDACTED"
+
+// scrubbedHeaders are never written to a cassette
+//
+var scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is a request/response pair as stored on one line of a cassette
+//
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of an Interaction, with credentials scrubbed
+//
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Url    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an Interaction
+//
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records api interactions to a JSONL cassette
+// or replays them from it, set it as ServiceConfig.Transport:
+//
+//	recorder, e := insightlytest.NewRecorder("testdata/opportunities.jsonl", insightlytest.ModeReplay, nil)
+//	...
+//	defer recorder.Close()
+//	service, e := insightly.NewService(&insightly.ServiceConfig{Pod: "na1", ApiKey: "replay", Transport: recorder})
+//
+// Authorization and cookie headers are scrubbed before writing. In replay mode requests
+// are matched on method, path, query and body, not on the host of the pod, each recorded
+// interaction is served once in the order it was recorded. Requests without a matching
+// interaction are answered with 501 Not Implemented.
+//
+type Recorder struct {
+	mode         RecorderMode
+	transport    http.RoundTripper
+	mutex        sync.Mutex
+	file         *os.File
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder opens the cassette at path, which is truncated in ModeRecord, transport
+// is used to send requests in ModeRecord and defaults to http.DefaultTransport
+//
+func NewRecorder(path string, mode RecorderMode, transport http.RoundTripper) (*Recorder, *errortools.Error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	recorder := Recorder{
+		mode:      mode,
+		transport: transport,
+	}
+
+	switch mode {
+	case ModeRecord:
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, errortools.ErrorMessage(err)
+		}
+		recorder.file = file
+	case ModeReplay:
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errortools.ErrorMessage(err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 64*1024*1024)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			interaction := Interaction{}
+			err = json.Unmarshal(scanner.Bytes(), &interaction)
+			if err != nil {
+				return nil, errortools.ErrorMessagef("Invalid interaction in cassette %s: %s", path, err.Error())
+			}
+			recorder.interactions = append(recorder.interactions, interaction)
+		}
+		if scanner.Err() != nil {
+			return nil, errortools.ErrorMessage(scanner.Err())
+		}
+		recorder.used = make([]bool, len(recorder.interactions))
+	default:
+		return nil, errortools.ErrorMessagef("Invalid RecorderMode %v", mode)
+	}
+
+	return &recorder, nil
+}
+
+// Close closes the cassette
+//
+func (r *Recorder) Close() *errortools.Error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return errortools.ErrorMessage(err)
+	}
+
+	return nil
+}
+
+// Unused returns the recorded interactions that have not been replayed
+//
+func (r *Recorder) Unused() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	unused := []Interaction{}
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+
+	return unused
+}
+
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	request, requestBody, err := readBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		return r.replay(request, requestBody)
+	}
+
+	response, err := r.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: request.Method,
+			Url:    request.URL.String(),
+			Header: scrub(request.Header),
+			Body:   string(requestBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     scrub(response.Header),
+			Body:       string(responseBody),
+		},
+	}
+
+	b, err := json.Marshal(interaction)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil, fmt.Errorf("recorder is closed")
+	}
+	_, err = r.file.Write(append(b, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (r *Recorder) replay(request *http.Request, requestBody []byte) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		if !interaction.Request.matches(request, requestBody) {
+			continue
+		}
+
+		r.used[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%v %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       request,
+		}, nil
+	}
+
+	// answer with a status code that is not retried rather than with a network error
+	body, err := json.Marshal(insightly.ErrorResponse{
+		Name:    http.StatusText(http.StatusNotImplemented),
+		Message: fmt.Sprintf("No recorded interaction for %s %s", request.Method, request.URL.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%v %s", http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented)),
+		StatusCode:    http.StatusNotImplemented,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// readBody returns the body of request without modifying request, as RoundTrip may not.
+// If the body cannot be read through GetBody, a clone of request with a new body is
+// returned to be sent instead.
+//
+func readBody(request *http.Request) (*http.Request, []byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return request, nil, nil
+	}
+
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			request.Body.Close()
+			return nil, nil, err
+		}
+		defer body.Close()
+
+		b, err := io.ReadAll(body)
+		if err != nil {
+			request.Body.Close()
+			return nil, nil, err
+		}
+
+		return request, b, nil
+	}
+
+	b, err := io.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clone := request.Clone(request.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(b))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return clone, b, nil
+}
+
+// matches reports whether request is the recorded request, the host is ignored so a
+// cassette recorded against one pod can be replayed against another
+//
+func (recorded *RecordedRequest) matches(request *http.Request, requestBody []byte) bool {
+	if recorded.Method != request.Method || recorded.Body != string(requestBody) {
+		return false
+	}
+
+	recordedUrl, err := url.Parse(recorded.Url)
+	if err != nil {
+		return false
+	}
+
+	return recordedUrl.Path == request.URL.Path && recordedUrl.Query().Encode() == request.URL.Query().Encode()
+}
+
+// scrub returns a copy of header with credentials redacted
+//
+func scrub(header http.Header) http.Header {
+	scrubbed := header.Clone()
+	for _, key := range scrubbedHeaders {
+		if scrubbed.Get(key) != "" {
+			scrubbed.Set(key, redacted)
+		}
+	}
+
+	return scrubbed
+}
@@ -0,0 +1,188 @@
+package insightlytest_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+func newReplayService(t *testing.T, path string) (*insightly.Service, *insightlytest.Recorder) {
+	t.Helper()
+
+	recorder, e := insightlytest.NewRecorder(path, insightlytest.ModeReplay, nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	t.Cleanup(func() { recorder.Close() })
+
+	service, e := insightly.NewService(&insightly.ServiceConfig{
+		Pod:         "eu1",
+		ApiKey:      "replay",
+		Transport:   recorder,
+		RetryPolicy: &insightly.RetryPolicy{},
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	return service, recorder
+}
+
+func TestReplayOpportunities(t *testing.T) {
+	// the cassette was recorded on pod na1, replay ignores the host
+	service, recorder := newReplayService(t, "testdata/opportunities.jsonl")
+
+	opportunities, e := service.GetOpportunities(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*opportunities) != 2 || *(*opportunities)[0].OpportunityName != "Website redesign" || *(*opportunities)[1].StageID != 32 {
+		t.Errorf("got %v opportunities, want the two recorded ones", len(*opportunities))
+	}
+
+	opportunity, e := service.UpdateOpportunityPipeline(4021, &insightly.OpportunityPipeline{
+		PipelineID:          12,
+		PipelineStateChange: insightly.OpportunityPipelineStageChange{StageID: 33},
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if opportunity.OpportunityID != 4021 || *opportunity.PipelineID != 12 || *opportunity.StageID != 33 {
+		t.Errorf("got opportunity %v in stage %v, want 4021 in stage 33", opportunity.OpportunityID, *opportunity.StageID)
+	}
+
+	if unused := recorder.Unused(); len(unused) != 0 {
+		t.Errorf("got %v unused interactions, want 0", len(unused))
+	}
+}
+
+func TestReplayUnmatchedRequest(t *testing.T) {
+	service, _ := newReplayService(t, "testdata/opportunities.jsonl")
+
+	// a different stage makes a different body
+	_, e := service.UpdateOpportunityPipeline(4021, &insightly.OpportunityPipeline{
+		PipelineID:          12,
+		PipelineStateChange: insightly.OpportunityPipelineStageChange{StageID: 34},
+	})
+	if e == nil {
+		t.Fatal("expected an error for a request that was not recorded")
+	}
+
+	if response := e.Response(); response == nil || response.StatusCode != http.StatusNotImplemented {
+		t.Errorf("got %s, want 501 Not Implemented", e.Message())
+	}
+}
+
+func TestRecord(t *testing.T) {
+	server := insightlytest.NewServer()
+	t.Cleanup(server.Close)
+	ids := server.Seed("Opportunities", insightly.Opportunity{OpportunityName: ptr("Website redesign")})
+
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recorder, e := insightlytest.NewRecorder(path, insightlytest.ModeRecord, nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	config := server.ServiceConfig()
+	config.Transport = recorder
+
+	service, e := insightly.NewService(config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	_, e = service.UpdateOpportunityPipeline(ids[0], &insightly.OpportunityPipeline{
+		PipelineID:          12,
+		PipelineStateChange: insightly.OpportunityPipelineStageChange{StageID: 33},
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	e = recorder.Close()
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(b)
+
+	if strings.Contains(cassette, insightlytest.ApiKey) || !strings.Contains(cassette, "REDACTED") {
+		t.Error("expected the Authorization header to be scrubbed")
+	}
+	if !strings.Contains(cassette, `{\"PIPELINE_ID\":12,\"PIPELINE_STAGE_CHANGE\":{\"STAGE_ID\":33}}`) {
+		t.Error("expected the request body to be recorded")
+	}
+}
+
+// bodyReader is a request body that records whether it was closed
+//
+type bodyReader struct {
+	io.Reader
+	closed bool
+}
+
+func (b *bodyReader) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRecordDoesNotModifyRequest(t *testing.T) {
+	server := insightlytest.NewServer()
+	t.Cleanup(server.Close)
+
+	recorder, e := insightlytest.NewRecorder(filepath.Join(t.TempDir(), "cassette.jsonl"), insightlytest.ModeRecord, nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+	t.Cleanup(func() { recorder.Close() })
+
+	for _, withGetBody := range []bool{true, false} {
+		body := &bodyReader{Reader: strings.NewReader(`{"FIRST_NAME":"a"}`)}
+
+		request, err := http.NewRequest(http.MethodPost, server.URL()+"/Contacts", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Authorization", "Basic "+base64.URLEncoding.EncodeToString([]byte(insightlytest.ApiKey)))
+		if withGetBody {
+			request.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader([]byte(`{"FIRST_NAME":"a"}`))), nil
+			}
+		}
+
+		response, err := recorder.RoundTrip(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("got status %v, want 200", response.StatusCode)
+		}
+		if request.Body != body {
+			t.Errorf("GetBody %v: the body of the request was replaced", withGetBody)
+		}
+		if !body.closed {
+			t.Errorf("GetBody %v: the body of the request was not closed", withGetBody)
+		}
+	}
+
+	if server.Count("Contacts") != 2 {
+		t.Errorf("got %v contacts, want 2", server.Count("Contacts"))
+	}
+}
@@ -0,0 +1,949 @@
+// Package insightlytest provides an in-memory fake of the Insightly v3.1 API for
+// testing code that uses the insightly package without an Insightly account.
+//
+// The fake supports list requests with skip/top paging, count_total and brief mode,
+// the /Search endpoints with field_name/field_value and updated_after_utc, the
+// /SearchByTag endpoints, getting, creating, updating and deleting records, Links, Tags
+// and the opportunity Pipeline endpoint. Rate limiting and failures can be simulated
+// deterministically with SetRateLimit, OmitRetryAfter, Throttle and FailNext.
+//
+package insightlytest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+)
+
+const (
+	// ApiKey is the api key the server accepts
+	ApiKey               string = "insightlytest"
+	apiPath              string = "/v3.1"
+	dateTimeFormat       string = "2006-01-02 15:04:05"
+	searchDateTimeFormat string = "2006-01-02T15:04:05Z"
+	defaultTop           int    = 100
+	maxTop               int    = 500
+)
+
+// Request stores a request received by the Server
+//
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+type rateLimit struct {
+	limit      int64
+	remaining  int64
+	retryAfter time.Duration
+	resetAt    time.Time
+}
+
+// Server is an in-memory fake of the Insightly v3.1 API, it is safe for concurrent use
+//
+type Server struct {
+	server             *httptest.Server
+	mutex              sync.Mutex
+	now                func() time.Time
+	records            map[string]map[int64]map[string]any
+	nextIDs            map[string]int64
+	links              []map[string]any
+	nextLinkID         int64
+	requests           []Request
+	rateLimit          *rateLimit
+	omitRetryAfter     bool
+	throttleCount      int
+	throttleRetryAfter time.Duration
+	failCount          int
+	failStatusCode     int
+}
+
+// NewServer starts a new Server, call Close when done
+//
+func NewServer() *Server {
+	s := Server{
+		now:        time.Now,
+		records:    make(map[string]map[int64]map[string]any),
+		nextIDs:    make(map[string]int64),
+		nextLinkID: 1,
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return &s
+}
+
+// Close shuts down the Server
+//
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base url of the fake api, to be used as ServiceConfig.BaseUrl
+//
+func (s *Server) URL() string {
+	return s.server.URL + apiPath
+}
+
+// ServiceConfig returns a ServiceConfig pointing at the Server
+//
+func (s *Server) ServiceConfig() *insightly.ServiceConfig {
+	baseUrl := s.URL()
+
+	return &insightly.ServiceConfig{
+		ApiKey:  ApiKey,
+		BaseUrl: &baseUrl,
+	}
+}
+
+// SetNow replaces the clock of the Server, used for DATE_CREATED_UTC, DATE_UPDATED_UTC
+// and rate limit resets
+//
+func (s *Server) SetNow(now func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.now = now
+}
+
+// Seed stores records in a collection, e.g. Seed("Contacts", insightly.Contact{...}),
+// and returns their ids; records without id get the next free id
+//
+func (s *Server) Seed(collectionName string, records ...any) []int64 {
+	c, ok := lookupCollection(collectionName)
+	if !ok {
+		panic(fmt.Sprintf("insightlytest: unknown collection %s", collectionName))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := []int64{}
+	for _, record := range records {
+		b, err := json.Marshal(record)
+		if err != nil {
+			panic(fmt.Sprintf("insightlytest: %v", err))
+		}
+		r, err := decodeRecord(b)
+		if err != nil {
+			panic(fmt.Sprintf("insightlytest: %v", err))
+		}
+
+		ids = append(ids, s.store(c, r))
+	}
+
+	return ids
+}
+
+// SeedLink stores a link between two records and returns its id
+//
+func (s *Server) SeedLink(link insightly.Link) int64 {
+	b, err := json.Marshal(link)
+	if err != nil {
+		panic(fmt.Sprintf("insightlytest: %v", err))
+	}
+	l, err := decodeRecord(b)
+	if err != nil {
+		panic(fmt.Sprintf("insightlytest: %v", err))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.storeLink(l)
+}
+
+// Get unmarshals a stored record into v and reports whether it exists
+//
+func (s *Server) Get(collectionName string, id int64, v any) bool {
+	c, ok := lookupCollection(collectionName)
+	if !ok {
+		return false
+	}
+
+	s.mutex.Lock()
+	record, ok := s.records[c.Name][id]
+	if ok {
+		record = s.output(c, record, false)
+	}
+	s.mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(b, v) == nil
+}
+
+// Count returns the number of records in a collection
+//
+func (s *Server) Count(collectionName string) int {
+	c, ok := lookupCollection(collectionName)
+	if !ok {
+		return 0
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.records[c.Name])
+}
+
+// SetRateLimit makes the Server send X-RateLimit-Limit and X-RateLimit-Remaining headers.
+// Once remaining reaches zero requests are answered with 429 and a Retry-After header
+// until retryAfter has passed on the clock of the Server, then remaining is reset to limit.
+//
+func (s *Server) SetRateLimit(limit int64, remaining int64, retryAfter time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rateLimit = &rateLimit{
+		limit:      limit,
+		remaining:  remaining,
+		retryAfter: retryAfter,
+		resetAt:    s.now().Add(retryAfter),
+	}
+}
+
+// OmitRetryAfter makes successful responses that use up the rate limit of SetRateLimit
+// leave out the Retry-After header, 429 responses still send it
+//
+func (s *Server) OmitRetryAfter(omit bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.omitRetryAfter = omit
+}
+
+// Throttle answers the next count requests with 429 and a Retry-After header
+//
+func (s *Server) Throttle(count int, retryAfter time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.throttleCount = count
+	s.throttleRetryAfter = retryAfter
+}
+
+// FailNext answers the next count requests with statusCode, e.g. http.StatusServiceUnavailable
+//
+func (s *Server) FailNext(count int, statusCode int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failCount = count
+	s.failStatusCode = statusCode
+}
+
+// Requests returns the requests received so far
+//
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// ResetRequests clears the received requests
+//
+func (s *Server) ResetRequests() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Body:   body,
+	})
+
+	if r.Header.Get("Authorization") != "Basic "+base64.URLEncoding.EncodeToString([]byte(ApiKey)) {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "Invalid API key")
+		return
+	}
+
+	if !s.allow(w) {
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, apiPath+"/") {
+		writeError(w, http.StatusNotFound, "Not Found", "Unknown endpoint")
+		return
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPath), "/"), "/")
+
+	if strings.EqualFold(segments[0], "Tags") && len(segments) == 1 && r.Method == http.MethodGet {
+		s.getTags(w, r)
+		return
+	}
+	if strings.EqualFold(segments[0], "Instance") && len(segments) == 1 && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]any{"INSTANCE_NAME": "insightlytest", "PLAN_NAME": "Professional"})
+		return
+	}
+
+	c, ok := lookupCollection(segments[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Unknown endpoint %s", segments[0]))
+		return
+	}
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r, c, false)
+		case http.MethodPost:
+			s.create(w, c, body)
+		case http.MethodPut:
+			s.update(w, c, body)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", r.Method)
+		}
+		return
+	}
+
+	if len(segments) == 2 && strings.EqualFold(segments[1], "Search") && r.Method == http.MethodGet {
+		s.list(w, r, c, true)
+		return
+	}
+	if len(segments) == 2 && strings.EqualFold(segments[1], "SearchByTag") && r.Method == http.MethodGet {
+		s.listByTag(w, r, c)
+		return
+	}
+
+	id, err := strconv.ParseInt(segments[1], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid id %s", segments[1]))
+		return
+	}
+
+	if _, ok := s.records[c.Name][id]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("%s %v not found", c.ObjectName, id))
+		return
+	}
+
+	switch {
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.output(c, s.records[c.Name][id], r.URL.Query().Get("brief") == "true"))
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		s.delete(w, c, id)
+	case len(segments) == 3 && strings.EqualFold(segments[2], "Links"):
+		s.handleLinks(w, r, c, id, body)
+	case len(segments) == 3 && strings.EqualFold(segments[2], "Tags"):
+		s.handleTags(w, r, c, id, body)
+	case len(segments) == 4 && strings.EqualFold(segments[2], "Links") && r.Method == http.MethodDelete:
+		linkID, err := strconv.ParseInt(segments[3], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid link id %s", segments[3]))
+			return
+		}
+		s.deleteLink(w, c, id, linkID)
+	case len(segments) == 3 && strings.EqualFold(segments[2], "Pipeline") && r.Method == http.MethodPut:
+		s.updatePipeline(w, c, id, body)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found", "Unknown endpoint")
+	}
+}
+
+// allow applies FailNext, Throttle and SetRateLimit and reports whether the request may proceed
+//
+func (s *Server) allow(w http.ResponseWriter) bool {
+	if s.failCount > 0 {
+		s.failCount--
+		writeError(w, s.failStatusCode, http.StatusText(s.failStatusCode), "Simulated failure")
+		return false
+	}
+
+	if s.throttleCount > 0 {
+		s.throttleCount--
+		w.Header().Set("Retry-After", retryAfterSeconds(s.throttleRetryAfter))
+		writeError(w, http.StatusTooManyRequests, "Too Many Requests", "Rate limit exceeded")
+		return false
+	}
+
+	if s.rateLimit == nil {
+		return true
+	}
+
+	now := s.now()
+	allowed := true
+	if s.rateLimit.remaining > 0 {
+		s.rateLimit.remaining--
+		if s.rateLimit.remaining == 0 {
+			s.rateLimit.resetAt = now.Add(s.rateLimit.retryAfter)
+		}
+	} else if !now.Before(s.rateLimit.resetAt) {
+		s.rateLimit.remaining = s.rateLimit.limit - 1
+		s.rateLimit.resetAt = now.Add(s.rateLimit.retryAfter)
+	} else {
+		allowed = false
+	}
+
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%v", s.rateLimit.limit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%v", s.rateLimit.remaining))
+	if s.rateLimit.remaining == 0 && !(allowed && s.omitRetryAfter) {
+		w.Header().Set("Retry-After", retryAfterSeconds(s.rateLimit.resetAt.Sub(now)))
+	}
+
+	if !allowed {
+		writeError(w, http.StatusTooManyRequests, "Too Many Requests", "Rate limit exceeded")
+	}
+
+	return allowed
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, c *collection, isSearch bool) {
+	query := r.URL.Query()
+
+	records := s.sorted(c)
+
+	if isSearch {
+		if query.Has("field_name") {
+			records = slices.DeleteFunc(records, func(record map[string]any) bool {
+				return !matchField(record, query.Get("field_name"), query.Get("field_value"))
+			})
+		}
+		if query.Has("updated_after_utc") {
+			updatedAfter, err := time.Parse(searchDateTimeFormat, query.Get("updated_after_utc"))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid updated_after_utc %s", query.Get("updated_after_utc")))
+				return
+			}
+			records = slices.DeleteFunc(records, func(record map[string]any) bool {
+				dateUpdated, err := time.Parse(dateTimeFormat, fmt.Sprintf("%v", record["DATE_UPDATED_UTC"]))
+				return err != nil || !dateUpdated.After(updatedAfter)
+			})
+		}
+	}
+
+	page, ok := paginate(w, query, records)
+	if !ok {
+		return
+	}
+
+	brief := query.Get("brief") == "true"
+	rows := []map[string]any{}
+	for _, record := range page {
+		rows = append(rows, s.output(c, record, brief))
+	}
+
+	writeJSON(w, http.StatusOK, rows)
+}
+
+func (s *Server) listByTag(w http.ResponseWriter, r *http.Request, c *collection) {
+	query := r.URL.Query()
+
+	records := slices.DeleteFunc(s.sorted(c), func(record map[string]any) bool {
+		return !slices.ContainsFunc(recordTagNames(record), func(tagName string) bool {
+			return strings.EqualFold(tagName, query.Get("tagName"))
+		})
+	})
+
+	page, ok := paginate(w, query, records)
+	if !ok {
+		return
+	}
+
+	brief := query.Get("brief") == "true"
+	rows := []map[string]any{}
+	for _, record := range page {
+		rows = append(rows, s.output(c, record, brief))
+	}
+
+	writeJSON(w, http.StatusOK, rows)
+}
+
+// handleTags adds a tag to or removes a tag from a record, the tag is passed in the body
+//
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request, c *collection, id int64, body []byte) {
+	tag, err := decodeRecord(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	tagName, _ := tag["TAG_NAME"].(string)
+	if tagName == "" {
+		writeError(w, http.StatusBadRequest, "Bad Request", "TAG_NAME is required")
+		return
+	}
+
+	record := s.records[c.Name][id]
+	tags, _ := record["TAGS"].([]any)
+	index := slices.IndexFunc(tags, func(t any) bool {
+		tag, _ := t.(map[string]any)
+		name, _ := tag["TAG_NAME"].(string)
+		return strings.EqualFold(name, tagName)
+	})
+
+	switch r.Method {
+	case http.MethodPost:
+		if index < 0 {
+			record["TAGS"] = append(tags, map[string]any{"TAG_NAME": tagName})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"TAG_NAME": tagName})
+	case http.MethodDelete:
+		if index < 0 {
+			writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Tag %s not found", tagName))
+			return
+		}
+		record["TAGS"] = slices.Delete(tags, index, index+1)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", r.Method)
+	}
+}
+
+func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	c, ok := lookupCollection(query.Get("record_type"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid record_type %s", query.Get("record_type")))
+		return
+	}
+
+	tagNames := []string{}
+	for _, record := range s.records[c.Name] {
+		for _, tagName := range recordTagNames(record) {
+			if !slices.Contains(tagNames, tagName) {
+				tagNames = append(tagNames, tagName)
+			}
+		}
+	}
+	sort.Strings(tagNames)
+
+	tags := []map[string]any{}
+	for _, tagName := range tagNames {
+		tags = append(tags, map[string]any{"TAG_NAME": tagName})
+	}
+
+	page, ok := paginate(w, query, tags)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) create(w http.ResponseWriter, c *collection, body []byte) {
+	record, err := decodeRecord(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	delete(record, c.IdField)
+	now := s.now().UTC().Format(dateTimeFormat)
+	record["DATE_CREATED_UTC"] = now
+	record["DATE_UPDATED_UTC"] = now
+
+	id := s.store(c, record)
+
+	writeJSON(w, http.StatusOK, s.output(c, s.records[c.Name][id], false))
+}
+
+func (s *Server) update(w http.ResponseWriter, c *collection, body []byte) {
+	update, err := decodeRecord(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	id, ok := idOf(update, c.IdField)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("%s is required", c.IdField))
+		return
+	}
+
+	record, ok := s.records[c.Name][id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("%s %v not found", c.ObjectName, id))
+		return
+	}
+
+	delete(update, "LINKS")
+	delete(update, "DATE_CREATED_UTC")
+	for key, value := range update {
+		record[key] = value
+	}
+	record[c.IdField] = id
+	record["DATE_UPDATED_UTC"] = s.now().UTC().Format(dateTimeFormat)
+
+	writeJSON(w, http.StatusOK, s.output(c, record, false))
+}
+
+func (s *Server) delete(w http.ResponseWriter, c *collection, id int64) {
+	delete(s.records[c.Name], id)
+
+	s.links = slices.DeleteFunc(s.links, func(link map[string]any) bool {
+		return linkInvolves(link, c, id) != linkNone
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) updatePipeline(w http.ResponseWriter, c *collection, id int64, body []byte) {
+	pipeline := struct {
+		PipelineID          int64 `json:"PIPELINE_ID"`
+		PipelineStageChange struct {
+			StageID int64 `json:"STAGE_ID"`
+		} `json:"PIPELINE_STAGE_CHANGE"`
+	}{}
+	err := json.Unmarshal(body, &pipeline)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	record := s.records[c.Name][id]
+	record["PIPELINE_ID"] = pipeline.PipelineID
+	record["STAGE_ID"] = pipeline.PipelineStageChange.StageID
+	record["DATE_UPDATED_UTC"] = s.now().UTC().Format(dateTimeFormat)
+
+	writeJSON(w, http.StatusOK, s.output(c, record, false))
+}
+
+func (s *Server) handleLinks(w http.ResponseWriter, r *http.Request, c *collection, id int64, body []byte) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.linksOf(c, id))
+	case http.MethodPost:
+		link, err := decodeRecord(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		linkObject, ok := lookupObjectName(fmt.Sprintf("%v", link["LINK_OBJECT_NAME"]))
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid LINK_OBJECT_NAME %v", link["LINK_OBJECT_NAME"]))
+			return
+		}
+		linkObjectID, ok := idOf(link, "LINK_OBJECT_ID")
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad Request", "LINK_OBJECT_ID is required")
+			return
+		}
+		if _, ok := s.records[linkObject.Name][linkObjectID]; !ok {
+			writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("%s %v not found", linkObject.ObjectName, linkObjectID))
+			return
+		}
+		delete(link, "LINK_ID")
+		link["OBJECT_NAME"] = c.ObjectName
+		link["OBJECT_ID"] = id
+		link["LINK_OBJECT_NAME"] = linkObject.ObjectName
+		link["LINK_OBJECT_ID"] = linkObjectID
+		s.storeLink(link)
+		writeJSON(w, http.StatusOK, link)
+	case http.MethodPut:
+		update, err := decodeRecord(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		linkID, _ := idOf(update, "LINK_ID")
+		for _, link := range s.links {
+			if l, _ := idOf(link, "LINK_ID"); l == linkID && linkInvolves(link, c, id) != linkNone {
+				for _, key := range []string{"ROLE", "DETAILS", "RELATIONSHIP_ID"} {
+					if value, ok := update[key]; ok {
+						link[key] = value
+					}
+				}
+				writeJSON(w, http.StatusOK, viewLink(link, c, id))
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Link %v not found", linkID))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", r.Method)
+	}
+}
+
+func (s *Server) deleteLink(w http.ResponseWriter, c *collection, id int64, linkID int64) {
+	for i, link := range s.links {
+		if l, _ := idOf(link, "LINK_ID"); l == linkID && linkInvolves(link, c, id) != linkNone {
+			s.links = slices.Delete(s.links, i, i+1)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Link %v not found", linkID))
+}
+
+// store saves a record, assigning the next free id if it has none, and returns its id
+//
+func (s *Server) store(c *collection, record map[string]any) int64 {
+	if s.records[c.Name] == nil {
+		s.records[c.Name] = make(map[int64]map[string]any)
+	}
+
+	id, ok := idOf(record, c.IdField)
+	if !ok || id == 0 {
+		s.nextIDs[c.Name]++
+		id = s.nextIDs[c.Name]
+	} else if id > s.nextIDs[c.Name] {
+		s.nextIDs[c.Name] = id
+	}
+	record[c.IdField] = id
+
+	if links, ok := record["LINKS"].([]any); ok {
+		for _, l := range links {
+			if link, ok := l.(map[string]any); ok {
+				link["OBJECT_NAME"] = c.ObjectName
+				link["OBJECT_ID"] = id
+				s.storeLink(link)
+			}
+		}
+	}
+	delete(record, "LINKS")
+
+	s.records[c.Name][id] = record
+
+	return id
+}
+
+func (s *Server) storeLink(link map[string]any) int64 {
+	linkID := s.nextLinkID
+	s.nextLinkID++
+
+	link["LINK_ID"] = linkID
+	s.links = append(s.links, link)
+
+	return linkID
+}
+
+// sorted returns the records of a collection ordered by id
+//
+func (s *Server) sorted(c *collection) []map[string]any {
+	ids := []int64{}
+	for id := range s.records[c.Name] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	records := []map[string]any{}
+	for _, id := range ids {
+		records = append(records, s.records[c.Name][id])
+	}
+
+	return records
+}
+
+// output returns a copy of a record as sent in responses, including its links unless brief
+//
+func (s *Server) output(c *collection, record map[string]any, brief bool) map[string]any {
+	o := make(map[string]any, len(record)+1)
+	for key, value := range record {
+		o[key] = value
+	}
+
+	if brief {
+		for _, field := range briefFields {
+			delete(o, field)
+		}
+		return o
+	}
+
+	id, _ := idOf(record, c.IdField)
+	o["LINKS"] = s.linksOf(c, id)
+
+	return o
+}
+
+func (s *Server) linksOf(c *collection, id int64) []map[string]any {
+	links := []map[string]any{}
+	for _, link := range s.links {
+		if linkInvolves(link, c, id) != linkNone {
+			links = append(links, viewLink(link, c, id))
+		}
+	}
+
+	return links
+}
+
+const (
+	linkNone int = iota
+	linkObject
+	linkLinkObject
+)
+
+// linkInvolves reports on which side of a link a record is
+//
+func linkInvolves(link map[string]any, c *collection, id int64) int {
+	objectID, _ := idOf(link, "OBJECT_ID")
+	if strings.EqualFold(fmt.Sprintf("%v", link["OBJECT_NAME"]), c.ObjectName) && objectID == id {
+		return linkObject
+	}
+
+	linkObjectID, _ := idOf(link, "LINK_OBJECT_ID")
+	if strings.EqualFold(fmt.Sprintf("%v", link["LINK_OBJECT_NAME"]), c.ObjectName) && linkObjectID == id {
+		return linkLinkObject
+	}
+
+	return linkNone
+}
+
+// viewLink returns a link as seen from the record c/id, swapping both sides if needed
+//
+func viewLink(link map[string]any, c *collection, id int64) map[string]any {
+	view := make(map[string]any, len(link))
+	for key, value := range link {
+		view[key] = value
+	}
+
+	if linkInvolves(link, c, id) == linkLinkObject {
+		view["OBJECT_NAME"], view["LINK_OBJECT_NAME"] = link["LINK_OBJECT_NAME"], link["OBJECT_NAME"]
+		view["OBJECT_ID"], view["LINK_OBJECT_ID"] = link["LINK_OBJECT_ID"], link["OBJECT_ID"]
+		if isForward, ok := link["IS_FORWARD"].(bool); ok {
+			view["IS_FORWARD"] = !isForward
+		}
+	}
+
+	return view
+}
+
+// matchField reports whether a record has fieldValue in field fieldName or in the custom field fieldName
+//
+func matchField(record map[string]any, fieldName string, fieldValue string) bool {
+	for key, value := range record {
+		if strings.EqualFold(key, fieldName) {
+			return value != nil && strings.EqualFold(fmt.Sprintf("%v", value), fieldValue)
+		}
+	}
+
+	customFields, _ := record["CUSTOMFIELDS"].([]any)
+	for _, cf := range customFields {
+		customField, ok := cf.(map[string]any)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(fmt.Sprintf("%v", customField["FIELD_NAME"]), fieldName) {
+			value := customField["FIELD_VALUE"]
+			return value != nil && strings.EqualFold(fmt.Sprintf("%v", value), fieldValue)
+		}
+	}
+
+	return false
+}
+
+func recordTagNames(record map[string]any) []string {
+	tagNames := []string{}
+
+	tags, _ := record["TAGS"].([]any)
+	for _, t := range tags {
+		tag, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		if tagName, ok := tag["TAG_NAME"].(string); ok {
+			tagNames = append(tagNames, tagName)
+		}
+	}
+
+	return tagNames
+}
+
+// paginate applies skip, top and count_total to rows
+//
+func paginate[T any](w http.ResponseWriter, query url.Values, rows []T) ([]T, bool) {
+	skip := 0
+	top := defaultTop
+
+	if query.Has("skip") {
+		_skip, err := strconv.Atoi(query.Get("skip"))
+		if err != nil || _skip < 0 {
+			writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid skip %s", query.Get("skip")))
+			return nil, false
+		}
+		skip = _skip
+	}
+	if query.Has("top") {
+		_top, err := strconv.Atoi(query.Get("top"))
+		if err != nil || _top < 1 || _top > maxTop {
+			writeError(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Invalid top %s, must be between 1 and %v", query.Get("top"), maxTop))
+			return nil, false
+		}
+		top = _top
+	}
+
+	if query.Get("count_total") == "true" {
+		w.Header().Set("X-Total-Count", fmt.Sprintf("%v", len(rows)))
+	}
+
+	if skip >= len(rows) {
+		return []T{}, true
+	}
+
+	return rows[skip:min(skip+top, len(rows))], true
+}
+
+func idOf(record map[string]any, idField string) (int64, bool) {
+	switch id := record[idField].(type) {
+	case int64:
+		return id, true
+	case json.Number:
+		i, err := id.Int64()
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+func decodeRecord(b []byte) (map[string]any, error) {
+	record := map[string]any{}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&record)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request body: %v", err)
+	}
+
+	return record, nil
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return fmt.Sprintf("%v", int64(math.Ceil(math.Max(d.Seconds(), 0))))
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, name string, message string) {
+	writeJSON(w, statusCode, insightly.ErrorResponse{
+		Name:    name,
+		Message: message,
+	})
+}
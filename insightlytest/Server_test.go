@@ -0,0 +1,291 @@
+package insightlytest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	insightly "github.com/leapforce-libraries/go_insightly"
+	"github.com/leapforce-libraries/go_insightly/insightlytest"
+)
+
+func newService(t *testing.T) (*insightly.Service, *insightlytest.Server) {
+	t.Helper()
+
+	server := insightlytest.NewServer()
+	t.Cleanup(server.Close)
+
+	config := server.ServiceConfig()
+	config.RetryPolicy = &insightly.RetryPolicy{}
+
+	service, e := insightly.NewService(config)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	return service, server
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func seedContacts(server *insightlytest.Server, firstNames ...string) []int64 {
+	contacts := []any{}
+	for _, firstName := range firstNames {
+		contacts = append(contacts, insightly.Contact{FirstName: ptr(firstName)})
+	}
+
+	return server.Seed("Contacts", contacts...)
+}
+
+func TestPaging(t *testing.T) {
+	service, server := newService(t)
+	seedContacts(server, "a", "b", "c", "d", "e", "f", "g")
+
+	firstNames := []string{}
+	pages := 0
+	config := &insightly.GetContactsConfig{Top: ptr(uint64(3))}
+	for {
+		contacts, cursor, e := service.GetContactsPage(config)
+		if e != nil {
+			t.Fatal(e.Message())
+		}
+		pages++
+		for _, contact := range *contacts {
+			firstNames = append(firstNames, *contact.FirstName)
+		}
+		if cursor == nil {
+			break
+		}
+		config = &insightly.GetContactsConfig{Top: ptr(uint64(3)), Cursor: cursor}
+	}
+
+	if pages != 3 {
+		t.Errorf("got %v pages, want 3", pages)
+	}
+	if len(firstNames) != 7 || firstNames[0] != "a" || firstNames[6] != "g" {
+		t.Errorf("got %v, want a to g in id order", firstNames)
+	}
+
+	skips := []string{}
+	for _, request := range server.Requests() {
+		skips = append(skips, request.Query.Get("skip"))
+	}
+	if len(skips) != 3 || skips[0] != "0" || skips[1] != "3" || skips[2] != "6" {
+		t.Errorf("got skips %v, want [0 3 6]", skips)
+	}
+}
+
+func TestPagingInvalidTop(t *testing.T) {
+	service, _ := newService(t)
+
+	_, _, e := service.GetContactsPage(&insightly.GetContactsConfig{Top: ptr(uint64(501))})
+	if e == nil {
+		t.Fatal("expected an error for top 501")
+	}
+
+	var validationError *insightly.ValidationError
+	if !errors.As(insightly.AsError(e), &validationError) || validationError.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want a 400 ValidationError", e.Message())
+	}
+}
+
+func TestSearchByField(t *testing.T) {
+	service, server := newService(t)
+	seedContacts(server, "Ann", "Bob", "ann")
+
+	contacts, e := service.GetContacts(&insightly.GetContactsConfig{
+		FieldFilter: &insightly.FieldFilter{FieldName: "FIRST_NAME", FieldValue: "ANN"},
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*contacts) != 2 {
+		t.Errorf("got %v contacts, want 2", len(*contacts))
+	}
+
+	request := server.Requests()[0]
+	if request.Path != "/v3.1/Contacts/Search" {
+		t.Errorf("got path %s, want /v3.1/Contacts/Search", request.Path)
+	}
+}
+
+func TestSearchUpdatedAfter(t *testing.T) {
+	service, server := newService(t)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.SetNow(func() time.Time { return now })
+	_, e := service.CreateContact(&insightly.Contact{FirstName: ptr("old")})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	now = now.Add(time.Hour)
+	created, e := service.CreateContact(&insightly.Contact{FirstName: ptr("new")})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	updatedAfter := now.Add(-time.Minute)
+	contacts, e := service.GetContacts(&insightly.GetContactsConfig{UpdatedAfter: &updatedAfter})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*contacts) != 1 || (*contacts)[0].ContactID != created.ContactID {
+		t.Errorf("got %v contacts, want only contact %v", len(*contacts), created.ContactID)
+	}
+}
+
+func TestSearchByTag(t *testing.T) {
+	service, server := newService(t)
+	server.Seed("Contacts",
+		insightly.Contact{FirstName: ptr("a"), Tags: &[]insightly.Tag{{TagName: "vip"}}},
+		insightly.Contact{FirstName: ptr("b")},
+		insightly.Contact{FirstName: ptr("c"), Tags: &[]insightly.Tag{{TagName: "VIP"}, {TagName: "lead"}}},
+	)
+
+	contacts, e := service.SearchContactsByTag("vip", nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*contacts) != 2 || *(*contacts)[0].FirstName != "a" || *(*contacts)[1].FirstName != "c" {
+		t.Errorf("got %v contacts, want a and c", len(*contacts))
+	}
+
+	contacts, e = service.SearchContactsByTag("vip", &insightly.SearchByTagConfig{Skip: ptr(uint64(1)), Top: ptr(uint64(1))})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*contacts) != 1 || *(*contacts)[0].FirstName != "c" {
+		t.Errorf("got %v contacts, want only c", len(*contacts))
+	}
+}
+
+func TestLinks(t *testing.T) {
+	service, server := newService(t)
+	contactIDs := seedContacts(server, "a")
+	organisationIDs := server.Seed("Organisations", insightly.Organisation{OrganisationName: ptr("Acme")})
+
+	link, e := service.CreateLink(insightly.ObjectNameContact, contactIDs[0], &insightly.Link{
+		LinkObjectName: ptr(string(insightly.ObjectNameOrganisation)),
+		LinkObjectID:   &organisationIDs[0],
+		Role:           ptr("Employee"),
+	})
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	links, e := service.GetLinks(insightly.ObjectNameOrganisation, organisationIDs[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*links) != 1 {
+		t.Fatalf("got %v links, want 1", len(*links))
+	}
+	if l := (*links)[0]; *l.LinkID != *link.LinkID || *l.LinkObjectID != contactIDs[0] || *l.ObjectID != organisationIDs[0] {
+		t.Errorf("got link %v/%v -> %v, want it seen from the organisation", *l.LinkID, *l.ObjectID, *l.LinkObjectID)
+	}
+
+	link.Role = ptr("Owner")
+	_, e = service.UpdateLink(insightly.ObjectNameContact, contactIDs[0], link)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	contact, e := service.GetContact(contactIDs[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if contact.Links == nil || len(*contact.Links) != 1 || *(*contact.Links)[0].Role != "Owner" {
+		t.Errorf("expected the contact to have the updated link")
+	}
+
+	e = service.DeleteLink(insightly.ObjectNameContact, contactIDs[0], *link.LinkID)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	links, e = service.GetLinks(insightly.ObjectNameContact, contactIDs[0])
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	if len(*links) != 0 {
+		t.Errorf("got %v links after delete, want 0", len(*links))
+	}
+}
+
+func TestTags(t *testing.T) {
+	service, server := newService(t)
+	contactIDs := seedContacts(server, "a")
+
+	e := service.AddTag(insightly.ObjectNameContact, contactIDs[0], "vip")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	contact := insightly.Contact{}
+	server.Get("Contacts", contactIDs[0], &contact)
+	if contact.Tags == nil || len(*contact.Tags) != 1 || (*contact.Tags)[0].TagName != "vip" {
+		t.Fatalf("expected the contact to be tagged vip")
+	}
+
+	e = service.RemoveTag(insightly.ObjectNameContact, contactIDs[0], "vip")
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	contact = insightly.Contact{}
+	server.Get("Contacts", contactIDs[0], &contact)
+	if contact.Tags != nil && len(*contact.Tags) != 0 {
+		t.Errorf("got %v tags after remove, want 0", len(*contact.Tags))
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	service, server := newService(t)
+	server.SetRateLimit(10, 1, time.Minute)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	rateLimit := service.RateLimit()
+	if rateLimit.Limit == nil || *rateLimit.Limit != 10 {
+		t.Errorf("got limit %v, want 10", rateLimit.Limit)
+	}
+	if rateLimit.Remaining == nil || *rateLimit.Remaining != 0 {
+		t.Errorf("got remaining %v, want 0", rateLimit.Remaining)
+	}
+	if rateLimit.RetryAt == nil {
+		t.Error("expected RetryAt from the Retry-After header")
+	}
+}
+
+func TestOmitRetryAfter(t *testing.T) {
+	service, server := newService(t)
+	server.SetRateLimit(10, 1, time.Minute)
+	server.OmitRetryAfter(true)
+
+	_, e := service.GetContacts(nil)
+	if e != nil {
+		t.Fatal(e.Message())
+	}
+
+	rateLimit := service.RateLimit()
+	if rateLimit.Remaining == nil || *rateLimit.Remaining != 0 {
+		t.Errorf("got remaining %v, want 0", rateLimit.Remaining)
+	}
+	if rateLimit.RetryAt != nil {
+		t.Errorf("got RetryAt %v, want none", rateLimit.RetryAt)
+	}
+}